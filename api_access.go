@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// JoinRequestResponse is the JSON form of a JoinRequest
+type JoinRequestResponse struct {
+	Username  string    `json:"username"`
+	Message   string    `json:"message,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *APIServer) handleUpdateSubreddit(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	var req UpdateSubredditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	var err error
+	if req.Type != "" {
+		var subType SubredditType
+		subType, err = ParseSubredditType(req.Type)
+		if err == nil {
+			err = s.engine.SetSubredditType(subredditName, username, subType)
+		}
+	}
+	if err == nil && req.RequirePostFlair != nil {
+		err = s.engine.SetRequirePostFlair(subredditName, username, *req.RequirePostFlair)
+	}
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to update subreddit: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s updated the settings of '%s'", username, subredditName),
+	})
+}
+
+func (s *APIServer) handleGetApprovedUsers(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	approved, err := s.engine.GetApprovedUsers(subredditName, username)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get approved users: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	approved, info, err := paginate(approved, kindUser, func(username string) string { return username }, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get approved users: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d approved users of '%s'", len(approved), subredditName), approved, info))
+}
+
+func (s *APIServer) handleApproveUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	var req ApproveUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := s.engine.ApproveUser(subredditName, username, req.Username); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to approve user: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s approved %s in '%s'", username, req.Username, subredditName),
+	})
+}
+
+func (s *APIServer) handleUnapproveUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	target := vars["username"]
+	username := currentUser(r)
+
+	if err := s.engine.UnapproveUser(subredditName, username, target); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to unapprove user: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s unapproved %s in '%s'", username, target, subredditName),
+	})
+}
+
+func (s *APIServer) handleRequestToJoin(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	// The message is optional, so an empty body is fine
+	var req JoinRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := s.engine.RequestToJoin(subredditName, username, req.Message); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to request to join: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s asked to join '%s'", username, subredditName),
+	})
+}
+
+func (s *APIServer) handleGetJoinRequests(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	requests, err := s.engine.GetJoinRequests(subredditName, username)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get join requests: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	requests, info, err := paginate(requests, kindUser, func(jr *JoinRequest) string { return jr.Username }, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get join requests: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	requestList := make([]JoinRequestResponse, 0)
+	for _, request := range requests {
+		requestList = append(requestList, JoinRequestResponse{
+			Username:  request.Username,
+			Message:   request.Message,
+			CreatedAt: request.CreatedAt,
+		})
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d join requests for '%s'", len(requestList), subredditName), requestList, info))
+}
+
+func (s *APIServer) handleApproveJoinRequest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	target := vars["username"]
+	username := currentUser(r)
+
+	if err := s.engine.ApproveJoinRequest(subredditName, username, target); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to approve join request: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s let %s into '%s'", username, target, subredditName),
+	})
+}
+
+func (s *APIServer) handleDenyJoinRequest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	target := vars["username"]
+	username := currentUser(r)
+
+	if err := s.engine.DenyJoinRequest(subredditName, username, target); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to deny join request: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s denied the join request of %s in '%s'", username, target, subredditName),
+	})
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func (s *APIServer) handleSuspendUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	target := vars["username"]
+	username := currentUser(r)
+
+	var req SuspensionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	duration := time.Duration(req.DurationDays) * 24 * time.Hour
+	if err := s.engine.SuspendUser(username, target, req.Reason, duration); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to suspend user: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s suspended %s", username, target),
+	})
+}
+
+func (s *APIServer) handleUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	target := vars["username"]
+	username := currentUser(r)
+
+	if err := s.engine.UnsuspendUser(username, target); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to unsuspend user: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s lifted the suspension of %s", username, target),
+	})
+}
+
+func (s *APIServer) handleBanSubreddit(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	var req SubredditBanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := s.engine.BanSubreddit(username, subredditName, req.Reason); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to ban subreddit: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s banned subreddit '%s'", username, subredditName),
+	})
+}
+
+func (s *APIServer) handleUnbanSubreddit(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	if err := s.engine.UnbanSubreddit(username, subredditName); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to unban subreddit: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s unbanned subreddit '%s'", username, subredditName),
+	})
+}
+
+func (s *APIServer) handleDeleteSubreddit(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	if err := s.engine.DeleteSubreddit(username, subredditName); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to delete subreddit: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s deleted subreddit '%s'", username, subredditName),
+	})
+}
+
+func (s *APIServer) handleQuarantineSubreddit(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	if err := s.engine.QuarantineSubreddit(username, subredditName, true); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to quarantine subreddit: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s quarantined subreddit '%s'", username, subredditName),
+	})
+}
+
+func (s *APIServer) handleLiftQuarantine(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	if err := s.engine.QuarantineSubreddit(username, subredditName, false); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to lift quarantine: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s lifted the quarantine of '%s'", username, subredditName),
+	})
+}
+
+func (s *APIServer) handleOptInToQuarantine(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	if err := s.engine.OptInToQuarantine(username, subredditName); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to opt in: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s opted in to view '%s'", username, subredditName),
+	})
+}
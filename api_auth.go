@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type contextKey string
+
+// userContextKey holds the authenticated username in a request context
+const userContextKey contextKey = "user"
+
+// publicRoutes may be called without a session even though they write
+var publicRoutes = map[string]bool{
+	"/api/register": true,
+	"/api/login":    true,
+}
+
+// authMiddleware resolves the bearer token of a request and stores its user
+// in the request context. Requests with an invalid or expired token are
+// rejected; requests without one may only read.
+func (s *APIServer) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, hasToken := bearerToken(r)
+
+		if hasToken {
+			username, err := s.engine.Authenticate(token)
+			if err != nil {
+				writeJSON(w, ErrorResponse{
+					Status:  "error",
+					Message: fmt.Sprintf("Authentication failed: %v", err),
+					Err:     err,
+				})
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), userContextKey, username))
+		} else if r.Method != http.MethodGet && !publicRoutes[r.URL.Path] {
+			writeAuthRequired(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// currentUser returns the authenticated username of a request, or "" for an
+// anonymous request
+func currentUser(r *http.Request) string {
+	username, _ := r.Context().Value(userContextKey).(string)
+	return username
+}
+
+func writeAuthRequired(w http.ResponseWriter) {
+	writeJSON(w, ErrorResponse{
+		Status:  "error",
+		Message: "Authentication required",
+		Err:     ErrUnauthorized,
+	})
+}
+
+func (s *APIServer) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	session, err := s.engine.Login(req.Username, req.Password)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to log in: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s logged in", session.Username),
+		Data: LoginResponse{
+			Username:  session.Username,
+			Token:     session.Token,
+			ExpiresAt: session.ExpiresAt,
+		},
+	})
+}
+
+func (s *APIServer) handleLogout(w http.ResponseWriter, r *http.Request) {
+	token, _ := bearerToken(r)
+	username := currentUser(r)
+
+	if err := s.engine.Logout(token); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to log out: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s logged out", username),
+	})
+}
+
+// LoginResponse is returned by a successful login
+type LoginResponse struct {
+	Username  string    `json:"username"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// AutoModRuleConfig is the JSON form of an AutoModRule, used both to read and
+// to replace the rules of a subreddit
+type AutoModRuleConfig struct {
+	Name              string        `json:"name"`
+	Type              string        `json:"type,omitempty"` // "post", "comment" or empty for both
+	TitleRegex        string        `json:"title_regex,omitempty"`
+	BodyRegex         string        `json:"body_regex,omitempty"`
+	MinAccountAgeDays int           `json:"min_account_age_days,omitempty"`
+	MinKarma          *int          `json:"min_karma,omitempty"`
+	Domains           []string      `json:"domains,omitempty"`
+	Action            AutoModAction `json:"action"`
+	Flair             string        `json:"flair,omitempty"`
+	Comment           string        `json:"comment,omitempty"`
+}
+
+func newAutoModRuleConfig(rule *AutoModRule) AutoModRuleConfig {
+	return AutoModRuleConfig{
+		Name:              rule.Name,
+		Type:              rule.Type,
+		TitleRegex:        rule.TitleRegex,
+		BodyRegex:         rule.BodyRegex,
+		MinAccountAgeDays: rule.MinAccountAgeDays,
+		MinKarma:          rule.MinKarma,
+		Domains:           rule.Domains,
+		Action:            rule.Action,
+		Flair:             rule.Flair,
+		Comment:           rule.Comment,
+	}
+}
+
+func (c AutoModRuleConfig) rule() *AutoModRule {
+	return &AutoModRule{
+		Name:              c.Name,
+		Type:              c.Type,
+		TitleRegex:        c.TitleRegex,
+		BodyRegex:         c.BodyRegex,
+		MinAccountAgeDays: c.MinAccountAgeDays,
+		MinKarma:          c.MinKarma,
+		Domains:           c.Domains,
+		Action:            c.Action,
+		Flair:             c.Flair,
+		Comment:           c.Comment,
+	}
+}
+
+func autoModRuleConfigs(rules []*AutoModRule) []AutoModRuleConfig {
+	configs := make([]AutoModRuleConfig, 0, len(rules))
+	for _, rule := range rules {
+		configs = append(configs, newAutoModRuleConfig(rule))
+	}
+	return configs
+}
+
+func (s *APIServer) handleGetAutoModRules(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	rules, err := s.engine.GetAutoModRules(subredditName, username)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get AutoModerator rules: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Retrieved %d AutoModerator rules of '%s'", len(rules), subredditName),
+		Data:    autoModRuleConfigs(rules),
+	})
+}
+
+func (s *APIServer) handleSetAutoModRules(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	var req AutoModRulesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	rules := make([]*AutoModRule, 0, len(req.Rules))
+	for _, config := range req.Rules {
+		rules = append(rules, config.rule())
+	}
+
+	if err := s.engine.SetAutoModRules(subredditName, username, rules); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to set AutoModerator rules: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s set %d AutoModerator rules in '%s'", username, len(rules), subredditName),
+		Data:    autoModRuleConfigs(rules),
+	})
+}
+
+func (s *APIServer) handleTestAutoModRules(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	var req AutoModTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	sample := AutoModSample{
+		Kind:    req.Kind,
+		Title:   req.Title,
+		Content: req.Content,
+		URL:     req.URL,
+		Author:  req.Author,
+	}
+	rules, err := s.engine.TestAutoModRules(subredditName, username, sample)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to test AutoModerator rules: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Sample would trigger %d AutoModerator rules in '%s'", len(rules), subredditName),
+		Data:    autoModRuleConfigs(rules),
+	})
+}
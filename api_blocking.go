@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (s *APIServer) handleGetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	username := currentUser(r)
+	if username == "" {
+		writeAuthRequired(w)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	blocked, err := s.engine.GetBlockedUsers(username)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get blocked users: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	blocked, info, err := paginate(blocked, kindUser, func(username string) string { return username }, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get blocked users: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d blocked users", len(blocked)), blocked, info))
+}
+
+func (s *APIServer) handleBlockUser(w http.ResponseWriter, r *http.Request) {
+	username := currentUser(r)
+
+	var req BlockUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := s.engine.BlockUser(username, req.Username); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to block user: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s blocked %s", username, req.Username),
+	})
+}
+
+func (s *APIServer) handleUnblockUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	target := vars["username"]
+	username := currentUser(r)
+
+	if err := s.engine.UnblockUser(username, target); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to unblock user: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s unblocked %s", username, target),
+	})
+}
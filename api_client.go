@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 )
 
 type APIClient struct {
 	baseURL  string
 	username string
+	token    string // bearer token set by Login
 	client   *http.Client
 }
 
@@ -19,23 +23,165 @@ type RegisterRequest struct {
 	Password string  `json:"password"`
 }
 
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
 type CreateSubredditRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	Type        string `json:"type,omitempty"` // "public" (default), "restricted" or "private"
+}
+
+// UpdateSubredditRequest changes the settings of a subreddit. Settings left
+// out stay as they are.
+type UpdateSubredditRequest struct {
+	Type             string `json:"type,omitempty"`
+	RequirePostFlair *bool  `json:"require_post_flair,omitempty"`
+}
+
+// FlairTemplateRequest adds a flair template to a subreddit
+type FlairTemplateRequest struct {
+	Kind     string `json:"kind,omitempty"` // "post" (default) or "user"
+	Text     string `json:"text"`
+	Color    string `json:"color,omitempty"` // "#rrggbb"
+	Editable bool   `json:"editable,omitempty"`
+	ModOnly  bool   `json:"mod_only,omitempty"`
 }
 
+// FlairRequest picks a flair template for a post or user. An empty
+// TemplateID clears the flair.
+type FlairRequest struct {
+	TemplateID string `json:"template_id,omitempty"`
+	Text       string `json:"text,omitempty"` // replaces the text of an editable template
+}
+
+// ApproveUserRequest names a user to approve in a subreddit
+type ApproveUserRequest struct {
+	Username string `json:"username"`
+}
+
+// BlockUserRequest names a user to block
+type BlockUserRequest struct {
+	Username string `json:"username"`
+}
+
+// SuspensionRequest suspends a user site-wide. Zero DurationDays makes the
+// suspension permanent.
+type SuspensionRequest struct {
+	Reason       string `json:"reason"`
+	DurationDays int    `json:"duration_days,omitempty"`
+}
+
+// SubredditBanRequest carries the reason an admin bans a subreddit
+type SubredditBanRequest struct {
+	Reason string `json:"reason"`
+}
+
+// JoinRequestRequest asks to join a private subreddit
+type JoinRequestRequest struct {
+	Message string `json:"message,omitempty"`
+}
+
+// CreatePostRequest submits a self, link or poll post. Image posts are
+// uploaded as multipart forms instead.
 type CreatePostRequest struct {
-	Title     string `json:"title"`
-	Content   string `json:"content"`
-	Subreddit string `json:"subreddit"`
+	Title             string   `json:"title"`
+	Content           string   `json:"content"`
+	Subreddit         string   `json:"subreddit"`
+	Kind              string   `json:"kind,omitempty"` // "self" (default), "link" or "poll"
+	URL               string   `json:"url,omitempty"`
+	PollOptions       []string `json:"poll_options,omitempty"`
+	PollDurationHours int      `json:"poll_duration_hours,omitempty"` // defaults to three days
+	FlairTemplateID   string   `json:"flair_template_id,omitempty"`
+	FlairText         string   `json:"flair_text,omitempty"` // replaces the text of an editable flair
 }
 
-type CommentRequest struct {
+// CrosspostRequest shares a post into Subreddit. An empty Title reuses the
+// original's.
+type CrosspostRequest struct {
+	Subreddit       string `json:"subreddit"`
+	Title           string `json:"title,omitempty"`
+	FlairTemplateID string `json:"flair_template_id,omitempty"`
+	FlairText       string `json:"flair_text,omitempty"`
+}
+
+// EditRequest carries the new text of an edited post or comment
+type EditRequest struct {
 	Content string `json:"content"`
 }
 
+// ModeratorRequest names a user to add or invite as moderator. An empty
+// permission list grants full permissions.
+type ModeratorRequest struct {
+	Username    string   `json:"username"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// RestrictionRequest bans or mutes a user. A DurationDays of zero makes the
+// restriction permanent.
+type RestrictionRequest struct {
+	Username     string `json:"username"`
+	Reason       string `json:"reason"`
+	DurationDays int    `json:"duration_days,omitempty"`
+}
+
+// ReportRequest flags a post or comment for the moderators
+type ReportRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ModerateRequest carries the optional reason of a moderator action
+type ModerateRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// LockRequest locks a post, optionally freezing its votes
+type LockRequest struct {
+	FreezeVotes bool `json:"freeze_votes"`
+}
+
+// AutoModRulesRequest replaces the AutoModerator rules of a subreddit
+type AutoModRulesRequest struct {
+	Rules []AutoModRuleConfig `json:"rules"`
+}
+
+// AutoModTestRequest is a sample post or comment for an AutoModerator dry
+// run. Author defaults to the requesting moderator.
+type AutoModTestRequest struct {
+	Kind    string `json:"kind,omitempty"` // "post" (default) or "comment"
+	Title   string `json:"title,omitempty"`
+	Content string `json:"content"`
+	URL     string `json:"url,omitempty"`
+	Author  string `json:"author,omitempty"` // defaults to a brand-new account with no karma
+}
+
+// PollVoteRequest picks a poll option, counted from zero
+type PollVoteRequest struct {
+	Option *int `json:"option"`
+}
+
+type CommentRequest struct {
+	Content  string `json:"content"`
+	ParentID string `json:"parent_id,omitempty"` // comment being replied to; empty for a top-level comment
+}
+
 type VoteRequest struct {
-	Upvote bool `json:"upvote"`
+	Upvote    bool `json:"upvote"`
+	Direction *int `json:"dir,omitempty"` // 1, 0 or -1; takes precedence over Upvote
+}
+
+// direction returns the vote direction requested, falling back to the
+// legacy upvote flag when no explicit direction was sent
+func (r VoteRequest) direction() int {
+	if r.Direction != nil {
+		return *r.Direction
+	}
+	if r.Upvote {
+		return VoteUp
+	}
+	return VoteDown
 }
 
 type MessageRequest struct {
@@ -51,13 +197,43 @@ func NewAPIClient(baseURL, username string) *APIClient {
 	}
 }
 
+// Register creates an account. An empty username lets the server pick one.
 func (c *APIClient) Register(username, password string) error {
 	data := RegisterRequest{
 		Password: password,
 	}
+	if username != "" {
+		data.Username = &username
+	}
 	return c.post("/api/register", data, nil)
 }
 
+// Login opens a session for the client's user. Every later request carries
+// the session token.
+func (c *APIClient) Login(password string) error {
+	var response struct {
+		Data LoginResponse `json:"data"`
+	}
+	data := LoginRequest{
+		Username: c.username,
+		Password: password,
+	}
+	if err := c.post("/api/login", data, &response); err != nil {
+		return err
+	}
+	c.token = response.Data.Token
+	return nil
+}
+
+// Logout ends the client's session
+func (c *APIClient) Logout() error {
+	if err := c.post("/api/logout", nil, nil); err != nil {
+		return err
+	}
+	c.token = ""
+	return nil
+}
+
 func (c *APIClient) CreateSubreddit(name, description string) error {
 	data := CreateSubredditRequest{
 		Name:        name,
@@ -74,6 +250,85 @@ func (c *APIClient) LeaveSubreddit(name string) error {
 	return c.post(fmt.Sprintf("/api/subreddits/%s/leave", name), nil, nil)
 }
 
+// OptInToQuarantine lets the client see a quarantined subreddit
+func (c *APIClient) OptInToQuarantine(name string) error {
+	return c.post(fmt.Sprintf("/api/subreddits/%s/quarantine_optin", name), nil, nil)
+}
+
+// SetSubredditType makes a subreddit public, restricted or private
+func (c *APIClient) SetSubredditType(name string, subType SubredditType) error {
+	return c.send("PATCH", fmt.Sprintf("/api/subreddits/%s", name), UpdateSubredditRequest{Type: string(subType)}, nil)
+}
+
+// SetRequirePostFlair controls whether new posts in a subreddit need a flair
+func (c *APIClient) SetRequirePostFlair(name string, require bool) error {
+	return c.send("PATCH", fmt.Sprintf("/api/subreddits/%s", name), UpdateSubredditRequest{RequirePostFlair: &require}, nil)
+}
+
+// GetFlairTemplates returns the post or user flair templates of a subreddit
+func (c *APIClient) GetFlairTemplates(name string, kind FlairKind) ([]FlairTemplateResponse, error) {
+	var response struct {
+		Data []FlairTemplateResponse `json:"data"`
+	}
+	query := url.Values{}
+	query.Set("kind", string(kind))
+	if err := c.get(fmt.Sprintf("/api/subreddits/%s/flair_templates?%s", name, query.Encode()), &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// AddFlairTemplate offers a new flair in a subreddit
+func (c *APIClient) AddFlairTemplate(name string, template FlairTemplateRequest) (*FlairTemplateResponse, error) {
+	var response struct {
+		Data FlairTemplateResponse `json:"data"`
+	}
+	if err := c.post(fmt.Sprintf("/api/subreddits/%s/flair_templates", name), template, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// RemoveFlairTemplate stops offering a flair
+func (c *APIClient) RemoveFlairTemplate(name, templateID string) error {
+	return c.send("DELETE", fmt.Sprintf("/api/subreddits/%s/flair_templates/%s", name, templateID), nil, nil)
+}
+
+// SetUserFlair sets the flair username shows in a subreddit; an empty
+// templateID clears it
+func (c *APIClient) SetUserFlair(name, username, templateID, text string) error {
+	data := FlairRequest{TemplateID: templateID, Text: text}
+	return c.send("PUT", fmt.Sprintf("/api/subreddits/%s/user_flair/%s", name, username), data, nil)
+}
+
+// SetPostFlair sets the flair of a post; an empty templateID clears it
+func (c *APIClient) SetPostFlair(postID, templateID, text string) error {
+	data := FlairRequest{TemplateID: templateID, Text: text}
+	return c.send("PUT", fmt.Sprintf("/api/posts/%s/flair", postID), data, nil)
+}
+
+// RequestToJoin asks the moderators of a private subreddit to let the
+// client in
+func (c *APIClient) RequestToJoin(name, message string) error {
+	return c.post(fmt.Sprintf("/api/subreddits/%s/join_requests", name), JoinRequestRequest{Message: message}, nil)
+}
+
+// IterJoinRequests iterates over the pending join requests of a subreddit;
+// items decode into JoinRequestResponse
+func (c *APIClient) IterJoinRequests(name string) *ListingIterator {
+	return c.iterate(fmt.Sprintf("/api/subreddits/%s/join_requests", name), url.Values{})
+}
+
+// ApproveJoinRequest lets username into a private subreddit
+func (c *APIClient) ApproveJoinRequest(name, username string) error {
+	return c.post(fmt.Sprintf("/api/subreddits/%s/join_requests/%s/approve", name, username), nil, nil)
+}
+
+// DenyJoinRequest drops the join request of username
+func (c *APIClient) DenyJoinRequest(name, username string) error {
+	return c.send("DELETE", fmt.Sprintf("/api/subreddits/%s/join_requests/%s", name, username), nil, nil)
+}
+
 func (c *APIClient) CreatePost(title, content, subreddit string) error {
 	data := CreatePostRequest{
 		Title:     title,
@@ -83,57 +338,689 @@ func (c *APIClient) CreatePost(title, content, subreddit string) error {
 	return c.post("/api/posts", data, nil)
 }
 
-func (c *APIClient) GetPosts() ([]*Post, error) {
-	var response SuccessResponse
-	err := c.get("/api/posts", &response)
-	if err != nil {
+// CreateLinkPost submits a link to another site
+func (c *APIClient) CreateLinkPost(title, link, subreddit string) error {
+	data := CreatePostRequest{
+		Title:     title,
+		Subreddit: subreddit,
+		Kind:      string(PostLink),
+		URL:       link,
+	}
+	return c.post("/api/posts", data, nil)
+}
+
+// Crosspost shares a post into another subreddit and returns the new post.
+// An empty title reuses the original's.
+func (c *APIClient) Crosspost(postID, subreddit, title string) (*PostResponse, error) {
+	var response struct {
+		Data PostResponse `json:"data"`
+	}
+	data := CrosspostRequest{Subreddit: subreddit, Title: title}
+	if err := c.post(fmt.Sprintf("/api/posts/%s/crosspost", postID), data, &response); err != nil {
 		return nil, err
 	}
+	return &response.Data, nil
+}
+
+// IterCrossposts iterates over the crossposts of a post, newest first; items
+// decode into PostResponse
+func (c *APIClient) IterCrossposts(postID string) *ListingIterator {
+	return c.iterate(fmt.Sprintf("/api/posts/%s/crossposts", postID), url.Values{})
+}
+
+// CreateImagePost uploads image as a new image post. filename is only a hint;
+// the server detects the image type from its content.
+func (c *APIClient) CreateImagePost(title, subreddit, filename string, image []byte) error {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("title", title)
+	form.WriteField("subreddit", subreddit)
+	part, err := form.CreateFormFile("image", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(image); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL+"/api/posts", &body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	c.authorize(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return decodeAPIError(resp)
+	}
+	return nil
+}
+
+// CreatePollPost starts a poll that closes after duration, which must be a
+// whole number of hours; zero selects the default of three days
+func (c *APIClient) CreatePollPost(title, subreddit string, options []string, duration time.Duration) error {
+	if duration%time.Hour != 0 {
+		return &ValidationError{Field: "poll duration", Reason: fmt.Sprintf("%s is not a whole number of hours", duration)}
+	}
+	data := CreatePostRequest{
+		Title:             title,
+		Subreddit:         subreddit,
+		Kind:              string(PostPoll),
+		PollOptions:       options,
+		PollDurationHours: int(duration / time.Hour),
+	}
+	return c.post("/api/posts", data, nil)
+}
 
-	// Type assertion for the response data
-	postsData, ok := response.Data.([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid response format")
+// VotePoll votes for option, counted from zero, in a poll post and returns
+// the results as the client user now sees them
+func (c *APIClient) VotePoll(postID string, option int) (*PollResponse, error) {
+	var response struct {
+		Data PollResponse `json:"data"`
 	}
+	if err := c.post(fmt.Sprintf("/api/posts/%s/poll", postID), PollVoteRequest{Option: &option}, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
 
-	// Convert the data to []*Post
-	posts := make([]*Post, 0, len(postsData))
-	for _, postData := range postsData {
-		if postMap, ok := postData.(map[string]interface{}); ok {
-			post := &Post{
-				ID:        postMap["id"].(string),
-				Title:     postMap["title"].(string),
-				Content:   postMap["content"].(string),
-				Author:    postMap["author"].(string),
-				Subreddit: postMap["subreddit"].(string),
-				Votes:     int(postMap["votes"].(float64)),
-			}
-			posts = append(posts, post)
+// IterFlairPosts iterates over the posts of a subreddit with the given
+// flair; items decode into PostResponse
+func (c *APIClient) IterFlairPosts(name, flair string, sortMode SortMode, window TimeWindow) *ListingIterator {
+	query := url.Values{}
+	query.Set("sort", string(sortMode))
+	query.Set("t", string(window))
+	query.Set("flair", flair)
+	return c.iterate(fmt.Sprintf("/api/subreddits/%s/posts", name), query)
+}
+
+// IterDomainPosts iterates over the link posts to a site across every
+// subreddit; items decode into PostResponse
+func (c *APIClient) IterDomainPosts(domain string, sortMode SortMode, window TimeWindow) *ListingIterator {
+	query := url.Values{}
+	query.Set("sort", string(sortMode))
+	query.Set("t", string(window))
+	return c.iterate(fmt.Sprintf("/api/domains/%s/posts", domain), query)
+}
+
+// GetPosts returns the client user's whole feed ordered by sortMode, walking
+// every page of the listing. window only matters for the top and
+// controversial sorts.
+func (c *APIClient) GetPosts(sortMode SortMode, window TimeWindow) ([]*Post, error) {
+	posts := make([]*Post, 0)
+	it := c.IterPosts(sortMode, window)
+	for it.Next() {
+		var data PostResponse
+		if err := it.Decode(&data); err != nil {
+			return nil, err
 		}
+		posts = append(posts, &Post{
+			ID:        data.ID,
+			Title:     data.Title,
+			Content:   data.Content,
+			Author:    data.Author,
+			Subreddit: data.Subreddit,
+			CreatedAt: data.CreatedAt,
+			Votes:     data.Votes,
+			Upvotes:   data.Upvotes,
+			Downvotes: data.Downvotes,
+		})
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
 	}
 
 	return posts, nil
 }
 
-func (c *APIClient) VotePost(postID string, upvote bool) error {
-	data := VoteRequest{Upvote: upvote}
-	return c.post(fmt.Sprintf("/api/posts/%s/vote", postID), data, nil)
+// GetPost returns a single post
+func (c *APIClient) GetPost(postID string) (*PostResponse, error) {
+	var response struct {
+		Data PostResponse `json:"data"`
+	}
+	if err := c.get(fmt.Sprintf("/api/posts/%s", postID), &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// EditPost replaces the text of one of the client user's posts
+func (c *APIClient) EditPost(postID, content string) (*PostResponse, error) {
+	var response struct {
+		Data PostResponse `json:"data"`
+	}
+	data := EditRequest{Content: content}
+	if err := c.send("PATCH", fmt.Sprintf("/api/posts/%s", postID), data, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// DeletePost deletes one of the client user's posts, leaving a tombstone
+func (c *APIClient) DeletePost(postID string) error {
+	return c.send("DELETE", fmt.Sprintf("/api/posts/%s", postID), nil, nil)
+}
+
+// AddComment comments on a post, or replies to parentID when it is not empty
+func (c *APIClient) AddComment(postID, parentID, content string) (*CommentResponse, error) {
+	var response struct {
+		Data CommentResponse `json:"data"`
+	}
+	data := CommentRequest{Content: content, ParentID: parentID}
+	if err := c.post(fmt.Sprintf("/api/posts/%s/comments", postID), data, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// GetComment returns a comment with up to context ancestors above it and depth
+// levels of replies below it. A negative depth returns every reply.
+func (c *APIClient) GetComment(commentID string, context, depth int) (*CommentResponse, error) {
+	query := url.Values{}
+	query.Set("context", strconv.Itoa(context))
+	if depth >= 0 {
+		query.Set("depth", strconv.Itoa(depth))
+	}
+
+	var response struct {
+		Data CommentResponse `json:"data"`
+	}
+	if err := c.get(fmt.Sprintf("/api/comments/%s?%s", commentID, query.Encode()), &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// EditComment replaces the text of one of the client user's comments
+func (c *APIClient) EditComment(commentID, content string) (*CommentResponse, error) {
+	var response struct {
+		Data CommentResponse `json:"data"`
+	}
+	data := EditRequest{Content: content}
+	if err := c.send("PATCH", fmt.Sprintf("/api/comments/%s", commentID), data, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// DeleteComment deletes one of the client user's comments, keeping its replies
+func (c *APIClient) DeleteComment(commentID string) error {
+	return c.send("DELETE", fmt.Sprintf("/api/comments/%s", commentID), nil, nil)
+}
+
+// IterPosts iterates over the client user's feed; items decode into PostResponse
+func (c *APIClient) IterPosts(sortMode SortMode, window TimeWindow) *ListingIterator {
+	query := url.Values{}
+	query.Set("sort", string(sortMode))
+	query.Set("t", string(window))
+	return c.iterate("/api/posts", query)
+}
+
+// IterSubreddits iterates over the subreddits whose name starts with prefix;
+// items decode into SubredditResponse
+func (c *APIClient) IterSubreddits(prefix string, order SubredditSort) *ListingIterator {
+	query := url.Values{}
+	query.Set("q", prefix)
+	query.Set("sort", string(order))
+	return c.iterate("/api/subreddits", query)
+}
+
+// GetSubreddit returns the details of a single subreddit
+func (c *APIClient) GetSubreddit(name string) (*SubredditResponse, error) {
+	var response struct {
+		Data SubredditResponse `json:"data"`
+	}
+	if err := c.get(fmt.Sprintf("/api/subreddits/%s", name), &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// IterSubredditPosts iterates over the posts of a subreddit without joining
+// it; items decode into PostResponse
+func (c *APIClient) IterSubredditPosts(name string, sortMode SortMode, window TimeWindow) *ListingIterator {
+	query := url.Values{}
+	query.Set("sort", string(sortMode))
+	query.Set("t", string(window))
+	return c.iterate(fmt.Sprintf("/api/subreddits/%s/posts", name), query)
+}
+
+// IterSubredditMembers iterates over the usernames of a subreddit's members;
+// items decode into string
+func (c *APIClient) IterSubredditMembers(name string) *ListingIterator {
+	return c.iterate(fmt.Sprintf("/api/subreddits/%s/members", name), url.Values{})
+}
+
+// GetModerators returns the moderators of a subreddit in order of seniority
+func (c *APIClient) GetModerators(subreddit string) ([]ModeratorResponse, error) {
+	var response struct {
+		Data []ModeratorResponse `json:"data"`
+	}
+	if err := c.get(fmt.Sprintf("/api/subreddits/%s/moderators", subreddit), &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// AddModerator makes username a moderator of a subreddit right away
+func (c *APIClient) AddModerator(subreddit, username string, permissions ...string) error {
+	data := ModeratorRequest{Username: username, Permissions: permissions}
+	return c.post(fmt.Sprintf("/api/subreddits/%s/moderators", subreddit), data, nil)
+}
+
+// InviteModerator invites username to moderate a subreddit
+func (c *APIClient) InviteModerator(subreddit, username string, permissions ...string) error {
+	data := ModeratorRequest{Username: username, Permissions: permissions}
+	return c.post(fmt.Sprintf("/api/subreddits/%s/moderators/invite", subreddit), data, nil)
+}
+
+// AcceptModeratorInvite accepts the client user's invitation to moderate
+func (c *APIClient) AcceptModeratorInvite(subreddit string) error {
+	return c.post(fmt.Sprintf("/api/subreddits/%s/moderators/accept", subreddit), nil, nil)
+}
+
+// RemoveModerator removes username from a subreddit's moderators
+func (c *APIClient) RemoveModerator(subreddit, username string) error {
+	return c.send("DELETE", fmt.Sprintf("/api/subreddits/%s/moderators/%s", subreddit, username), nil, nil)
+}
+
+// IterBans iterates over the active bans of a subreddit; items decode into
+// RestrictionResponse
+func (c *APIClient) IterBans(subreddit string) *ListingIterator {
+	return c.iterate(fmt.Sprintf("/api/subreddits/%s/bans", subreddit), url.Values{})
+}
+
+// BanUser bans username from a subreddit for days, or permanently when days
+// is zero
+func (c *APIClient) BanUser(subreddit, username, reason string, days int) error {
+	data := RestrictionRequest{Username: username, Reason: reason, DurationDays: days}
+	return c.post(fmt.Sprintf("/api/subreddits/%s/bans", subreddit), data, nil)
+}
+
+// UnbanUser lifts username's ban from a subreddit
+func (c *APIClient) UnbanUser(subreddit, username string) error {
+	return c.send("DELETE", fmt.Sprintf("/api/subreddits/%s/bans/%s", subreddit, username), nil, nil)
+}
+
+// IterMutes iterates over the active mutes of a subreddit; items decode into
+// RestrictionResponse
+func (c *APIClient) IterMutes(subreddit string) *ListingIterator {
+	return c.iterate(fmt.Sprintf("/api/subreddits/%s/mutes", subreddit), url.Values{})
+}
+
+// MuteUser mutes username in a subreddit for days, or permanently when days
+// is zero
+func (c *APIClient) MuteUser(subreddit, username, reason string, days int) error {
+	data := RestrictionRequest{Username: username, Reason: reason, DurationDays: days}
+	return c.post(fmt.Sprintf("/api/subreddits/%s/mutes", subreddit), data, nil)
+}
+
+// UnmuteUser lifts username's mute in a subreddit
+func (c *APIClient) UnmuteUser(subreddit, username string) error {
+	return c.send("DELETE", fmt.Sprintf("/api/subreddits/%s/mutes/%s", subreddit, username), nil, nil)
+}
+
+// IterApprovedUsers iterates over the approved users of a subreddit; items
+// decode into strings
+func (c *APIClient) IterApprovedUsers(subreddit string) *ListingIterator {
+	return c.iterate(fmt.Sprintf("/api/subreddits/%s/approved", subreddit), url.Values{})
+}
+
+// ApproveUser lets username post in a restricted subreddit, or invites them
+// into a private one
+func (c *APIClient) ApproveUser(subreddit, username string) error {
+	return c.post(fmt.Sprintf("/api/subreddits/%s/approved", subreddit), ApproveUserRequest{Username: username}, nil)
+}
+
+// UnapproveUser takes back the approval of username
+func (c *APIClient) UnapproveUser(subreddit, username string) error {
+	return c.send("DELETE", fmt.Sprintf("/api/subreddits/%s/approved/%s", subreddit, username), nil, nil)
+}
+
+// ReportPost flags a post for the moderators of its subreddit
+func (c *APIClient) ReportPost(postID, reason string) error {
+	return c.post(fmt.Sprintf("/api/posts/%s/report", postID), ReportRequest{Reason: reason}, nil)
+}
+
+// ReportComment flags a comment for the moderators of its subreddit
+func (c *APIClient) ReportComment(commentID, reason string) error {
+	return c.post(fmt.Sprintf("/api/comments/%s/report", commentID), ReportRequest{Reason: reason}, nil)
+}
+
+// ModeratePost approves, removes or ignores the reports of a post. reason
+// is recorded in the mod log and may be empty.
+func (c *APIClient) ModeratePost(postID string, action ModAction, reason string) error {
+	data := ModerateRequest{Reason: reason}
+	return c.post(fmt.Sprintf("/api/posts/%s/%s", postID, action), data, nil)
+}
+
+// ModerateComment approves, removes or ignores the reports of a comment
+func (c *APIClient) ModerateComment(commentID string, action ModAction, reason string) error {
+	data := ModerateRequest{Reason: reason}
+	return c.post(fmt.Sprintf("/api/comments/%s/%s", commentID, action), data, nil)
+}
+
+// StickyPost pins a post to the top of its subreddit
+func (c *APIClient) StickyPost(postID string) error {
+	return c.post(fmt.Sprintf("/api/posts/%s/sticky", postID), nil, nil)
+}
+
+// UnstickyPost unpins a sticky post
+func (c *APIClient) UnstickyPost(postID string) error {
+	return c.post(fmt.Sprintf("/api/posts/%s/unsticky", postID), nil, nil)
+}
+
+// LockPost stops new comments on a post, and its votes when freezeVotes is set
+func (c *APIClient) LockPost(postID string, freezeVotes bool) error {
+	return c.post(fmt.Sprintf("/api/posts/%s/lock", postID), LockRequest{FreezeVotes: freezeVotes}, nil)
+}
+
+// UnlockPost reopens a locked post
+func (c *APIClient) UnlockPost(postID string) error {
+	return c.post(fmt.Sprintf("/api/posts/%s/unlock", postID), nil, nil)
+}
+
+// GetAutoModRules returns the AutoModerator rules of a subreddit
+func (c *APIClient) GetAutoModRules(subreddit string) ([]AutoModRuleConfig, error) {
+	var response struct {
+		Data []AutoModRuleConfig `json:"data"`
+	}
+	if err := c.get(fmt.Sprintf("/api/subreddits/%s/automod", subreddit), &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// SetAutoModRules replaces the AutoModerator rules of a subreddit
+func (c *APIClient) SetAutoModRules(subreddit string, rules []AutoModRuleConfig) error {
+	return c.send("PUT", fmt.Sprintf("/api/subreddits/%s/automod", subreddit), AutoModRulesRequest{Rules: rules}, nil)
+}
+
+// TestAutoModRules returns the AutoModerator rules a sample post or comment
+// would trigger, without creating it
+func (c *APIClient) TestAutoModRules(subreddit string, sample AutoModTestRequest) ([]AutoModRuleConfig, error) {
+	var response struct {
+		Data []AutoModRuleConfig `json:"data"`
+	}
+	if err := c.post(fmt.Sprintf("/api/subreddits/%s/automod/test", subreddit), sample, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// IterModLog iterates over the mod log of a subreddit, newest first.
+// moderator and action narrow the entries when not empty; items decode into
+// ModLogEntryResponse.
+func (c *APIClient) IterModLog(subreddit, moderator string, action ModLogAction) *ListingIterator {
+	query := url.Values{}
+	query.Set("mod", moderator)
+	query.Set("type", string(action))
+	return c.iterate(fmt.Sprintf("/api/subreddits/%s/log", subreddit), query)
+}
+
+// IterModQueue iterates over the items waiting for the moderators of a
+// subreddit; items decode into ModQueueItemResponse
+func (c *APIClient) IterModQueue(subreddit string) *ListingIterator {
+	return c.iterate(fmt.Sprintf("/api/subreddits/%s/modqueue", subreddit), url.Values{})
+}
+
+// IterMessages iterates over the client user's direct messages; items decode
+// into DirectMessage
+func (c *APIClient) IterMessages() *ListingIterator {
+	return c.iterate("/api/messages", url.Values{})
+}
+
+// IterUsers iterates over every registered user; items decode into UserResponse
+func (c *APIClient) IterUsers() *ListingIterator {
+	return c.iterate("/api/users", url.Values{})
+}
+
+// IterBlockedUsers iterates over the users the client user has blocked;
+// items decode into strings
+func (c *APIClient) IterBlockedUsers() *ListingIterator {
+	return c.iterate("/api/blocked", url.Values{})
+}
+
+// BlockUser stops username from messaging the client user or replying to
+// them, and hides their posts and comments
+func (c *APIClient) BlockUser(username string) error {
+	return c.post("/api/blocked", BlockUserRequest{Username: username}, nil)
+}
+
+// UnblockUser lifts a block
+func (c *APIClient) UnblockUser(username string) error {
+	return c.send("DELETE", fmt.Sprintf("/api/blocked/%s", username), nil, nil)
+}
+
+// SavePost bookmarks a post for the client user
+func (c *APIClient) SavePost(postID string) error {
+	return c.post(fmt.Sprintf("/api/posts/%s/save", postID), nil, nil)
+}
+
+// UnsavePost removes a post from the client user's bookmarks
+func (c *APIClient) UnsavePost(postID string) error {
+	return c.post(fmt.Sprintf("/api/posts/%s/unsave", postID), nil, nil)
+}
+
+// SaveComment bookmarks a comment for the client user
+func (c *APIClient) SaveComment(commentID string) error {
+	return c.post(fmt.Sprintf("/api/comments/%s/save", commentID), nil, nil)
+}
+
+// UnsaveComment removes a comment from the client user's bookmarks
+func (c *APIClient) UnsaveComment(commentID string) error {
+	return c.post(fmt.Sprintf("/api/comments/%s/unsave", commentID), nil, nil)
+}
+
+// HidePost keeps a post out of the client user's feed
+func (c *APIClient) HidePost(postID string) error {
+	return c.post(fmt.Sprintf("/api/posts/%s/hide", postID), nil, nil)
+}
+
+// UnhidePost puts a hidden post back in the client user's feed
+func (c *APIClient) UnhidePost(postID string) error {
+	return c.post(fmt.Sprintf("/api/posts/%s/unhide", postID), nil, nil)
+}
+
+// IterSaved iterates over the posts and comments the client user saved,
+// optionally only those from subreddit; items decode into SavedItemResponse
+func (c *APIClient) IterSaved(subreddit string) *ListingIterator {
+	params := url.Values{}
+	if subreddit != "" {
+		params.Set("subreddit", subreddit)
+	}
+	return c.iterate("/api/users/me/saved", params)
+}
+
+// IterHidden iterates over the posts the client user hid; items decode into
+// PostResponse
+func (c *APIClient) IterHidden() *ListingIterator {
+	return c.iterate("/api/users/me/hidden", url.Values{})
+}
+
+// SuspendUser suspends username site-wide for days, or permanently when days
+// is zero. Needs an admin client.
+func (c *APIClient) SuspendUser(username, reason string, days int) error {
+	data := SuspensionRequest{Reason: reason, DurationDays: days}
+	return c.post(fmt.Sprintf("/api/admin/users/%s/suspension", username), data, nil)
+}
+
+// UnsuspendUser lifts the suspension of username. Needs an admin client.
+func (c *APIClient) UnsuspendUser(username string) error {
+	return c.send("DELETE", fmt.Sprintf("/api/admin/users/%s/suspension", username), nil, nil)
+}
+
+// BanSubreddit shuts a subreddit down. Needs an admin client.
+func (c *APIClient) BanSubreddit(name, reason string) error {
+	return c.post(fmt.Sprintf("/api/admin/subreddits/%s/ban", name), SubredditBanRequest{Reason: reason}, nil)
+}
+
+// UnbanSubreddit reopens a banned subreddit. Needs an admin client.
+func (c *APIClient) UnbanSubreddit(name string) error {
+	return c.send("DELETE", fmt.Sprintf("/api/admin/subreddits/%s/ban", name), nil, nil)
+}
+
+// DeleteSubreddit removes a subreddit and everything in it. Needs an admin
+// client.
+func (c *APIClient) DeleteSubreddit(name string) error {
+	return c.send("DELETE", fmt.Sprintf("/api/admin/subreddits/%s", name), nil, nil)
+}
+
+// QuarantineSubreddit quarantines a subreddit, or lifts the quarantine when
+// quarantine is false. Needs an admin client.
+func (c *APIClient) QuarantineSubreddit(name string, quarantine bool) error {
+	endpoint := fmt.Sprintf("/api/admin/subreddits/%s/quarantine", name)
+	if !quarantine {
+		return c.send("DELETE", endpoint, nil, nil)
+	}
+	return c.post(endpoint, nil, nil)
+}
+
+// IterComments iterates over the top-level comments of a post; items decode
+// into CommentResponse
+func (c *APIClient) IterComments(postID string) *ListingIterator {
+	return c.iterate(fmt.Sprintf("/api/posts/%s/comments", postID), url.Values{})
+}
+
+// ListingIterator walks a paginated listing one item at a time, requesting
+// the next page with the after cursor once the current page is used up.
+//
+//	it := client.IterPosts(SortHot, TimeAll)
+//	for it.Next() {
+//		var post PostResponse
+//		it.Decode(&post)
+//	}
+//	if err := it.Err(); err != nil { ... }
+type ListingIterator struct {
+	client   *APIClient
+	endpoint string
+	query    url.Values
+	page     []json.RawMessage
+	current  json.RawMessage
+	after    string
+	count    int
+	done     bool
+	err      error
+}
+
+func (c *APIClient) iterate(endpoint string, query url.Values) *ListingIterator {
+	query.Set("limit", strconv.Itoa(maxPageLimit))
+	return &ListingIterator{
+		client:   c,
+		endpoint: endpoint,
+		query:    query,
+	}
+}
+
+// Next advances to the next item, fetching a new page when needed. It returns
+// false at the end of the listing or after an error.
+func (it *ListingIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.fetch()
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Decode unmarshals the current item into v
+func (it *ListingIterator) Decode(v interface{}) error {
+	return json.Unmarshal(it.current, v)
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *ListingIterator) Err() error {
+	return it.err
+}
+
+func (it *ListingIterator) fetch() {
+	if it.after != "" {
+		it.query.Set("after", it.after)
+		it.query.Set("count", strconv.Itoa(it.count))
+	}
+
+	var response struct {
+		Data  []json.RawMessage `json:"data"`
+		After string            `json:"after"`
+	}
+	if err := it.client.get(it.endpoint+"?"+it.query.Encode(), &response); err != nil {
+		it.err = err
+		return
+	}
+
+	it.page = response.Data
+	it.count += len(response.Data)
+	it.after = response.After
+	if it.after == "" || len(response.Data) == 0 {
+		it.done = true
+	}
+}
+
+// VotePost casts a vote on a post. direction is VoteUp, VoteDown or VoteNone
+// to withdraw an earlier vote.
+func (c *APIClient) VotePost(postID string, direction int) (*VoteResult, error) {
+	data := VoteRequest{Upvote: direction == VoteUp, Direction: &direction}
+	var response struct {
+		Data VoteResponse `json:"data"`
+	}
+	if err := c.post(fmt.Sprintf("/api/posts/%s/vote", postID), data, &response); err != nil {
+		return nil, err
+	}
+	return &VoteResult{
+		Upvotes:   response.Data.Upvotes,
+		Downvotes: response.Data.Downvotes,
+		Score:     response.Data.Score,
+	}, nil
+}
+
+// VoteComment casts a vote on a comment with the same directions as VotePost
+func (c *APIClient) VoteComment(commentID string, direction int) (*VoteResult, error) {
+	data := VoteRequest{Upvote: direction == VoteUp, Direction: &direction}
+	var response struct {
+		Data VoteResponse `json:"data"`
+	}
+	if err := c.post(fmt.Sprintf("/api/comments/%s/vote", commentID), data, &response); err != nil {
+		return nil, err
+	}
+	return &VoteResult{
+		Upvotes:   response.Data.Upvotes,
+		Downvotes: response.Data.Downvotes,
+		Score:     response.Data.Score,
+	}, nil
 }
 
 // Helper methods for HTTP requests
 func (c *APIClient) post(endpoint string, data interface{}, response interface{}) error {
+	return c.send("POST", endpoint, data, response)
+}
+
+// send issues a request with a JSON body, decoding the reply into response
+// when it is not nil
+func (c *APIClient) send(method, endpoint string, data interface{}, response interface{}) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+endpoint, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest(method, c.baseURL+endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Username", c.username)
+	c.authorize(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -142,9 +1029,7 @@ func (c *APIClient) post(endpoint string, data interface{}, response interface{}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		var errResp ErrorResponse
-		json.NewDecoder(resp.Body).Decode(&errResp)
-		return fmt.Errorf(errResp.Message)
+		return decodeAPIError(resp)
 	}
 
 	if response != nil {
@@ -153,13 +1038,64 @@ func (c *APIClient) post(endpoint string, data interface{}, response interface{}
 	return nil
 }
 
+// APIError is an error response returned by the server. errors.Is matches
+// it against the engine's sentinel errors by its code, so callers can write
+// errors.Is(err, ErrNotFound).
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RetryAfter time.Duration // set for rate limit errors
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.StatusCode, e.Code)
+}
+
+// apiErrorKinds maps the error codes written by the server to sentinel errors
+var apiErrorKinds = map[string]error{
+	"VALIDATION_FAILED": ErrValidation,
+	"UNAUTHORIZED":      ErrUnauthorized,
+	"FORBIDDEN":         ErrForbidden,
+	"NOT_FOUND":         ErrNotFound,
+	"ALREADY_EXISTS":    ErrAlreadyExists,
+	"CONFLICT":          ErrConflict,
+	"RATE_LIMITED":      ErrRateLimited,
+}
+
+func (e *APIError) Is(target error) bool {
+	kind, ok := apiErrorKinds[e.Code]
+	return ok && kind == target
+}
+
+// decodeAPIError reads the error response of a failed request
+func decodeAPIError(resp *http.Response) error {
+	var errResp ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Message == "" {
+		errResp.Message = resp.Status
+	}
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Code:       errResp.Code,
+		Message:    errResp.Message,
+		RetryAfter: time.Duration(errResp.RetryAfter) * time.Second,
+	}
+}
+
+// authorize attaches the session token, if any, to a request
+func (c *APIClient) authorize(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
+
 func (c *APIClient) get(endpoint string, response interface{}) error {
 	req, err := http.NewRequest("GET", c.baseURL+endpoint, nil)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Username", c.username)
+	c.authorize(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -168,9 +1104,7 @@ func (c *APIClient) get(endpoint string, response interface{}) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		var errResp ErrorResponse
-		json.NewDecoder(resp.Body).Decode(&errResp)
-		return fmt.Errorf(errResp.Message)
+		return decodeAPIError(resp)
 	}
 
 	return json.NewDecoder(resp.Body).Decode(response)
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// CrosspostParentResponse describes the original of a crosspost. Only
+// Unavailable is set when the viewer cannot see the original, when it was
+// removed or when it is gone with its subreddit.
+type CrosspostParentResponse struct {
+	ID          string `json:"id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Author      string `json:"author,omitempty"`
+	Subreddit   string `json:"subreddit,omitempty"`
+	Deleted     bool   `json:"deleted"`
+	Unavailable bool   `json:"unavailable"`
+}
+
+func newCrosspostParentResponse(original *Post, canSeeOriginal bool) *CrosspostParentResponse {
+	if original == nil || !canSeeOriginal {
+		return &CrosspostParentResponse{Unavailable: true}
+	}
+
+	original.mu.RLock()
+	defer original.mu.RUnlock()
+
+	return &CrosspostParentResponse{
+		ID:        original.ID,
+		Title:     original.Title,
+		Author:    original.Author,
+		Subreddit: original.Subreddit,
+		Deleted:   original.Deleted,
+	}
+}
+
+func (s *APIServer) handleCrosspost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+	username := currentUser(r)
+
+	var req CrosspostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	post, err := s.engine.CrosspostPost(username, postID, req.Subreddit, req.Title, req.FlairTemplateID, req.FlairText)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to crosspost: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s crossposted %s to %s", username, postID, req.Subreddit),
+		Data:    s.newPostResponse(post, username),
+	})
+}
+
+func (s *APIServer) handleGetCrossposts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	originalID := vars["id"]
+	username := currentUser(r)
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	crossposts, err := s.engine.GetCrossposts(originalID, username)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get crossposts: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	crossposts, info, err := paginate(crossposts, kindPost, postID, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get crossposts: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	posts := make([]PostResponse, 0)
+	for _, post := range crossposts {
+		posts = append(posts, s.newPostResponse(post, username))
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d crossposts of %s", len(posts), originalID), posts, info))
+}
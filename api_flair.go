@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// FlairTemplateResponse is the JSON form of a FlairTemplate
+type FlairTemplateResponse struct {
+	ID       string    `json:"id"`
+	Kind     FlairKind `json:"kind"`
+	Text     string    `json:"text"`
+	Color    string    `json:"color,omitempty"`
+	Editable bool      `json:"editable"`
+	ModOnly  bool      `json:"mod_only"`
+}
+
+func newFlairTemplateResponse(template *FlairTemplate) FlairTemplateResponse {
+	return FlairTemplateResponse{
+		ID:       template.ID,
+		Kind:     template.Kind,
+		Text:     template.Text,
+		Color:    template.Color,
+		Editable: template.Editable,
+		ModOnly:  template.ModOnly,
+	}
+}
+
+// flairFilter applies the ?flair= filter of a listing request, keeping the
+// posts whose flair text matches it
+func flairFilter(r *http.Request, posts []*Post) []*Post {
+	if flair := r.URL.Query().Get("flair"); flair != "" {
+		return postsWithFlair(posts, flair)
+	}
+	return posts
+}
+
+func (s *APIServer) handleGetFlairTemplates(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+
+	kind, err := ParseFlairKind(r.URL.Query().Get("kind"))
+	var templates []*FlairTemplate
+	if err == nil {
+		templates, err = s.engine.GetFlairTemplates(subredditName, currentUser(r), kind)
+	}
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get flair templates: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	response := make([]FlairTemplateResponse, 0, len(templates))
+	for _, template := range templates {
+		response = append(response, newFlairTemplateResponse(template))
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Retrieved %d %s flair templates of '%s'", len(response), kind, subredditName),
+		Data:    response,
+	})
+}
+
+func (s *APIServer) handleAddFlairTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	var req FlairTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	template, err := s.engine.AddFlairTemplate(subredditName, username, FlairTemplate{
+		Kind:     FlairKind(req.Kind),
+		Text:     req.Text,
+		Color:    req.Color,
+		Editable: req.Editable,
+		ModOnly:  req.ModOnly,
+	})
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to add flair template: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s added %s flair %q to '%s'", username, template.Kind, template.Text, subredditName),
+		Data:    newFlairTemplateResponse(template),
+	})
+}
+
+func (s *APIServer) handleRemoveFlairTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	templateID := vars["id"]
+	username := currentUser(r)
+
+	if err := s.engine.RemoveFlairTemplate(subredditName, username, templateID); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to remove flair template: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s removed flair template %s from '%s'", username, templateID, subredditName),
+	})
+}
+
+// decodeFlairRequest reads an optional FlairRequest body. An empty body or
+// template ID clears the flair.
+func decodeFlairRequest(r *http.Request) (FlairRequest, error) {
+	var req FlairRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return req, err
+	}
+	return req, nil
+}
+
+func (s *APIServer) handleSetPostFlair(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+	username := currentUser(r)
+
+	req, err := decodeFlairRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := s.engine.SetPostFlair(postID, username, req.TemplateID, req.Text); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to set post flair: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s set the flair of post %s", username, postID),
+	})
+}
+
+func (s *APIServer) handleSetUserFlair(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	target := vars["username"]
+	username := currentUser(r)
+
+	req, err := decodeFlairRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := s.engine.SetUserFlair(subredditName, username, target, req.TemplateID, req.Text); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to set user flair: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s set the flair of %s in '%s'", username, target, subredditName),
+	})
+}
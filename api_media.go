@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// multipartOverhead is room for the form fields and boundaries of an image
+// upload on top of the image itself
+const multipartOverhead = 1 << 20
+
+// ImageResponse is the JSON form of an uploaded image
+type ImageResponse struct {
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	MIMEType     string `json:"mime_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Size         int64  `json:"size"`
+}
+
+func newImageResponse(media *Media) ImageResponse {
+	return ImageResponse{
+		URL:          "/media/" + media.FileName(),
+		ThumbnailURL: "/media/" + media.ThumbnailName(),
+		MIMEType:     media.MIMEType,
+		Width:        media.Width,
+		Height:       media.Height,
+		Size:         media.Size,
+	}
+}
+
+// isMultipart reports whether a request carries a multipart form
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// handleUploadImagePost creates an image post from a multipart form with
+// title, subreddit and optional content and flair fields and the file in
+// "image"
+func (s *APIServer) handleUploadImagePost(w http.ResponseWriter, r *http.Request) {
+	maxSize := int64(DefaultMaxImageSize)
+	if store := s.engine.Media(); store != nil {
+		maxSize = store.MaxSize()
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+multipartOverhead)
+
+	if err := r.ParseMultipartForm(multipartOverhead); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = &ValidationError{Field: "image", Reason: fmt.Sprintf("is larger than %d bytes", maxSize)}
+			writeJSON(w, ErrorResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("Failed to create post: %v", err),
+				Err:     err,
+			})
+			return
+		}
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		err = &ValidationError{Field: "image", Reason: "is required"}
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to create post: %v", err),
+			Err:     err,
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	username := currentUser(r)
+	subredditName := r.FormValue("subreddit")
+	post, err := s.engine.SubmitPost(username, subredditName, PostSubmission{
+		Kind:            PostImage,
+		Title:           r.FormValue("title"),
+		Content:         r.FormValue("content"),
+		Image:           data,
+		FlairTemplateID: r.FormValue("flair_template_id"),
+		FlairText:       r.FormValue("flair_text"),
+	})
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to create post: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Post created by %s in %s", username, subredditName),
+		Data:    s.newPostResponse(post, username),
+	})
+}
+
+// handleGetMedia serves an uploaded image or thumbnail. Files are named by
+// their content, so they can be cached forever.
+func (s *APIServer) handleGetMedia(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	store := s.engine.Media()
+	if store == nil {
+		err := &NotFoundError{Kind: "media", ID: name}
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get media: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	file, err := store.Open(name)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get media: %v", err),
+			Err:     err,
+		})
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get media: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+func (s *APIServer) handleGetDomainPosts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	domain := vars["domain"]
+
+	sortMode, window, err := parseListingSort(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	posts, err := s.engine.GetDomainPosts(domain, currentUser(r), sortMode, window)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get posts: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	posts, info, err := paginate(flairFilter(r, posts), kindPost, postID, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get posts: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	prettifiedPosts := make([]PostResponse, 0)
+	for _, post := range posts {
+		prettifiedPosts = append(prettifiedPosts, s.newPostResponse(post, currentUser(r)))
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d posts from %s", len(posts), domain), prettifiedPosts, info))
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ModeratorResponse is the JSON form of a Moderator
+type ModeratorResponse struct {
+	Username    string    `json:"username"`
+	Permissions []string  `json:"permissions"`
+	AddedBy     string    `json:"added_by"`
+	AddedAt     time.Time `json:"added_at"`
+}
+
+// ModInviteResponse is the JSON form of a ModInvite
+type ModInviteResponse struct {
+	Username    string    `json:"username"`
+	Permissions []string  `json:"permissions"`
+	InvitedBy   string    `json:"invited_by"`
+	InvitedAt   time.Time `json:"invited_at"`
+}
+
+func (s *APIServer) handleGetModerators(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+
+	moderators, err := s.engine.GetModerators(subredditName)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get moderators: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	moderatorList := make([]ModeratorResponse, 0)
+	for _, mod := range moderators {
+		moderatorList = append(moderatorList, ModeratorResponse{
+			Username:    mod.Username,
+			Permissions: mod.Permissions.Names(),
+			AddedBy:     mod.AddedBy,
+			AddedAt:     mod.AddedAt,
+		})
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Retrieved %d moderators of '%s'", len(moderatorList), subredditName),
+		Data:    moderatorList,
+	})
+}
+
+func (s *APIServer) handleAddModerator(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	var req ModeratorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	perms, err := ParseModPermissions(req.Permissions)
+	if err == nil {
+		err = s.engine.AddModerator(subredditName, username, req.Username, perms)
+	}
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to add moderator: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s made %s a moderator of '%s'", username, req.Username, subredditName),
+	})
+}
+
+func (s *APIServer) handleGetModeratorInvites(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	invites, err := s.engine.GetModeratorInvites(subredditName, username)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get moderator invites: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	inviteList := make([]ModInviteResponse, 0)
+	for _, invite := range invites {
+		inviteList = append(inviteList, ModInviteResponse{
+			Username:    invite.Username,
+			Permissions: invite.Permissions.Names(),
+			InvitedBy:   invite.InvitedBy,
+			InvitedAt:   invite.InvitedAt,
+		})
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Retrieved %d pending moderator invites for '%s'", len(inviteList), subredditName),
+		Data:    inviteList,
+	})
+}
+
+func (s *APIServer) handleInviteModerator(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	var req ModeratorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	perms, err := ParseModPermissions(req.Permissions)
+	if err == nil {
+		err = s.engine.InviteModerator(subredditName, username, req.Username, perms)
+	}
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to invite moderator: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s invited %s to moderate '%s'", username, req.Username, subredditName),
+	})
+}
+
+func (s *APIServer) handleAcceptModeratorInvite(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	if err := s.engine.AcceptModeratorInvite(subredditName, username); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to accept moderator invite: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s is now a moderator of '%s'", username, subredditName),
+	})
+}
+
+func (s *APIServer) handleRemoveModerator(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	target := vars["username"]
+	username := currentUser(r)
+
+	if err := s.engine.RemoveModerator(subredditName, username, target); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to remove moderator: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s removed %s from the moderators of '%s'", username, target, subredditName),
+	})
+}
+
+// RestrictionResponse is the JSON form of a ban or mute
+type RestrictionResponse struct {
+	Username  string     `json:"username"`
+	Reason    string     `json:"reason"`
+	IssuedBy  string     `json:"issued_by"`
+	IssuedAt  time.Time  `json:"issued_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // absent for permanent restrictions
+}
+
+func newRestrictionResponse(r *Restriction) RestrictionResponse {
+	response := RestrictionResponse{
+		Username: r.Username,
+		Reason:   r.Reason,
+		IssuedBy: r.IssuedBy,
+		IssuedAt: r.IssuedAt,
+	}
+	if !r.ExpiresAt.IsZero() {
+		expiresAt := r.ExpiresAt
+		response.ExpiresAt = &expiresAt
+	}
+	return response
+}
+
+// restrictionVerbs holds the past tense used in messages for each kind
+var restrictionVerbs = map[RestrictionKind]string{
+	RestrictionBan:  "banned",
+	RestrictionMute: "muted",
+}
+
+func (s *APIServer) handleGetBans(w http.ResponseWriter, r *http.Request) {
+	s.handleGetRestrictions(w, r, RestrictionBan, s.engine.GetBans)
+}
+
+func (s *APIServer) handleGetMutes(w http.ResponseWriter, r *http.Request) {
+	s.handleGetRestrictions(w, r, RestrictionMute, s.engine.GetMutes)
+}
+
+func (s *APIServer) handleBanUser(w http.ResponseWriter, r *http.Request) {
+	s.handleRestrictUser(w, r, RestrictionBan, s.engine.BanUser)
+}
+
+func (s *APIServer) handleMuteUser(w http.ResponseWriter, r *http.Request) {
+	s.handleRestrictUser(w, r, RestrictionMute, s.engine.MuteUser)
+}
+
+func (s *APIServer) handleUnbanUser(w http.ResponseWriter, r *http.Request) {
+	s.handleLiftRestriction(w, r, RestrictionBan, s.engine.UnbanUser)
+}
+
+func (s *APIServer) handleUnmuteUser(w http.ResponseWriter, r *http.Request) {
+	s.handleLiftRestriction(w, r, RestrictionMute, s.engine.UnmuteUser)
+}
+
+func (s *APIServer) handleGetRestrictions(w http.ResponseWriter, r *http.Request, kind RestrictionKind, list func(subredditName, actor string) ([]*Restriction, error)) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	restrictions, err := list(subredditName, username)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get %ss: %v", kind, err),
+			Err:     err,
+		})
+		return
+	}
+
+	restrictions, info, err := paginate(restrictions, kindUser, func(r *Restriction) string { return r.Username }, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get %ss: %v", kind, err),
+			Err:     err,
+		})
+		return
+	}
+
+	restrictionList := make([]RestrictionResponse, 0)
+	for _, restriction := range restrictions {
+		restrictionList = append(restrictionList, newRestrictionResponse(restriction))
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d %ss in '%s'", len(restrictionList), kind, subredditName), restrictionList, info))
+}
+
+func (s *APIServer) handleRestrictUser(w http.ResponseWriter, r *http.Request, kind RestrictionKind, restrict func(subredditName, actor, username, reason string, duration time.Duration) error) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	var req RestrictionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	duration := time.Duration(req.DurationDays) * 24 * time.Hour
+	if err := restrict(subredditName, username, req.Username, req.Reason, duration); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to %s user: %v", kind, err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s %s %s in '%s'", username, restrictionVerbs[kind], req.Username, subredditName),
+	})
+}
+
+func (s *APIServer) handleLiftRestriction(w http.ResponseWriter, r *http.Request, kind RestrictionKind, lift func(subredditName, actor, username string) error) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	target := vars["username"]
+	username := currentUser(r)
+
+	if err := lift(subredditName, username, target); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to lift %s: %v", kind, err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s lifted the %s of %s in '%s'", username, kind, target, subredditName),
+	})
+}
+
+// ModLogEntryResponse is the JSON form of a ModLogEntry
+type ModLogEntryResponse struct {
+	ID        string    `json:"id"`
+	Moderator string    `json:"moderator"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *APIServer) handleGetModLog(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+	query := r.URL.Query()
+
+	action, err := ParseModLogAction(query.Get("type"))
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	entries, err := s.engine.GetModLog(subredditName, username, query.Get("mod"), action)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get mod log: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	entries, info, err := paginate(entries, kindModLog, func(entry *ModLogEntry) string { return entry.ID }, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get mod log: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	entryList := make([]ModLogEntryResponse, 0)
+	for _, entry := range entries {
+		entryList = append(entryList, ModLogEntryResponse{
+			ID:        entry.ID,
+			Moderator: entry.Actor,
+			Action:    string(entry.Action),
+			Target:    entry.Target,
+			Reason:    entry.Reason,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d mod log entries of '%s'", len(entryList), subredditName), entryList, info))
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ReportResponse is the JSON form of a Report. Reporters stay anonymous.
+type ReportResponse struct {
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ModQueueItemResponse is the JSON form of a ModQueueItem
+type ModQueueItemResponse struct {
+	Kind      string           `json:"kind"` // "post" or "comment"
+	ID        string           `json:"id"`
+	PostID    string           `json:"post_id"`
+	Title     string           `json:"title,omitempty"`
+	Author    string           `json:"author"`
+	Content   string           `json:"content"`
+	CreatedAt time.Time        `json:"created_at"`
+	Filtered  bool             `json:"filtered"`
+	Reports   []ReportResponse `json:"reports"`
+}
+
+func newModQueueItemResponse(item *ModQueueItem) ModQueueItemResponse {
+	var response ModQueueItemResponse
+	var status *ModStatus
+
+	if post := item.Post; post != nil {
+		post.mu.RLock()
+		defer post.mu.RUnlock()
+		response = ModQueueItemResponse{
+			Kind:      "post",
+			ID:        post.ID,
+			PostID:    post.ID,
+			Title:     post.Title,
+			Author:    post.Author,
+			Content:   post.Content,
+			CreatedAt: post.CreatedAt,
+		}
+		status = &post.ModStatus
+	} else {
+		comment := item.Comment
+		comment.mu.RLock()
+		defer comment.mu.RUnlock()
+		response = ModQueueItemResponse{
+			Kind:      "comment",
+			ID:        comment.ID,
+			PostID:    comment.PostID,
+			Author:    comment.Author,
+			Content:   comment.Content,
+			CreatedAt: comment.CreatedAt,
+		}
+		status = &comment.ModStatus
+	}
+
+	response.Filtered = status.Filtered
+	response.Reports = make([]ReportResponse, 0)
+	for _, report := range status.Reports {
+		response.Reports = append(response.Reports, ReportResponse{
+			Reason:    report.Reason,
+			CreatedAt: report.CreatedAt,
+		})
+	}
+	return response
+}
+
+func modQueueItemID(item *ModQueueItem) string {
+	if item.Post != nil {
+		return item.Post.ID
+	}
+	return item.Comment.ID
+}
+
+// commentView is how the user of a request sees a comment thread
+type commentView struct {
+	showRemoved bool             // the user may see removed content
+	flair       map[string]Flair // user flair of the authors in the subreddit
+}
+
+// masked reports whether the user sees a placeholder instead of a comment.
+// Callers must hold c.mu.
+func (v commentView) masked(c *Comment) bool {
+	return (c.hidden() && !v.showRemoved) || c.Blocked
+}
+
+// leftOut reports whether a comment should be left out of a thread: it is
+// masked and so are all of its replies. Masked comments with visible replies
+// stay as "[removed]" or "[blocked]" placeholders so the replies remain
+// reachable.
+func (v commentView) leftOut(c *Comment) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !v.masked(c) {
+		return false
+	}
+	for _, child := range c.Children {
+		if !v.leftOut(child) {
+			return false
+		}
+	}
+	return true
+}
+
+// canModeratePost reports whether the user of a request may see removed
+// content of a post
+func (s *APIServer) canModeratePost(r *http.Request, postID string) bool {
+	post, err := s.engine.GetPost(postID)
+	if err != nil {
+		return false
+	}
+	return s.engine.HasModPermission(post.Subreddit, currentUser(r), PermPosts)
+}
+
+// commentView returns how the user of a request sees the comments of a post
+func (s *APIServer) commentView(r *http.Request, postID string) commentView {
+	view := commentView{
+		showRemoved: s.canModeratePost(r, postID),
+	}
+	if post, err := s.engine.GetPost(postID); err == nil {
+		view.flair, _ = s.engine.GetUserFlairs(post.Subreddit)
+	}
+	return view
+}
+
+func (s *APIServer) handleReportPost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+	username := currentUser(r)
+
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := s.engine.ReportPost(postID, username, req.Reason); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to report post: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s reported post %s", username, postID),
+	})
+}
+
+func (s *APIServer) handleReportComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	commentID := vars["id"]
+	username := currentUser(r)
+
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := s.engine.ReportComment(commentID, username, req.Reason); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to report comment: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s reported comment %s", username, commentID),
+	})
+}
+
+func (s *APIServer) handleModeratePost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+	username := currentUser(r)
+
+	// The reason is optional, so an empty body is fine
+	var req ModerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	action, err := ParseModAction(vars["action"])
+	if err == nil {
+		err = s.engine.ModeratePost(postID, username, action, req.Reason)
+	}
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to moderate post: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s applied %s to post %s", username, action, postID),
+	})
+}
+
+func (s *APIServer) handleModerateComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	commentID := vars["id"]
+	username := currentUser(r)
+
+	// The reason is optional, so an empty body is fine
+	var req ModerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	action, err := ParseModAction(vars["action"])
+	if err == nil {
+		err = s.engine.ModerateComment(commentID, username, action, req.Reason)
+	}
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to moderate comment: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s applied %s to comment %s", username, action, commentID),
+	})
+}
+
+func (s *APIServer) handleGetModQueue(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+	username := currentUser(r)
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	queue, err := s.engine.GetModQueue(subredditName, username)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get mod queue: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	queue, info, err := paginate(queue, kindModQueue, modQueueItemID, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get mod queue: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	items := make([]ModQueueItemResponse, 0)
+	for _, item := range queue {
+		items = append(items, newModQueueItemResponse(item))
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d items from the mod queue of '%s'", len(items), subredditName), items, info))
+}
+
+func (s *APIServer) handleStickyPost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+	username := currentUser(r)
+
+	if err := s.engine.StickyPost(postID, username); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to sticky post: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s stickied post %s", username, postID),
+	})
+}
+
+func (s *APIServer) handleUnstickyPost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+	username := currentUser(r)
+
+	if err := s.engine.UnstickyPost(postID, username); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to unsticky post: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s unstickied post %s", username, postID),
+	})
+}
+
+func (s *APIServer) handleLockPost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+	username := currentUser(r)
+
+	// freeze_votes is optional, so an empty body is fine
+	var req LockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	if err := s.engine.LockPost(postID, username, req.FreezeVotes); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to lock post: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s locked post %s", username, postID),
+	})
+}
+
+func (s *APIServer) handleUnlockPost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+	username := currentUser(r)
+
+	if err := s.engine.UnlockPost(postID, username); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to unlock post: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s unlocked post %s", username, postID),
+	})
+}
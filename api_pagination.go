@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Listing page sizes, matching Reddit's defaults
+const (
+	defaultPageLimit = 25
+	maxPageLimit     = 100
+)
+
+// Cursor kinds, borrowed from Reddit's fullname prefixes
+const (
+	kindComment   = "t1"
+	kindUser      = "t2"
+	kindPost      = "t3"
+	kindMessage   = "t4"
+	kindSubreddit = "t5"
+	kindModQueue  = "mq" // posts and comments mixed
+	kindModLog    = "ml"
+	kindSaved     = "sv" // posts and comments mixed
+)
+
+// PageRequest holds the Reddit-style paging parameters of a listing request.
+// After and Before are opaque cursors returned by an earlier page; Count is
+// the number of items the caller has already seen.
+type PageRequest struct {
+	Limit  int
+	After  string
+	Before string
+	Count  int
+}
+
+// PageInfo describes where a page sits inside its listing
+type PageInfo struct {
+	After  string
+	Before string
+	Count  int
+}
+
+// parsePageRequest reads limit, after, before and count from the query string
+func parsePageRequest(r *http.Request) (PageRequest, error) {
+	query := r.URL.Query()
+	page := PageRequest{
+		Limit:  defaultPageLimit,
+		After:  query.Get("after"),
+		Before: query.Get("before"),
+	}
+
+	if page.After != "" && page.Before != "" {
+		return page, &ValidationError{Field: "after", Reason: "cannot be combined with before"}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return page, &ValidationError{Field: "limit", Reason: fmt.Sprintf("%q is not a positive number", value)}
+		}
+		if limit > maxPageLimit {
+			limit = maxPageLimit
+		}
+		page.Limit = limit
+	}
+
+	if value := query.Get("count"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			return page, &ValidationError{Field: "count", Reason: fmt.Sprintf("%q is not a non-negative number", value)}
+		}
+		page.Count = count
+	}
+
+	return page, nil
+}
+
+// encodeCursor turns an item ID and the item's position in its listing into
+// an opaque cursor for the given kind
+func encodeCursor(kind, id string, position int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(kind + "_" + strconv.Itoa(position) + "_" + id))
+}
+
+// decodeCursor reverses encodeCursor, rejecting cursors of another kind
+func decodeCursor(kind, cursor string) (string, int, error) {
+	malformed := &ValidationError{Field: "cursor", Reason: fmt.Sprintf("%q is malformed", cursor)}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, malformed
+	}
+	rest, ok := strings.CutPrefix(string(raw), kind+"_")
+	if !ok {
+		return "", 0, malformed
+	}
+	value, id, ok := strings.Cut(rest, "_")
+	position, err := strconv.Atoi(value)
+	if !ok || err != nil || position < 0 || id == "" {
+		return "", 0, malformed
+	}
+	return id, position, nil
+}
+
+// paginate returns the page of items selected by page. Cursors name the item
+// they follow or precede, so they stay valid while new items are added to
+// the listing. When that item has left the listing, because it was removed,
+// deleted or hidden from the viewer, the page resumes from the position the
+// item had instead. id must return a stable, unique ID for each item.
+func paginate[T any](items []T, kind string, id func(T) string, page PageRequest) ([]T, PageInfo, error) {
+	// indexOf returns the index of the cursor's item, or where it was when
+	// the item is gone: the index of the item that took its place
+	indexOf := func(cursor string) (index int, found bool, err error) {
+		target, position, err := decodeCursor(kind, cursor)
+		if err != nil {
+			return 0, false, err
+		}
+		for i, item := range items {
+			if id(item) == target {
+				return i, true, nil
+			}
+		}
+		return min(position, len(items)), false, nil
+	}
+
+	start, end := 0, len(items)
+	switch {
+	case page.After != "":
+		i, found, err := indexOf(page.After)
+		if err != nil {
+			return nil, PageInfo{}, err
+		}
+		start = i
+		if found {
+			start++
+		}
+	case page.Before != "":
+		i, _, err := indexOf(page.Before)
+		if err != nil {
+			return nil, PageInfo{}, err
+		}
+		end = i
+		start = max(0, end-page.Limit)
+	}
+	end = min(end, start+page.Limit)
+
+	info := PageInfo{Count: page.Count + end - start}
+	if end > start {
+		if end < len(items) {
+			info.After = encodeCursor(kind, id(items[end-1]), end-1)
+		}
+		if start > 0 {
+			info.Before = encodeCursor(kind, id(items[start]), start)
+		}
+	}
+	return items[start:end], info, nil
+}
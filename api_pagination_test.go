@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+	identity := func(item string) string { return item }
+	cursor := func(id string, position int) string { return encodeCursor(kindPost, id, position) }
+
+	tests := []struct {
+		name       string
+		items      []string
+		page       PageRequest
+		want       []string
+		wantAfter  string
+		wantBefore string
+		wantCount  int
+	}{
+		{
+			name:      "first page",
+			items:     items,
+			page:      PageRequest{Limit: 2},
+			want:      []string{"a", "b"},
+			wantAfter: cursor("b", 1),
+			wantCount: 2,
+		},
+		{
+			name:       "after a cursor",
+			items:      items,
+			page:       PageRequest{Limit: 2, After: cursor("b", 1), Count: 2},
+			want:       []string{"c", "d"},
+			wantAfter:  cursor("d", 3),
+			wantBefore: cursor("c", 2),
+			wantCount:  4,
+		},
+		{
+			name:       "last page",
+			items:      items,
+			page:       PageRequest{Limit: 2, After: cursor("d", 3), Count: 4},
+			want:       []string{"e"},
+			wantBefore: cursor("e", 4),
+			wantCount:  5,
+		},
+		{
+			name:       "before a cursor",
+			items:      items,
+			page:       PageRequest{Limit: 2, Before: cursor("e", 4)},
+			want:       []string{"c", "d"},
+			wantAfter:  cursor("d", 3),
+			wantBefore: cursor("c", 2),
+			wantCount:  2,
+		},
+		{
+			name:      "before near the start",
+			items:     items,
+			page:      PageRequest{Limit: 2, Before: cursor("b", 1)},
+			want:      []string{"a"},
+			wantAfter: cursor("a", 0),
+			wantCount: 1,
+		},
+		{
+			name:       "after a vanished item",
+			items:      []string{"a", "b", "d", "e"},
+			page:       PageRequest{Limit: 2, After: cursor("c", 2)},
+			want:       []string{"d", "e"},
+			wantBefore: cursor("d", 2),
+			wantCount:  2,
+		},
+		{
+			name:      "before a vanished item",
+			items:     []string{"a", "b", "d", "e"},
+			page:      PageRequest{Limit: 2, Before: cursor("c", 2)},
+			want:      []string{"a", "b"},
+			wantAfter: cursor("b", 1),
+			wantCount: 2,
+		},
+		{
+			name:  "after a vanished item past the end",
+			items: []string{"a", "b"},
+			page:  PageRequest{Limit: 2, After: cursor("e", 4)},
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, info, err := paginate(tt.items, kindPost, identity, tt.page)
+			if err != nil {
+				t.Fatalf("paginate: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("page = %v, want %v", got, tt.want)
+			}
+			want := PageInfo{After: tt.wantAfter, Before: tt.wantBefore, Count: tt.wantCount}
+			if info != want {
+				t.Errorf("info = %+v, want %+v", info, want)
+			}
+		})
+	}
+}
+
+func TestPaginateMalformedCursor(t *testing.T) {
+	identity := func(item string) string { return item }
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "%%%"},
+		{"other kind", encodeCursor(kindComment, "a", 0)},
+		{"no position", "dDNfYQ"}, // "t3_a"
+		{"negative position", encodeCursor(kindPost, "a", -1)},
+		{"no id", encodeCursor(kindPost, "", 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := paginate([]string{"a"}, kindPost, identity, PageRequest{Limit: 1, After: tt.cursor})
+			if !errors.Is(err, ErrValidation) {
+				t.Errorf("paginate = %v, want %v", err, ErrValidation)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// PollResponse is the JSON form of PollResults. Votes per option are left
+// out until the viewer has voted or the poll has closed.
+type PollResponse struct {
+	Options    []PollOptionResponse `json:"options"`
+	TotalVotes int                  `json:"total_votes"`
+	UserVote   *int                 `json:"user_vote,omitempty"` // option the viewer chose
+	ClosesAt   time.Time            `json:"closes_at"`
+	Closed     bool                 `json:"closed"`
+}
+
+// PollOptionResponse is one option of a poll
+type PollOptionResponse struct {
+	Text  string `json:"text"`
+	Votes *int   `json:"votes,omitempty"`
+}
+
+func newPollResponse(results *PollResults) *PollResponse {
+	response := &PollResponse{
+		Options:    make([]PollOptionResponse, len(results.Options)),
+		TotalVotes: results.TotalVotes,
+		ClosesAt:   results.ClosesAt,
+		Closed:     results.Closed,
+	}
+	for i, text := range results.Options {
+		response.Options[i].Text = text
+		if results.Tallies != nil {
+			votes := results.Tallies[i]
+			response.Options[i].Votes = &votes
+		}
+	}
+	if results.Choice >= 0 {
+		choice := results.Choice
+		response.UserVote = &choice
+	}
+	return response
+}
+
+func (s *APIServer) handleVotePoll(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+	username := currentUser(r)
+
+	var req PollVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+	if req.Option == nil {
+		err := &ValidationError{Field: "option", Reason: "is required"}
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to vote in poll: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	results, err := s.engine.VotePoll(postID, username, *req.Option)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to vote in poll: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s voted for %q in post %s", username, results.Options[*req.Option], postID),
+		Data:    newPollResponse(results),
+	})
+}
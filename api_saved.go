@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// SavedItemResponse is the JSON form of a SavedItem. Exactly one of Post and
+// Comment is set.
+type SavedItemResponse struct {
+	Kind    string           `json:"kind"` // "post" or "comment"
+	SavedAt time.Time        `json:"saved_at"`
+	Post    *PostResponse    `json:"post,omitempty"`
+	Comment *CommentResponse `json:"comment,omitempty"`
+}
+
+func savedItemID(item *SavedItem) string {
+	if item.Post != nil {
+		return item.Post.ID
+	}
+	return item.Comment.ID
+}
+
+func (s *APIServer) newSavedItemResponse(r *http.Request, item *SavedItem) SavedItemResponse {
+	response := SavedItemResponse{SavedAt: item.SavedAt}
+	if item.Post != nil {
+		post := s.newPostResponse(item.Post, currentUser(r))
+		response.Kind, response.Post = "post", &post
+	} else {
+		comment := newCommentResponse(item.Comment, 0, s.commentView(r, item.Comment.PostID))
+		response.Kind, response.Comment = "comment", &comment
+	}
+	return response
+}
+
+func (s *APIServer) handleSavePost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+	action := vars["action"]
+	username := currentUser(r)
+
+	var err error
+	switch action {
+	case "save":
+		err = s.engine.SavePost(username, postID)
+	case "unsave":
+		err = s.engine.UnsavePost(username, postID)
+	case "hide":
+		err = s.engine.HidePost(username, postID)
+	case "unhide":
+		err = s.engine.UnhidePost(username, postID)
+	}
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to %s post: %v", action, err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s applied %s to post %s", username, action, postID),
+	})
+}
+
+func (s *APIServer) handleSaveComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	commentID := vars["id"]
+	action := vars["action"]
+	username := currentUser(r)
+
+	var err error
+	switch action {
+	case "save":
+		err = s.engine.SaveComment(username, commentID)
+	case "unsave":
+		err = s.engine.UnsaveComment(username, commentID)
+	}
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to %s comment: %v", action, err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s applied %s to comment %s", username, action, commentID),
+	})
+}
+
+func (s *APIServer) handleGetSaved(w http.ResponseWriter, r *http.Request) {
+	username := currentUser(r)
+	if username == "" {
+		writeAuthRequired(w)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	saved, err := s.engine.GetSavedItems(username, r.URL.Query().Get("subreddit"))
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get saved items: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	saved, info, err := paginate(saved, kindSaved, savedItemID, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get saved items: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	items := make([]SavedItemResponse, 0)
+	for _, item := range saved {
+		items = append(items, s.newSavedItemResponse(r, item))
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d saved items", len(items)), items, info))
+}
+
+func (s *APIServer) handleGetHidden(w http.ResponseWriter, r *http.Request) {
+	username := currentUser(r)
+	if username == "" {
+		writeAuthRequired(w)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	hidden, err := s.engine.GetHiddenPosts(username)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get hidden posts: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	hidden, info, err := paginate(hidden, kindPost, postID, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get hidden posts: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	posts := make([]PostResponse, 0)
+	for _, post := range hidden {
+		posts = append(posts, s.newPostResponse(post, username))
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d hidden posts", len(posts)), posts, info))
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -21,14 +24,217 @@ type APIServer struct {
 
 // Response structures
 type ErrorResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status     string `json:"status"`
+	Code       string `json:"code"` // machine-readable, filled in by writeJSON from Err
+	Message    string `json:"message"`
+	RetryAfter int    `json:"retry_after,omitempty"` // seconds, filled in by writeJSON for rate limits
+	Err        error  `json:"-"`                     // engine error deciding the HTTP status
 }
 
 type SuccessResponse struct {
 	Status  string      `json:"status"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
+	After   string      `json:"after,omitempty"`  // cursor of the next page of a listing
+	Before  string      `json:"before,omitempty"` // cursor of the previous page of a listing
+	Count   int         `json:"count,omitempty"`  // listing items seen up to and including this page
+}
+
+// PostResponse is the JSON form of a Post
+type PostResponse struct {
+	ID              string                   `json:"id"`
+	Title           string                   `json:"title"`
+	Author          string                   `json:"author"`
+	Content         string                   `json:"content"`
+	Kind            PostKind                 `json:"kind"`
+	URL             string                   `json:"url,omitempty"`
+	Domain          string                   `json:"domain,omitempty"`
+	Image           *ImageResponse           `json:"image,omitempty"`
+	Poll            *PollResponse            `json:"poll,omitempty"`
+	CrosspostParent *CrosspostParentResponse `json:"crosspost_parent,omitempty"`
+	NumCrossposts   int                      `json:"num_crossposts"`
+	Subreddit       string                   `json:"subreddit"`
+	Votes           int                      `json:"votes"`
+	Upvotes         int                      `json:"upvotes"`
+	Downvotes       int                      `json:"downvotes"`
+	NumComments     int                      `json:"num_comments"`
+	CreatedAt       time.Time                `json:"created_at"`
+	EditedAt        *time.Time               `json:"edited_at,omitempty"`
+	Deleted         bool                     `json:"deleted"`
+	Flair           string                   `json:"flair,omitempty"`
+	FlairColor      string                   `json:"flair_color,omitempty"`
+	FlairTemplateID string                   `json:"flair_template_id,omitempty"`
+	Removed         bool                     `json:"removed"`
+	Stickied        bool                     `json:"stickied"`
+	Locked          bool                     `json:"locked"`
+	VotesFrozen     bool                     `json:"votes_frozen"`
+}
+
+// newPostResponse converts a post as viewer sees it, which matters for its
+// crosspost links and poll results
+func (s *APIServer) newPostResponse(post *Post, viewer string) PostResponse {
+	original, canSeeOriginal, numCrossposts := s.engine.CrosspostLinks(post, viewer)
+	var parent *CrosspostParentResponse
+	if post.Kind == PostCrosspost {
+		parent = newCrosspostParentResponse(original, canSeeOriginal)
+	}
+
+	post.mu.RLock()
+	defer post.mu.RUnlock()
+
+	response := PostResponse{
+		ID:              post.ID,
+		Title:           post.Title,
+		Author:          post.Author,
+		Content:         post.Content,
+		Kind:            post.Kind,
+		URL:             post.URL,
+		Domain:          post.Domain,
+		CrosspostParent: parent,
+		NumCrossposts:   numCrossposts,
+		Subreddit:       post.Subreddit,
+		Votes:           post.Votes,
+		Upvotes:         post.Upvotes,
+		Downvotes:       post.Downvotes,
+		NumComments:     countComments(post.Comments),
+		CreatedAt:       post.CreatedAt,
+		Deleted:         post.Deleted,
+		Flair:           post.Flair.Text,
+		FlairColor:      post.Flair.Color,
+		FlairTemplateID: post.Flair.TemplateID,
+		Removed:         post.Removed,
+		Stickied:        post.Stickied,
+		Locked:          post.Locked,
+		VotesFrozen:     post.VotesFrozen,
+	}
+	if !post.EditedAt.IsZero() {
+		editedAt := post.EditedAt
+		response.EditedAt = &editedAt
+	}
+	if post.Image != nil {
+		image := newImageResponse(post.Image)
+		response.Image = &image
+	}
+	if post.Poll != nil {
+		response.Poll = newPollResponse(post.Poll.results(viewer, time.Now()))
+	}
+	return response
+}
+
+// CommentResponse is the JSON form of a Comment and its replies
+type CommentResponse struct {
+	ID               string            `json:"id"`
+	PostID           string            `json:"post_id"`
+	ParentID         string            `json:"parent_id,omitempty"`
+	Content          string            `json:"content"`
+	Author           string            `json:"author"`
+	AuthorFlair      string            `json:"author_flair,omitempty"`
+	AuthorFlairColor string            `json:"author_flair_color,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	EditedAt         *time.Time        `json:"edited_at,omitempty"`
+	Deleted          bool              `json:"deleted"`
+	Removed          bool              `json:"removed"`
+	Votes            int               `json:"votes"`
+	Upvotes          int               `json:"upvotes"`
+	Downvotes        int               `json:"downvotes"`
+	Children         []CommentResponse `json:"children"`
+	MoreReplies      int               `json:"more_replies,omitempty"` // replies left out by a depth limit
+}
+
+// newCommentResponse converts a comment and up to depth levels of replies.
+// A negative depth includes the whole subtree. Comments the view masks, such
+// as removed ones or those by blocked authors, are replaced by placeholders
+// or, without visible replies, left out.
+func newCommentResponse(c *Comment, depth int, view commentView) CommentResponse {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	response := CommentResponse{
+		ID:        c.ID,
+		PostID:    c.PostID,
+		ParentID:  c.ParentID,
+		Content:   c.Content,
+		Author:    c.Author,
+		CreatedAt: c.CreatedAt,
+		Deleted:   c.Deleted,
+		Removed:   c.Removed,
+		Votes:     c.Votes,
+		Upvotes:   c.Upvotes,
+		Downvotes: c.Downvotes,
+		Children:  make([]CommentResponse, 0),
+	}
+	if !c.EditedAt.IsZero() {
+		editedAt := c.EditedAt
+		response.EditedAt = &editedAt
+	}
+	if c.hidden() && !view.showRemoved {
+		response.Author = removedMarker
+		response.Content = removedMarker
+		response.Removed = true
+	} else if flair, ok := view.flair[c.Author]; ok {
+		response.AuthorFlair = flair.Text
+		response.AuthorFlairColor = flair.Color
+	}
+
+	children := make([]*Comment, 0, len(c.Children))
+	for _, child := range c.Children {
+		if !view.leftOut(child) {
+			children = append(children, child)
+		}
+	}
+
+	if depth == 0 {
+		response.MoreReplies = len(children)
+		return response
+	}
+	for _, child := range children {
+		response.Children = append(response.Children, newCommentResponse(child, depth-1, view))
+	}
+	return response
+}
+
+// SubredditResponse is the JSON form of a Subreddit
+type SubredditResponse struct {
+	Name             string    `json:"name"`
+	Description      string    `json:"description"`
+	Creator          string    `json:"creator"`
+	CreatedAt        time.Time `json:"created_at"`
+	Type             string    `json:"type"`
+	Quarantined      bool      `json:"quarantined"`
+	RequirePostFlair bool      `json:"require_post_flair"`
+	Members          int       `json:"members"`
+	Posts            int       `json:"posts"`
+}
+
+func newSubredditResponse(subreddit *Subreddit) SubredditResponse {
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	return SubredditResponse{
+		Name:             subreddit.Name,
+		Description:      subreddit.Description,
+		Creator:          subreddit.Creator,
+		CreatedAt:        subreddit.CreatedAt,
+		Type:             string(subreddit.Type),
+		Quarantined:      subreddit.Quarantined,
+		RequirePostFlair: subreddit.RequirePostFlair,
+		Members:          len(subreddit.Members),
+		Posts:            len(subreddit.Posts),
+	}
+}
+
+// UserResponse is the public JSON form of a User
+type UserResponse struct {
+	Username     string    `json:"username"`
+	CreatedAt    time.Time `json:"created_at"`
+	Karma        int       `json:"karma"`
+	PostKarma    int       `json:"post_karma"`
+	CommentKarma int       `json:"comment_karma"`
+	Subreddits   int       `json:"subreddits"`
+	Admin        bool      `json:"admin"`
+	Suspended    bool      `json:"suspended"`
+	// SuspendedUntil is absent for permanent suspensions
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
 }
 
 func NewAPIServer(engine *RedditEngine) *APIServer {
@@ -41,32 +247,152 @@ func NewAPIServer(engine *RedditEngine) *APIServer {
 }
 
 func (s *APIServer) setupRoutes() {
+	s.router.Use(s.authMiddleware)
+
 	// Auth routes
 	s.router.HandleFunc("/api/register", s.handleRegister).Methods("POST")
+	s.router.HandleFunc("/api/login", s.handleLogin).Methods("POST")
+	s.router.HandleFunc("/api/logout", s.handleLogout).Methods("POST")
 
 	// Subreddit routes
 	s.router.HandleFunc("/api/subreddits", s.handleCreateSubreddit).Methods("POST")
+	s.router.HandleFunc("/api/subreddits", s.handleListSubreddits).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}", s.handleGetSubreddit).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}", s.handleUpdateSubreddit).Methods("PATCH")
+	s.router.HandleFunc("/api/subreddits/{name}/posts", s.handleGetSubredditPosts).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}/members", s.handleGetSubredditMembers).Methods("GET")
 	s.router.HandleFunc("/api/subreddits/{name}/join", s.handleJoinSubreddit).Methods("POST")
 	s.router.HandleFunc("/api/subreddits/{name}/leave", s.handleLeaveSubreddit).Methods("POST")
+	s.router.HandleFunc("/api/subreddits/{name}/quarantine_optin", s.handleOptInToQuarantine).Methods("POST")
+	s.router.HandleFunc("/api/subreddits/{name}/join_requests", s.handleRequestToJoin).Methods("POST")
+	s.router.HandleFunc("/api/subreddits/{name}/join_requests", s.handleGetJoinRequests).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}/join_requests/{username}/approve", s.handleApproveJoinRequest).Methods("POST")
+	s.router.HandleFunc("/api/subreddits/{name}/join_requests/{username}", s.handleDenyJoinRequest).Methods("DELETE")
+
+	// Moderation routes
+	s.router.HandleFunc("/api/subreddits/{name}/moderators", s.handleGetModerators).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}/moderators", s.handleAddModerator).Methods("POST")
+	s.router.HandleFunc("/api/subreddits/{name}/moderators/invites", s.handleGetModeratorInvites).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}/moderators/invite", s.handleInviteModerator).Methods("POST")
+	s.router.HandleFunc("/api/subreddits/{name}/moderators/accept", s.handleAcceptModeratorInvite).Methods("POST")
+	s.router.HandleFunc("/api/subreddits/{name}/moderators/{username}", s.handleRemoveModerator).Methods("DELETE")
+	s.router.HandleFunc("/api/subreddits/{name}/bans", s.handleGetBans).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}/bans", s.handleBanUser).Methods("POST")
+	s.router.HandleFunc("/api/subreddits/{name}/bans/{username}", s.handleUnbanUser).Methods("DELETE")
+	s.router.HandleFunc("/api/subreddits/{name}/mutes", s.handleGetMutes).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}/mutes", s.handleMuteUser).Methods("POST")
+	s.router.HandleFunc("/api/subreddits/{name}/mutes/{username}", s.handleUnmuteUser).Methods("DELETE")
+	s.router.HandleFunc("/api/subreddits/{name}/approved", s.handleGetApprovedUsers).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}/approved", s.handleApproveUser).Methods("POST")
+	s.router.HandleFunc("/api/subreddits/{name}/approved/{username}", s.handleUnapproveUser).Methods("DELETE")
+	s.router.HandleFunc("/api/subreddits/{name}/modqueue", s.handleGetModQueue).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}/log", s.handleGetModLog).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}/automod", s.handleGetAutoModRules).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}/automod", s.handleSetAutoModRules).Methods("PUT")
+	s.router.HandleFunc("/api/subreddits/{name}/automod/test", s.handleTestAutoModRules).Methods("POST")
+	s.router.HandleFunc("/api/subreddits/{name}/flair_templates", s.handleGetFlairTemplates).Methods("GET")
+	s.router.HandleFunc("/api/subreddits/{name}/flair_templates", s.handleAddFlairTemplate).Methods("POST")
+	s.router.HandleFunc("/api/subreddits/{name}/flair_templates/{id}", s.handleRemoveFlairTemplate).Methods("DELETE")
+	s.router.HandleFunc("/api/subreddits/{name}/user_flair/{username}", s.handleSetUserFlair).Methods("PUT")
+	s.router.HandleFunc("/api/posts/{id}/report", s.handleReportPost).Methods("POST")
+	s.router.HandleFunc("/api/posts/{id}/{action:approve|remove|ignore_reports}", s.handleModeratePost).Methods("POST")
+	s.router.HandleFunc("/api/posts/{id}/sticky", s.handleStickyPost).Methods("POST")
+	s.router.HandleFunc("/api/posts/{id}/unsticky", s.handleUnstickyPost).Methods("POST")
+	s.router.HandleFunc("/api/posts/{id}/lock", s.handleLockPost).Methods("POST")
+	s.router.HandleFunc("/api/posts/{id}/flair", s.handleSetPostFlair).Methods("PUT")
+	s.router.HandleFunc("/api/posts/{id}/unlock", s.handleUnlockPost).Methods("POST")
+	s.router.HandleFunc("/api/comments/{id}/report", s.handleReportComment).Methods("POST")
+	s.router.HandleFunc("/api/comments/{id}/{action:approve|remove|ignore_reports}", s.handleModerateComment).Methods("POST")
+
+	// Admin routes
+	s.router.HandleFunc("/api/admin/users/{username}/suspension", s.handleSuspendUser).Methods("POST")
+	s.router.HandleFunc("/api/admin/users/{username}/suspension", s.handleUnsuspendUser).Methods("DELETE")
+	s.router.HandleFunc("/api/admin/subreddits/{name}", s.handleDeleteSubreddit).Methods("DELETE")
+	s.router.HandleFunc("/api/admin/subreddits/{name}/ban", s.handleBanSubreddit).Methods("POST")
+	s.router.HandleFunc("/api/admin/subreddits/{name}/ban", s.handleUnbanSubreddit).Methods("DELETE")
+	s.router.HandleFunc("/api/admin/subreddits/{name}/quarantine", s.handleQuarantineSubreddit).Methods("POST")
+	s.router.HandleFunc("/api/admin/subreddits/{name}/quarantine", s.handleLiftQuarantine).Methods("DELETE")
 
 	// Post routes
 	s.router.HandleFunc("/api/posts", s.handleCreatePost).Methods("POST")
 	s.router.HandleFunc("/api/posts", s.handleGetPosts).Methods("GET")
+	s.router.HandleFunc("/api/posts/{id}", s.handleGetPost).Methods("GET")
+	s.router.HandleFunc("/api/posts/{id}", s.handleEditPost).Methods("PATCH")
+	s.router.HandleFunc("/api/posts/{id}", s.handleDeletePost).Methods("DELETE")
 	s.router.HandleFunc("/api/posts/{id}/vote", s.handleVotePost).Methods("POST")
+	s.router.HandleFunc("/api/posts/{id}/poll", s.handleVotePoll).Methods("POST")
 	s.router.HandleFunc("/api/posts/{id}/comments", s.handleAddComment).Methods("POST")
+	s.router.HandleFunc("/api/posts/{id}/{action:save|unsave|hide|unhide}", s.handleSavePost).Methods("POST")
+	s.router.HandleFunc("/api/posts/{id}/crosspost", s.handleCrosspost).Methods("POST")
+	s.router.HandleFunc("/api/posts/{id}/crossposts", s.handleGetCrossposts).Methods("GET")
+	s.router.HandleFunc("/api/domains/{domain}/posts", s.handleGetDomainPosts).Methods("GET")
+	s.router.HandleFunc("/media/{name}", s.handleGetMedia).Methods("GET")
+
+	// Comment routes
+	s.router.HandleFunc("/api/comments/{id}", s.handleGetComment).Methods("GET")
+	s.router.HandleFunc("/api/comments/{id}", s.handleEditComment).Methods("PATCH")
+	s.router.HandleFunc("/api/comments/{id}", s.handleDeleteComment).Methods("DELETE")
+	s.router.HandleFunc("/api/comments/{id}/vote", s.handleVoteComment).Methods("POST")
+	s.router.HandleFunc("/api/comments/{id}/{action:save|unsave}", s.handleSaveComment).Methods("POST")
 
 	// Message routes
 	s.router.HandleFunc("/api/messages", s.handleSendMessage).Methods("POST")
 	s.router.HandleFunc("/api/messages", s.handleGetMessages).Methods("GET")
 	s.router.HandleFunc("/api/users", s.handleGetUsers).Methods("GET")
+	s.router.HandleFunc("/api/blocked", s.handleGetBlockedUsers).Methods("GET")
+	s.router.HandleFunc("/api/blocked", s.handleBlockUser).Methods("POST")
+	s.router.HandleFunc("/api/blocked/{username}", s.handleUnblockUser).Methods("DELETE")
+	s.router.HandleFunc("/api/users/me/saved", s.handleGetSaved).Methods("GET")
+	s.router.HandleFunc("/api/users/me/hidden", s.handleGetHidden).Methods("GET")
 
 	s.router.HandleFunc("/api/posts/{id}/comments", s.handleGetComments).Methods("GET")
 	s.router.HandleFunc("/api/stats", s.handleGetStats).Methods("GET")
 
 }
 
+// writeJSON encodes data as the response body. An ErrorResponse is sent with
+// the HTTP status and code matching the kind of its Err; one without Err is
+// a malformed request. Rate limit errors also set the Retry-After header.
 func writeJSON(w http.ResponseWriter, data interface{}) {
+	status := http.StatusOK
+	if resp, ok := data.(ErrorResponse); ok {
+		status, resp.Code = errorStatus(resp.Err)
+		var limited *RateLimitError
+		if errors.As(resp.Err, &limited) {
+			resp.RetryAfter = int(math.Ceil(limited.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(resp.RetryAfter))
+		}
+		data = resp
+	}
+	writeJSONStatus(w, status, data)
+}
+
+// errorStatus maps an engine error to its HTTP status and error code
+func errorStatus(err error) (int, string) {
+	switch {
+	case err == nil:
+		return http.StatusBadRequest, "BAD_REQUEST"
+	case errors.Is(err, ErrValidation):
+		return http.StatusBadRequest, "VALIDATION_FAILED"
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized, "UNAUTHORIZED"
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden, "FORBIDDEN"
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound, "NOT_FOUND"
+	case errors.Is(err, ErrAlreadyExists):
+		return http.StatusConflict, "ALREADY_EXISTS"
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict, "CONFLICT"
+	case errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests, "RATE_LIMITED"
+	}
+	return http.StatusInternalServerError, "INTERNAL_ERROR"
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "    ")
 	if err := encoder.Encode(data); err != nil {
@@ -107,6 +433,7 @@ func (s *APIServer) handleRegister(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, ErrorResponse{
 			Status:  "error",
 			Message: fmt.Sprintf("Failed to register user: %v", err),
+			Err:     err,
 		})
 		return
 	}
@@ -130,12 +457,16 @@ func (s *APIServer) handleCreateSubreddit(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	username := r.Header.Get("Username")
-	err := s.engine.CreateSubreddit(req.Name, req.Description, username)
+	username := currentUser(r)
+	subType, err := ParseSubredditType(req.Type)
+	if err == nil {
+		err = s.engine.CreateSubreddit(req.Name, req.Description, username, subType)
+	}
 	if err != nil {
 		writeJSON(w, ErrorResponse{
 			Status:  "error",
 			Message: fmt.Sprintf("Failed to create subreddit '%s': %v", req.Name, err),
+			Err:     err,
 		})
 		return
 	}
@@ -147,20 +478,173 @@ func (s *APIServer) handleCreateSubreddit(w http.ResponseWriter, r *http.Request
 			"name":        req.Name,
 			"creator":     username,
 			"description": req.Description,
+			"type":        string(subType),
 		},
 	})
 }
 
+func (s *APIServer) handleListSubreddits(w http.ResponseWriter, r *http.Request) {
+	order, err := ParseSubredditSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	subreddits := s.engine.ListSubreddits(r.URL.Query().Get("q"), order, currentUser(r))
+	subreddits, info, err := paginate(subreddits, kindSubreddit, func(sr *Subreddit) string { return sr.Name }, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to list subreddits: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	subredditList := make([]SubredditResponse, 0)
+	for _, subreddit := range subreddits {
+		subredditList = append(subredditList, newSubredditResponse(subreddit))
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d subreddits", len(subredditList)), subredditList, info))
+}
+
+func (s *APIServer) handleGetSubreddit(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+
+	subreddit, err := s.engine.GetSubreddit(subredditName)
+	if err == nil {
+		err = s.engine.CanViewSubreddit(subredditName, currentUser(r))
+	}
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get subreddit: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Retrieved subreddit '%s'", subredditName),
+		Data:    newSubredditResponse(subreddit),
+	})
+}
+
+func (s *APIServer) handleGetSubredditPosts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+
+	sortMode, window, err := parseListingSort(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	posts, err := s.engine.GetSubredditPosts(subredditName, currentUser(r), sortMode, window)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get posts: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	posts, info, err := paginate(flairFilter(r, posts), kindPost, postID, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get posts: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	prettifiedPosts := make([]PostResponse, 0)
+	for _, post := range posts {
+		prettifiedPosts = append(prettifiedPosts, s.newPostResponse(post, currentUser(r)))
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d posts from '%s'", len(posts), subredditName), prettifiedPosts, info))
+}
+
+func (s *APIServer) handleGetSubredditMembers(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	subredditName := vars["name"]
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	members, err := s.engine.GetSubredditMembers(subredditName, currentUser(r))
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get members: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	members, info, err := paginate(members, kindUser, func(username string) string { return username }, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get members: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d members of '%s'", len(members), subredditName), members, info))
+}
+
 func (s *APIServer) handleJoinSubreddit(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	subredditName := vars["name"]
-	username := r.Header.Get("Username")
+	username := currentUser(r)
 
 	err := s.engine.JoinSubreddit(username, subredditName)
 	if err != nil {
 		writeJSON(w, ErrorResponse{
 			Status:  "error",
 			Message: fmt.Sprintf("Failed to join subreddit: %v", err),
+			Err:     err,
 		})
 		return
 	}
@@ -174,13 +658,14 @@ func (s *APIServer) handleJoinSubreddit(w http.ResponseWriter, r *http.Request)
 func (s *APIServer) handleLeaveSubreddit(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	subredditName := vars["name"]
-	username := r.Header.Get("Username")
+	username := currentUser(r)
 
 	err := s.engine.LeaveSubreddit(username, subredditName)
 	if err != nil {
 		writeJSON(w, ErrorResponse{
 			Status:  "error",
 			Message: fmt.Sprintf("Failed to leave subreddit: %v", err),
+			Err:     err,
 		})
 		return
 	}
@@ -192,6 +677,11 @@ func (s *APIServer) handleLeaveSubreddit(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *APIServer) handleCreatePost(w http.ResponseWriter, r *http.Request) {
+	if isMultipart(r) {
+		s.handleUploadImagePost(w, r)
+		return
+	}
+
 	var req CreatePostRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, ErrorResponse{
@@ -201,12 +691,22 @@ func (s *APIServer) handleCreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username := r.Header.Get("Username")
-	post, err := s.engine.CreatePost(req.Title, req.Content, username, req.Subreddit)
+	username := currentUser(r)
+	post, err := s.engine.SubmitPost(username, req.Subreddit, PostSubmission{
+		Kind:            PostKind(req.Kind),
+		Title:           req.Title,
+		Content:         req.Content,
+		URL:             req.URL,
+		PollOptions:     req.PollOptions,
+		PollDuration:    time.Duration(req.PollDurationHours) * time.Hour,
+		FlairTemplateID: req.FlairTemplateID,
+		FlairText:       req.FlairText,
+	})
 	if err != nil {
 		writeJSON(w, ErrorResponse{
 			Status:  "error",
 			Message: fmt.Sprintf("Failed to create post: %v", err),
+			Err:     err,
 		})
 		return
 	}
@@ -214,55 +714,182 @@ func (s *APIServer) handleCreatePost(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, SuccessResponse{
 		Status:  "success",
 		Message: fmt.Sprintf("Post created by %s in %s", username, req.Subreddit),
-		Data:    post,
+		Data:    s.newPostResponse(post, username),
 	})
 }
 
 func (s *APIServer) handleGetPosts(w http.ResponseWriter, r *http.Request) {
-	username := r.Header.Get("Username")
-	posts, err := s.engine.GetUserFeed(username)
+	username := currentUser(r)
+	if username == "" {
+		writeAuthRequired(w)
+		return
+	}
+	sortMode, window, err := parseListingSort(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	posts, err := s.engine.GetUserFeed(username, sortMode, window)
 	if err != nil {
 		writeJSON(w, ErrorResponse{
 			Status:  "error",
 			Message: fmt.Sprintf("Failed to get posts: %v", err),
+			Err:     err,
 		})
 		return
 	}
 
-	type PostResponse struct {
-		ID        string    `json:"id"`
-		Title     string    `json:"title"`
-		Author    string    `json:"author"`
-		Content   string    `json:"content"`
-		Subreddit string    `json:"subreddit"`
-		Votes     int       `json:"votes"`
-		CreatedAt time.Time `json:"created_at"`
+	posts, info, err := paginate(flairFilter(r, posts), kindPost, postID, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get posts: %v", err),
+			Err:     err,
+		})
+		return
 	}
 
 	prettifiedPosts := make([]PostResponse, 0)
 	for _, post := range posts {
-		prettifiedPosts = append(prettifiedPosts, PostResponse{
-			ID:        post.ID,
-			Title:     post.Title,
-			Author:    post.Author,
-			Content:   post.Content,
-			Subreddit: post.Subreddit,
-			Votes:     post.Votes,
-			CreatedAt: post.CreatedAt,
+		prettifiedPosts = append(prettifiedPosts, s.newPostResponse(post, username))
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d posts for %s", len(posts), username), prettifiedPosts, info))
+}
+
+// listingResponse wraps one page of a listing in a SuccessResponse
+func listingResponse(message string, items interface{}, info PageInfo) SuccessResponse {
+	return SuccessResponse{
+		Status:  "success",
+		Message: message,
+		Data:    items,
+		After:   info.After,
+		Before:  info.Before,
+		Count:   info.Count,
+	}
+}
+
+func postID(post *Post) string {
+	return post.ID
+}
+
+func (s *APIServer) handleGetPost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+
+	post, err := s.engine.GetPost(postID)
+	if err == nil {
+		err = s.engine.CanViewSubreddit(post.Subreddit, currentUser(r))
+	}
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get post: %v", err),
+			Err:     err,
 		})
+		return
 	}
 
+	response := s.newPostResponse(post, currentUser(r))
+	if response.Removed && !s.canModeratePost(r, postID) {
+		response.Author = removedMarker
+		response.Content = removedMarker
+		response.URL = ""
+		response.Domain = ""
+		response.Image = nil
+		response.Poll = nil
+		response.CrosspostParent = nil
+	}
 	writeJSON(w, SuccessResponse{
 		Status:  "success",
-		Message: fmt.Sprintf("Retrieved %d posts for %s", len(posts), username),
-		Data:    prettifiedPosts,
+		Message: fmt.Sprintf("Retrieved post %s", postID),
+		Data:    response,
 	})
 }
 
+func (s *APIServer) handleEditPost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+	username := currentUser(r)
+
+	var req EditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	post, err := s.engine.EditPost(postID, username, req.Content)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to edit post: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s edited post %s", username, postID),
+		Data:    s.newPostResponse(post, username),
+	})
+}
+
+func (s *APIServer) handleDeletePost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID := vars["id"]
+	username := currentUser(r)
+
+	if err := s.engine.DeletePost(postID, username); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to delete post: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s deleted post %s", username, postID),
+	})
+}
+
+// parseListingSort reads the sort and t query parameters of a post listing
+func parseListingSort(r *http.Request) (SortMode, TimeWindow, error) {
+	query := r.URL.Query()
+	sortMode, err := ParseSortMode(query.Get("sort"))
+	if err != nil {
+		return "", "", err
+	}
+	window, err := ParseTimeWindow(query.Get("t"))
+	if err != nil {
+		return "", "", err
+	}
+	return sortMode, window, nil
+}
+
 func (s *APIServer) handleVotePost(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	postID := vars["id"]
-	username := r.Header.Get("Username")
+	username := currentUser(r)
 
 	var req VoteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -273,30 +900,86 @@ func (s *APIServer) handleVotePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := s.engine.VotePost(postID, req.Upvote)
+	direction := req.direction()
+	result, err := s.engine.VotePost(postID, username, direction)
 	if err != nil {
 		writeJSON(w, ErrorResponse{
 			Status:  "error",
 			Message: fmt.Sprintf("Failed to vote on post: %v", err),
+			Err:     err,
 		})
 		return
 	}
 
-	voteType := "upvoted"
-	if !req.Upvote {
-		voteType = "downvoted"
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s %s post %s", username, voteVerb(direction), postID),
+		Data:    newVoteResponse(result),
+	})
+}
+
+// VoteResponse is the JSON form of a VoteResult
+type VoteResponse struct {
+	Upvotes   int `json:"upvotes"`
+	Downvotes int `json:"downvotes"`
+	Score     int `json:"score"`
+}
+
+func newVoteResponse(result *VoteResult) VoteResponse {
+	return VoteResponse{
+		Upvotes:   result.Upvotes,
+		Downvotes: result.Downvotes,
+		Score:     result.Score,
+	}
+}
+
+func voteVerb(direction int) string {
+	switch direction {
+	case VoteUp:
+		return "upvoted"
+	case VoteDown:
+		return "downvoted"
+	default:
+		return "cleared their vote on"
+	}
+}
+
+func (s *APIServer) handleVoteComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	commentID := vars["id"]
+	username := currentUser(r)
+
+	var req VoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	direction := req.direction()
+	result, err := s.engine.VoteComment(commentID, username, direction)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to vote on comment: %v", err),
+			Err:     err,
+		})
+		return
 	}
 
 	writeJSON(w, SuccessResponse{
 		Status:  "success",
-		Message: fmt.Sprintf("%s %s post %s", username, voteType, postID),
+		Message: fmt.Sprintf("%s %s comment %s", username, voteVerb(direction), commentID),
+		Data:    newVoteResponse(result),
 	})
 }
 
 func (s *APIServer) handleAddComment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	postID := vars["id"]
-	username := r.Header.Get("Username")
+	username := currentUser(r)
 
 	var req CommentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -307,11 +990,12 @@ func (s *APIServer) handleAddComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	comment, err := s.engine.AddComment(req.Content, username, postID, "")
+	comment, err := s.engine.AddComment(req.Content, username, postID, req.ParentID)
 	if err != nil {
 		writeJSON(w, ErrorResponse{
 			Status:  "error",
 			Message: fmt.Sprintf("Failed to add comment: %v", err),
+			Err:     err,
 		})
 		return
 	}
@@ -319,7 +1003,120 @@ func (s *APIServer) handleAddComment(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, SuccessResponse{
 		Status:  "success",
 		Message: fmt.Sprintf("%s commented on post %s", username, postID),
-		Data:    comment,
+		Data:    newCommentResponse(comment, -1, commentView{showRemoved: true}),
+	})
+}
+
+// maxCommentContext caps the ancestors a comment permalink shows, as on Reddit
+const maxCommentContext = 8
+
+func (s *APIServer) handleGetComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	commentID := vars["id"]
+	query := r.URL.Query()
+
+	ancestors := 0
+	if value := query.Get("context"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeJSON(w, ErrorResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("Invalid context %q", value),
+			})
+			return
+		}
+		ancestors = min(n, maxCommentContext)
+	}
+
+	depth := -1
+	if value := query.Get("depth"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeJSON(w, ErrorResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("Invalid depth %q", value),
+			})
+			return
+		}
+		depth = n
+	}
+
+	thread, err := s.engine.GetCommentContext(commentID, ancestors, currentUser(r))
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get comment: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	// Build the requested subtree, then wrap it in its ancestors so the
+	// response reads top-down like a permalink page
+	view := s.commentView(r, thread[0].PostID)
+	response := newCommentResponse(thread[len(thread)-1], depth, view)
+	for i := len(thread) - 2; i >= 0; i-- {
+		ancestor := newCommentResponse(thread[i], 0, view)
+		ancestor.MoreReplies--
+		ancestor.Children = []CommentResponse{response}
+		response = ancestor
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Retrieved comment %s with %d parent comments", commentID, len(thread)-1),
+		Data:    response,
+	})
+}
+
+func (s *APIServer) handleEditComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	commentID := vars["id"]
+	username := currentUser(r)
+
+	var req EditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	comment, err := s.engine.EditComment(commentID, username, req.Content)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to edit comment: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s edited comment %s", username, commentID),
+		Data:    newCommentResponse(comment, 0, commentView{showRemoved: true}),
+	})
+}
+
+func (s *APIServer) handleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	commentID := vars["id"]
+	username := currentUser(r)
+
+	if err := s.engine.DeleteComment(commentID, username); err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to delete comment: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, SuccessResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%s deleted comment %s", username, commentID),
 	})
 }
 
@@ -333,12 +1130,13 @@ func (s *APIServer) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username := r.Header.Get("Username")
+	username := currentUser(r)
 	msg, err := s.engine.SendDirectMessage(username, req.To, req.Content)
 	if err != nil {
 		writeJSON(w, ErrorResponse{
 			Status:  "error",
 			Message: fmt.Sprintf("Failed to send message: %v", err),
+			Err:     err,
 		})
 		return
 	}
@@ -351,21 +1149,42 @@ func (s *APIServer) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *APIServer) handleGetMessages(w http.ResponseWriter, r *http.Request) {
-	username := r.Header.Get("Username")
+	username := currentUser(r)
+	if username == "" {
+		writeAuthRequired(w)
+		return
+	}
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
 	messages, err := s.engine.GetDirectMessages(username)
 	if err != nil {
 		writeJSON(w, ErrorResponse{
 			Status:  "error",
 			Message: fmt.Sprintf("Failed to get messages: %v", err),
+			Err:     err,
 		})
 		return
 	}
 
-	writeJSON(w, SuccessResponse{
-		Status:  "success",
-		Message: fmt.Sprintf("Retrieved %d messages for %s", len(messages), username),
-		Data:    messages,
-	})
+	messages, info, err := paginate(messages, kindMessage, func(dm *DirectMessage) string { return dm.ID }, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get messages: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d messages for %s", len(messages), username), messages, info))
 }
 
 func (s *APIServer) Start(addr string) error {
@@ -373,89 +1192,110 @@ func (s *APIServer) Start(addr string) error {
 }
 
 func (s *APIServer) handleGetUsers(w http.ResponseWriter, r *http.Request) {
-	type UserInfo struct {
-		Username   string    `json:"username"`
-		CreatedAt  time.Time `json:"created_at"`
-		Karma      int       `json:"karma"`
-		Subreddits int       `json:"subreddits"`
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
+		})
+		return
 	}
 
-	userList := make([]UserInfo, 0)
-	for username, user := range s.engine.users {
+	userList := make([]UserResponse, 0)
+	for _, user := range s.engine.ListUsers() {
+		admin := s.engine.IsAdmin(user.Username)
 		user.mu.RLock()
-		userInfo := UserInfo{
-			Username:   username,
-			CreatedAt:  user.CreatedAt,
-			Karma:      user.Karma,
-			Subreddits: len(user.Subreddits),
+		userInfo := UserResponse{
+			Username:     user.Username,
+			CreatedAt:    user.CreatedAt,
+			Karma:        user.TotalKarma(),
+			PostKarma:    user.PostKarma,
+			CommentKarma: user.CommentKarma,
+			Subreddits:   len(user.Subreddits),
+			Admin:        admin,
+		}
+		if suspension := user.activeSuspension(); suspension != nil {
+			userInfo.Suspended = true
+			if !suspension.ExpiresAt.IsZero() {
+				until := suspension.ExpiresAt
+				userInfo.SuspendedUntil = &until
+			}
 		}
 		user.mu.RUnlock()
 		userList = append(userList, userInfo)
 	}
 
-	writeJSON(w, SuccessResponse{
-		Status:  "success",
-		Message: fmt.Sprintf("Retrieved %d users", len(userList)),
-		Data:    userList,
-	})
+	userList, info, err := paginate(userList, kindUser, func(u UserResponse) string { return u.Username }, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get users: %v", err),
+			Err:     err,
+		})
+		return
+	}
+
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d users", len(userList)), userList, info))
 }
 
 func (s *APIServer) handleGetComments(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	postID := vars["id"]
 
-	post, exists := s.engine.posts[postID]
-	if !exists {
+	page, err := parsePageRequest(r)
+	if err != nil {
 		writeJSON(w, ErrorResponse{
 			Status:  "error",
-			Message: "Post not found",
+			Message: fmt.Sprintf("Invalid listing parameters: %v", err),
+			Err:     err,
 		})
 		return
 	}
 
-	type CommentResponse struct {
-		ID        string            `json:"id"`
-		Content   string            `json:"content"`
-		Author    string            `json:"author"`
-		CreatedAt time.Time         `json:"created_at"`
-		Votes     int               `json:"votes"`
-		Children  []CommentResponse `json:"children"`
+	rootComments, err := s.engine.GetComments(postID, currentUser(r))
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get comments: %v", err),
+			Err:     err,
+		})
+		return
 	}
 
-	// Convert comments to response format
-	var convertComment func(*Comment) CommentResponse
-	convertComment = func(c *Comment) CommentResponse {
-		children := make([]CommentResponse, 0)
-		for _, child := range c.Children {
-			children = append(children, convertComment(child))
+	view := s.commentView(r, postID)
+	visible := make([]*Comment, 0, len(rootComments))
+	for _, comment := range rootComments {
+		if !view.leftOut(comment) {
+			visible = append(visible, comment)
 		}
+	}
+	rootComments = visible
 
-		return CommentResponse{
-			ID:        c.ID,
-			Content:   c.Content,
-			Author:    c.Author,
-			CreatedAt: c.CreatedAt,
-			Votes:     c.Votes,
-			Children:  children,
-		}
+	rootComments, info, err := paginate(rootComments, kindComment, func(c *Comment) string { return c.ID }, page)
+	if err != nil {
+		writeJSON(w, ErrorResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to get comments: %v", err),
+			Err:     err,
+		})
+		return
 	}
 
 	comments := make([]CommentResponse, 0)
-	for _, comment := range post.Comments {
-		comments = append(comments, convertComment(comment))
+	for _, comment := range rootComments {
+		comments = append(comments, newCommentResponse(comment, -1, view))
 	}
 
-	writeJSON(w, SuccessResponse{
-		Status:  "success",
-		Message: fmt.Sprintf("Retrieved %d comments for post %s", len(comments), postID),
-		Data:    comments,
-	})
+	writeJSON(w, listingResponse(fmt.Sprintf("Retrieved %d comments for post %s", len(comments), postID), comments, info))
 }
 
 func (s *APIServer) handleGetStats(w http.ResponseWriter, r *http.Request) {
 	type UserKarma struct {
-		Username string `json:"username"`
-		Karma    int    `json:"karma"`
+		Username     string `json:"username"`
+		Karma        int    `json:"karma"`
+		PostKarma    int    `json:"post_karma"`
+		CommentKarma int    `json:"comment_karma"`
 	}
 	log.Println("------Top 5 users based on karma------")
 
@@ -476,41 +1316,36 @@ func (s *APIServer) handleGetStats(w http.ResponseWriter, r *http.Request) {
 	totalDownvotes := 0
 	for _, post := range s.engine.posts {
 		post.mu.RLock()
-		if post.Votes > 0 {
-			totalUpvotes += post.Votes
-		} else {
-			totalDownvotes += -post.Votes
-		}
+		totalUpvotes += post.Upvotes
+		totalDownvotes += post.Downvotes
 		totalComments += countComments(post.Comments)
 		post.mu.RUnlock()
 	}
 
 	// Get top users by karma
-	type userKarmaPair struct {
-		username string
-		karma    int
-	}
-	userKarmas := make([]userKarmaPair, 0)
+	userKarmas := make([]UserKarma, 0)
 
-	for username, user := range s.engine.users {
+	users := s.engine.ListUsers()
+	for _, user := range users {
 		user.mu.RLock()
-		karma := user.Karma
+		userKarmas = append(userKarmas, UserKarma{
+			Username:     user.Username,
+			Karma:        user.TotalKarma(),
+			PostKarma:    user.PostKarma,
+			CommentKarma: user.CommentKarma,
+		})
 		user.mu.RUnlock()
-		userKarmas = append(userKarmas, userKarmaPair{username, karma})
 	}
 
 	// Sort users by karma
 	sort.Slice(userKarmas, func(i, j int) bool {
-		return userKarmas[i].karma > userKarmas[j].karma
+		return userKarmas[i].Karma > userKarmas[j].Karma
 	})
 
 	// Get top 5 users
-	topUsers := make([]UserKarma, 0)
-	for i := 0; i < len(userKarmas) && i < 5; i++ {
-		topUsers = append(topUsers, UserKarma{
-			Username: userKarmas[i].username,
-			Karma:    userKarmas[i].karma,
-		})
+	topUsers := userKarmas
+	if len(topUsers) > 5 {
+		topUsers = topUsers[:5]
 	}
 
 	// Count total direct messages
@@ -520,7 +1355,7 @@ func (s *APIServer) handleGetStats(w http.ResponseWriter, r *http.Request) {
 	}
 
 	stats := StatsResponse{
-		TotalUsers:      len(s.engine.users),
+		TotalUsers:      len(users),
 		TotalSubreddits: len(s.engine.subreddits),
 		TotalPosts:      len(s.engine.posts),
 		TotalComments:   totalComments,
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewCommentResponseDepth(t *testing.T) {
+	// root has two replies and the first of them has one more
+	leaf := &Comment{ID: "leaf"}
+	first := &Comment{ID: "first", Children: []*Comment{leaf}}
+	second := &Comment{ID: "second"}
+	root := &Comment{ID: "root", Children: []*Comment{first, second}}
+
+	tests := []struct {
+		name         string
+		depth        int
+		wantChildren int
+		wantMore     int
+		wantNested   int
+	}{
+		{"comment only", 0, 0, 2, 0},
+		{"direct replies", 1, 2, 0, 0},
+		{"whole subtree", -1, 2, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := newCommentResponse(root, tt.depth, commentView{})
+			if len(response.Children) != tt.wantChildren || response.MoreReplies != tt.wantMore {
+				t.Fatalf("newCommentResponse = %d children and %d more, want %d and %d",
+					len(response.Children), response.MoreReplies, tt.wantChildren, tt.wantMore)
+			}
+			if tt.wantChildren == 0 {
+				return
+			}
+			if nested := len(response.Children[0].Children); nested != tt.wantNested {
+				t.Errorf("first reply has %d children, want %d", nested, tt.wantNested)
+			}
+		})
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantKind   error
+	}{
+		{"validation", &ValidationError{Field: "title", Reason: "cannot be empty"}, http.StatusBadRequest, "VALIDATION_FAILED", ErrValidation},
+		{"unauthorized", newError(ErrUnauthorized, "invalid token"), http.StatusUnauthorized, "UNAUTHORIZED", ErrUnauthorized},
+		{"forbidden", &MembershipError{Username: "alice", Subreddit: "golang"}, http.StatusForbidden, "FORBIDDEN", ErrForbidden},
+		{"not found", &NotFoundError{Kind: "post", ID: "t3_1"}, http.StatusNotFound, "NOT_FOUND", ErrNotFound},
+		{"already exists", newError(ErrAlreadyExists, "taken"), http.StatusConflict, "ALREADY_EXISTS", ErrAlreadyExists},
+		{"conflict", newError(ErrConflict, "locked"), http.StatusConflict, "CONFLICT", ErrConflict},
+		{"rate limited", &RateLimitError{Kind: RateLimitPosts, RetryAfter: 90 * time.Second}, http.StatusTooManyRequests, "RATE_LIMITED", ErrRateLimited},
+		{"malformed request", nil, http.StatusBadRequest, "BAD_REQUEST", nil},
+		{"unclassified", errors.New("boom"), http.StatusInternalServerError, "INTERNAL_ERROR", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			writeJSON(recorder, ErrorResponse{Status: "error", Message: "request failed", Err: tt.err})
+			resp := recorder.Result()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			var apiErr *APIError
+			if err := decodeAPIError(resp); !errors.As(err, &apiErr) {
+				t.Fatalf("decodeAPIError = %v, want an APIError", err)
+			}
+			if apiErr.Code != tt.wantCode || apiErr.Message != "request failed" {
+				t.Errorf("APIError = %+v, want code %s", *apiErr, tt.wantCode)
+			}
+			if tt.wantKind != nil && !errors.Is(apiErr, tt.wantKind) {
+				t.Errorf("errors.Is(%v, %v) = false, want true", apiErr, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestRateLimitedResponseRetryAfter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writeJSON(recorder, ErrorResponse{
+		Status:  "error",
+		Message: "slow down",
+		Err:     &RateLimitError{Kind: RateLimitComments, RetryAfter: 1500 * time.Millisecond},
+	})
+	resp := recorder.Result()
+
+	if got := resp.Header.Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+	var apiErr *APIError
+	if err := decodeAPIError(resp); !errors.As(err, &apiErr) || apiErr.RetryAfter != 2*time.Second {
+		t.Errorf("decodeAPIError = %v, want RetryAfter 2s", err)
+	}
+}
+
+func TestCreatePollPostDuration(t *testing.T) {
+	var sent []CreatePostRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req CreatePostRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		sent = append(sent, req)
+		writeJSON(w, SuccessResponse{Status: "success"})
+	}))
+	defer server.Close()
+	client := NewAPIClient(server.URL, "alice")
+
+	tests := []struct {
+		duration  time.Duration
+		wantErr   error
+		wantHours int
+	}{
+		{72 * time.Hour, nil, 72},
+		{0, nil, 0},
+		{90 * time.Minute, ErrValidation, 0},
+		{time.Hour + time.Second, ErrValidation, 0},
+	}
+
+	for _, tt := range tests {
+		sent = nil
+		err := client.CreatePollPost("poll", "golang", []string{"yes", "no"}, tt.duration)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("CreatePollPost(%s) error = %v, want %v", tt.duration, err, tt.wantErr)
+			continue
+		}
+		switch {
+		case err != nil && len(sent) != 0:
+			t.Errorf("CreatePollPost(%s) sent a request despite the error", tt.duration)
+		case err == nil && (len(sent) != 1 || sent[0].PollDurationHours != tt.wantHours):
+			t.Errorf("CreatePollPost(%s) sent %+v, want one request for %d hours", tt.duration, sent, tt.wantHours)
+		}
+	}
+}
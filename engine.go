@@ -2,51 +2,109 @@ package main
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 // Data Models
 type User struct {
-	Username   string
-	Password   string
-	Karma      int
-	CreatedAt  time.Time
-	Subreddits map[string]bool
-	mu         sync.RWMutex
+	Username     string
+	PasswordHash []byte
+	PasswordSalt []byte
+	PostKarma    int
+	CommentKarma int
+	CreatedAt    time.Time
+	Subreddits   map[string]bool
+	Suspension   *Restriction         // site-wide, may have expired
+	Blocked      map[string]bool      // users whose messages and content are hidden
+	Saved        map[string]time.Time // post and comment IDs -> when saved
+	Hidden       map[string]time.Time // post IDs -> when hidden from the feed
+	System       bool                 // acts on its own and is not listed
+
+	recentActions map[string][]time.Time // rate limit history by kind and subreddit
+	mu            sync.RWMutex
+}
+
+// TotalKarma returns the sum of post and comment karma. Callers must hold u.mu.
+func (u *User) TotalKarma() int {
+	return u.PostKarma + u.CommentKarma
 }
 
 type Comment struct {
 	ID        string
 	Content   string
 	Author    string
+	PostID    string
 	ParentID  string
 	CreatedAt time.Time
-	Votes     int
+	EditedAt  time.Time // zero until the comment is edited
+	Deleted   bool
+	Blocked   bool // a "[blocked]" placeholder in a viewer's copy of the thread
+	Votes     int  // Score: Upvotes - Downvotes
+	Upvotes   int
+	Downvotes int
+	Voters    map[string]int // voter -> VoteUp or VoteDown
 	Children  []*Comment
-	mu        sync.RWMutex
+	ModStatus
+	mu sync.RWMutex
 }
 
 type Post struct {
-	ID        string
-	Title     string
-	Content   string
-	Author    string
-	Subreddit string
-	CreatedAt time.Time
-	Votes     int
-	Comments  []*Comment
-	mu        sync.RWMutex
+	ID          string
+	Kind        PostKind
+	Title       string
+	Content     string
+	URL         string  // link posts
+	Domain      string  // host of URL, without "www."
+	Image       *Media  // image posts
+	Poll        *Poll   // poll posts
+	CrosspostOf *Post   `json:"-"` // the original of a crosspost, nil once it is gone
+	Crossposts  []*Post `json:"-"` // crossposts of an original; both links would make JSON cycle
+	Author      string
+	Subreddit   string
+	CreatedAt   time.Time
+	EditedAt    time.Time // zero until the post is edited
+	Deleted     bool
+	Flair       Flair
+	Stickied    bool
+	Locked      bool // no new comments except from moderators
+	VotesFrozen bool // votes on the post and its comments are frozen
+	Votes       int  // Score: Upvotes - Downvotes
+	Upvotes     int
+	Downvotes   int
+	Voters      map[string]int // voter -> VoteUp or VoteDown
+	Comments    []*Comment
+	ModStatus
+	mu sync.RWMutex
 }
 
 type Subreddit struct {
-	Name        string
-	Description string
-	Creator     string
-	CreatedAt   time.Time
-	Posts       []*Post
-	Members     map[string]bool
-	mu          sync.RWMutex
+	Name             string
+	Description      string
+	Creator          string
+	CreatedAt        time.Time
+	Type             SubredditType
+	Posts            []*Post
+	Stickied         []*Post // at most maxStickyPosts, in display order
+	Members          map[string]bool
+	Approved         map[string]bool         // may post when restricted, may see when private
+	JoinRequests     map[string]*JoinRequest // pending requests by username
+	Banned           bool                    // shut down by an admin
+	BanReason        string
+	Quarantined      bool
+	QuarantineOptIns map[string]bool         // users who chose to see the quarantined subreddit
+	Moderators       []*Moderator            // in order of seniority
+	ModInvites       map[string]*ModInvite   // pending invites by username
+	Bans             map[string]*Restriction // by username, may hold expired bans
+	Mutes            map[string]*Restriction // by username, may hold expired mutes
+	ModLog           []*ModLogEntry          // append-only, oldest first
+	AutoModRules     []*AutoModRule          // checked in order against new content
+	FlairTemplates   []*FlairTemplate        // post and user flair, in the order added
+	RequirePostFlair bool
+	UserFlair        map[string]Flair // by username
+	mu               sync.RWMutex
 }
 
 type DirectMessage struct {
@@ -59,106 +117,347 @@ type DirectMessage struct {
 	mu        sync.RWMutex
 }
 
+// deletedMarker replaces the author and text of deleted content
+const deletedMarker = "[deleted]"
+
+// Vote directions. VoteNone withdraws a previous vote.
+const (
+	VoteDown = -1
+	VoteNone = 0
+	VoteUp   = 1
+)
+
+// VoteResult holds the vote counts of an item after a vote was applied
+type VoteResult struct {
+	Upvotes   int
+	Downvotes int
+	Score     int
+}
+
 // RedditEngine represents the main engine
 type RedditEngine struct {
 	users          map[string]*User
 	subreddits     map[string]*Subreddit
 	posts          map[string]*Post
+	comments       map[string]*Comment
 	directMessages map[string][]*DirectMessage
-	mu             sync.RWMutex
+	sessions       map[string]*Session // token -> session
+
+	requireMembership bool            // users must join a subreddit before posting
+	admins            map[string]bool // site admins by username
+	rateLimits        RateLimits
+	media             *MediaStore // nil when image uploads are off
+
+	mu sync.RWMutex
 }
 
 // NewRedditEngine creates a new Reddit engine instance
 func NewRedditEngine() *RedditEngine {
 	return &RedditEngine{
-		users:          make(map[string]*User),
+		users: map[string]*User{
+			autoModerator: newSystemUser(autoModerator),
+		},
 		subreddits:     make(map[string]*Subreddit),
 		posts:          make(map[string]*Post),
+		comments:       make(map[string]*Comment),
 		directMessages: make(map[string][]*DirectMessage),
+		sessions:       make(map[string]*Session),
+		admins:         make(map[string]bool),
+		rateLimits:     DefaultRateLimits,
 	}
 }
 
 // User Management Methods
 func (e *RedditEngine) RegisterUser(username, password string) error {
+	if err := requireText("username", username); err != nil {
+		return err
+	}
+	if password == "" {
+		return &ValidationError{Field: "password", Reason: "cannot be empty"}
+	}
+
+	// Hash before taking the lock, it is deliberately slow
+	salt, err := newPasswordSalt()
+	if err != nil {
+		return err
+	}
+	hash := hashPassword(password, salt)
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
 	if _, exists := e.users[username]; exists {
-		return fmt.Errorf("user already exists")
+		return newError(ErrAlreadyExists, "user %q already exists", username)
 	}
 
 	e.users[username] = &User{
+		Username:     username,
+		PasswordHash: hash,
+		PasswordSalt: salt,
+		CreatedAt:    time.Now(),
+		Subreddits:   make(map[string]bool),
+		Blocked:      make(map[string]bool),
+		Saved:        make(map[string]time.Time),
+		Hidden:       make(map[string]time.Time),
+	}
+	return nil
+}
+
+// newSystemUser creates an account that acts on its own and cannot log in
+func newSystemUser(username string) *User {
+	return &User{
 		Username:   username,
-		Password:   password,
 		CreatedAt:  time.Now(),
+		System:     true,
 		Subreddits: make(map[string]bool),
+		Blocked:    make(map[string]bool),
+		Saved:      make(map[string]time.Time),
+		Hidden:     make(map[string]time.Time),
 	}
-	return nil
+}
+
+// ListUsers returns every registered user, oldest account first. System
+// accounts such as AutoModerator are left out.
+func (e *RedditEngine) ListUsers() []*User {
+	e.mu.RLock()
+	users := make([]*User, 0, len(e.users))
+	for _, user := range e.users {
+		if !user.System {
+			users = append(users, user)
+		}
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.Before(users[j].CreatedAt)
+		}
+		return users[i].Username < users[j].Username
+	})
+	return users
 }
 
 // Subreddit Management Methods
-func (e *RedditEngine) CreateSubreddit(name, description, creator string) error {
+
+// CreateSubreddit creates a subreddit moderated by its creator. An empty
+// subType creates a public subreddit.
+func (e *RedditEngine) CreateSubreddit(name, description, creator string, subType SubredditType) error {
+	if err := e.checkNotSuspended(creator); err != nil {
+		return err
+	}
+
+	if err := requireText("subreddit name", name); err != nil {
+		return err
+	}
+	subType, err := ParseSubredditType(string(subType))
+	if err != nil {
+		return err
+	}
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	if _, err := e.lookupUser(creator); err != nil {
+		return err
+	}
+
 	if _, exists := e.subreddits[name]; exists {
-		return fmt.Errorf("subreddit already exists")
+		return newError(ErrAlreadyExists, "subreddit %q already exists", name)
 	}
 
 	e.subreddits[name] = &Subreddit{
-		Name:        name,
-		Description: description,
-		Creator:     creator,
-		CreatedAt:   time.Now(),
-		Posts:       make([]*Post, 0),
-		Members:     make(map[string]bool),
+		Name:             name,
+		Description:      description,
+		Creator:          creator,
+		CreatedAt:        time.Now(),
+		Type:             subType,
+		Posts:            make([]*Post, 0),
+		Members:          make(map[string]bool),
+		Approved:         make(map[string]bool),
+		JoinRequests:     make(map[string]*JoinRequest),
+		QuarantineOptIns: make(map[string]bool),
+		Moderators: []*Moderator{{
+			Username:    creator,
+			Permissions: PermAll,
+			AddedBy:     creator,
+			AddedAt:     time.Now(),
+		}},
+		ModInvites: make(map[string]*ModInvite),
+		Bans:       make(map[string]*Restriction),
+		Mutes:      make(map[string]*Restriction),
+		ModLog:     make([]*ModLogEntry, 0),
+		UserFlair:  make(map[string]Flair),
 	}
 	return nil
 }
 
+// SubredditSort selects the order of subreddit listings
+type SubredditSort string
+
+const (
+	SubredditSortMembers SubredditSort = "members" // most members first
+	SubredditSortNew     SubredditSort = "new"     // newest first
+	SubredditSortOld     SubredditSort = "old"     // oldest first
+)
+
+// ParseSubredditSort converts a sort query parameter into a SubredditSort.
+// An empty string selects SubredditSortMembers.
+func ParseSubredditSort(value string) (SubredditSort, error) {
+	switch order := SubredditSort(value); order {
+	case "":
+		return SubredditSortMembers, nil
+	case SubredditSortMembers, SubredditSortNew, SubredditSortOld:
+		return order, nil
+	}
+	return "", &ValidationError{Field: "sort", Reason: fmt.Sprintf("unknown subreddit sort %q", value)}
+}
+
+// ListSubreddits returns the subreddits whose name starts with prefix
+// (ignoring case) and that viewer may see, ordered by order. Ties are broken
+// by name. Quarantined subreddits are never listed.
+func (e *RedditEngine) ListSubreddits(prefix string, order SubredditSort, viewer string) []*Subreddit {
+	prefix = strings.ToLower(prefix)
+
+	type listedSubreddit struct {
+		subreddit *Subreddit
+		members   int
+	}
+
+	e.mu.RLock()
+	listed := make([]listedSubreddit, 0, len(e.subreddits))
+	for name, subreddit := range e.subreddits {
+		if !strings.HasPrefix(strings.ToLower(name), prefix) {
+			continue
+		}
+		subreddit.mu.RLock()
+		if !subreddit.Quarantined && subreddit.canView(viewer) {
+			listed = append(listed, listedSubreddit{subreddit, len(subreddit.Members)})
+		}
+		subreddit.mu.RUnlock()
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(listed, func(i, j int) bool {
+		a, b := listed[i], listed[j]
+		switch order {
+		case SubredditSortMembers:
+			if a.members != b.members {
+				return a.members > b.members
+			}
+		case SubredditSortNew:
+			if !a.subreddit.CreatedAt.Equal(b.subreddit.CreatedAt) {
+				return a.subreddit.CreatedAt.After(b.subreddit.CreatedAt)
+			}
+		case SubredditSortOld:
+			if !a.subreddit.CreatedAt.Equal(b.subreddit.CreatedAt) {
+				return a.subreddit.CreatedAt.Before(b.subreddit.CreatedAt)
+			}
+		}
+		return a.subreddit.Name < b.subreddit.Name
+	})
+
+	subreddits := make([]*Subreddit, len(listed))
+	for i, entry := range listed {
+		subreddits[i] = entry.subreddit
+	}
+	return subreddits
+}
+
+// GetSubreddit returns a single subreddit by name
+func (e *RedditEngine) GetSubreddit(name string) (*Subreddit, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.lookupSubreddit(name)
+}
+
+// GetSubredditMembers returns the usernames of a subreddit's members in
+// alphabetical order, as seen by viewer
+func (e *RedditEngine) GetSubredditMembers(name, viewer string) ([]string, error) {
+	subreddit, err := e.GetSubreddit(name)
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	if err := subreddit.checkCanView(viewer); err != nil {
+		subreddit.mu.RUnlock()
+		return nil, err
+	}
+	members := make([]string, 0, len(subreddit.Members))
+	for username := range subreddit.Members {
+		members = append(members, username)
+	}
+	subreddit.mu.RUnlock()
+
+	sort.Strings(members)
+	return members, nil
+}
+
 func (e *RedditEngine) JoinSubreddit(username, subredditName string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	user, ok := e.users[username]
-	if !ok {
-		return fmt.Errorf("user not found")
+	user, err := e.lookupUser(username)
+	if err != nil {
+		return err
 	}
 
-	subreddit, ok := e.subreddits[subredditName]
-	if !ok {
-		return fmt.Errorf("subreddit not found")
+	subreddit, err := e.lookupSubreddit(subredditName)
+	if err != nil {
+		return err
 	}
 
-	user.mu.Lock()
-	user.Subreddits[subredditName] = true
-	user.mu.Unlock()
-
 	subreddit.mu.Lock()
+	if err := subreddit.checkNotBanned(username); err != nil {
+		subreddit.mu.Unlock()
+		return err
+	}
+	if subreddit.Type == SubredditPrivate && !subreddit.canView(username) {
+		subreddit.mu.Unlock()
+		return newError(ErrForbidden, "%s is private; ask to join or wait for an invite", subredditName)
+	}
+	if err := subreddit.checkCanView(username); err != nil {
+		subreddit.mu.Unlock()
+		return err
+	}
 	subreddit.Members[username] = true
+	delete(subreddit.JoinRequests, username)
 	subreddit.mu.Unlock()
 
+	user.mu.Lock()
+	user.Subreddits[subredditName] = true
+	user.mu.Unlock()
+
 	return nil
 }
 
 func (e *RedditEngine) LeaveSubreddit(username, subredditName string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
 	fmt.Printf("LeaveSubreddit called for user: %s, subreddit: %s\n", username, subredditName)
 
 	// Check if user exists
-	user, ok := e.users[username]
-	if !ok {
+	user, err := e.lookupUser(username)
+	if err != nil {
 		fmt.Printf("User %s not found\n", username)
-		return fmt.Errorf("user not found")
+		return err
 	}
 
 	// Check if subreddit exists
-	subreddit, ok := e.subreddits[subredditName]
-	if !ok {
+	subreddit, err := e.lookupSubreddit(subredditName)
+	if err != nil {
 		fmt.Printf("Subreddit %s not found\n", subredditName)
-		return fmt.Errorf("subreddit not found")
+		return err
 	}
 
 	// Remove user from subreddit's members
@@ -175,173 +474,572 @@ func (e *RedditEngine) LeaveSubreddit(username, subredditName string) error {
 	return nil
 }
 
-// NEW
-func (e *RedditEngine) GetComments(postID string) ([]*Comment, error) {
+// GetComments returns the root comments of a post, as seen by viewer.
+// Comments by users the viewer blocked are "[blocked]" placeholders, or left
+// out when none of their replies are visible.
+func (e *RedditEngine) GetComments(postID, viewer string) ([]*Comment, error) {
 	e.mu.RLock()
-	post, exists := e.posts[postID]
+	post, err := e.lookupPost(postID)
+	if err == nil {
+		err = e.checkCanViewPost(post, viewer)
+	}
+	blocked := e.blockedBy(viewer)
 	e.mu.RUnlock()
 
-	if !exists {
-		return nil, fmt.Errorf("post not found")
+	if err != nil {
+		return nil, err
 	}
 
 	// Return the root comments
-	return post.Comments, nil
+	post.mu.RLock()
+	roots := make([]*Comment, len(post.Comments))
+	copy(roots, post.Comments)
+	post.mu.RUnlock()
+
+	comments := make([]*Comment, 0, len(roots))
+	for _, comment := range roots {
+		if view, visible := viewWithBlocks(comment, blocked); visible {
+			comments = append(comments, view)
+		}
+	}
+	return comments, nil
 }
 
 // Post Management Methods
+
+// CreatePost creates a self (text) post
 func (e *RedditEngine) CreatePost(title, content, author, subredditName string) (*Post, error) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+	return e.SubmitPost(author, subredditName, PostSubmission{Kind: PostSelf, Title: title, Content: content})
+}
 
-	subreddit, ok := e.subreddits[subredditName]
-	if !ok {
-		return nil, fmt.Errorf("subreddit not found")
+// GetPost returns a single post by ID
+func (e *RedditEngine) GetPost(postID string) (*Post, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.lookupPost(postID)
+}
+
+// EditPost replaces the text of a post. Only the author may edit, and deleted
+// posts cannot be edited.
+func (e *RedditEngine) EditPost(postID, editor, content string) (*Post, error) {
+	if err := e.checkNotSuspended(editor); err != nil {
+		return nil, err
 	}
 
-	post := &Post{
-		ID:        fmt.Sprintf("post_%d", time.Now().UnixNano()),
-		Title:     title,
-		Content:   content,
-		Author:    author,
-		Subreddit: subredditName,
-		CreatedAt: time.Now(),
-		Comments:  make([]*Comment, 0),
+	post, err := e.GetPost(postID)
+	if err != nil {
+		return nil, err
 	}
 
-	e.posts[post.ID] = post
+	post.mu.Lock()
+	defer post.mu.Unlock()
 
-	subreddit.mu.Lock()
-	subreddit.Posts = append(subreddit.Posts, post)
-	subreddit.mu.Unlock()
+	if post.Deleted {
+		return nil, newError(ErrConflict, "post has been deleted")
+	}
+	if post.Author != editor {
+		return nil, newError(ErrForbidden, "only the author can edit this post")
+	}
 
+	post.Content = content
+	post.EditedAt = time.Now()
 	return post, nil
 }
 
+// DeletePost turns a post into a tombstone. The post keeps its ID, title,
+// place in the subreddit and comment tree, but its author and text are
+// replaced with "[deleted]" and its link, image or poll is dropped. A sticky
+// post also loses its sticky slot. Only the author may delete.
+func (e *RedditEngine) DeletePost(postID, requester string) error {
+	if err := e.checkNotSuspended(requester); err != nil {
+		return err
+	}
+
+	post, subreddit, err := e.lookupModeratedPost(postID)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	post.mu.Lock()
+	switch {
+	case post.Deleted:
+		err = newError(ErrConflict, "post has already been deleted")
+	case post.Author != requester:
+		err = newError(ErrForbidden, "only the author can delete this post")
+	default:
+		post.Deleted = true
+		post.Author = deletedMarker
+		post.Content = deletedMarker
+		post.URL = ""
+		post.Domain = ""
+		post.Image = nil
+		post.Poll = nil
+	}
+	post.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	// Deleted posts give up their sticky slot
+	subreddit.unsticky(post)
+	return nil
+}
+
 // Comment Methods
 func (e *RedditEngine) AddComment(content, author, postID, parentCommentID string) (*Comment, error) {
+	if err := e.checkNotSuspended(author); err != nil {
+		return nil, err
+	}
+
+	if err := requireText("comment", content); err != nil {
+		return nil, err
+	}
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	post, ok := e.posts[postID]
-	if !ok {
-		return nil, fmt.Errorf("post not found")
+	user, err := e.lookupUser(author)
+	if err != nil {
+		return nil, err
 	}
 
+	post, err := e.lookupPost(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	var parent *Comment
+	if parentCommentID != "" {
+		parent, err = e.lookupComment(parentCommentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.PostID != postID {
+			return nil, &ValidationError{Field: "parent_id", Reason: fmt.Sprintf("comment %q belongs to another post", parentCommentID)}
+		}
+	}
+
+	if err := e.checkCanComment(author, post); err != nil {
+		return nil, err
+	}
+	if err := e.checkCanReply(author, post, parent); err != nil {
+		return nil, err
+	}
+	subreddit := e.subreddits[post.Subreddit]
+	if err := e.checkRateLimit(user, RateLimitComments, subreddit); err != nil {
+		return nil, err
+	}
+
+	comment, err := e.insertComment(post, parent, author, content)
+	if err != nil {
+		return nil, err
+	}
+	e.recordRateLimit(user, RateLimitComments, subreddit)
+
+	e.applyAutoModerator(user, post, comment)
+	return comment, nil
+}
+
+// insertComment adds a comment to post, as a reply to parent when it is not
+// nil. Callers must hold e.mu for writing.
+func (e *RedditEngine) insertComment(post *Post, parent *Comment, author, content string) (*Comment, error) {
 	comment := &Comment{
 		ID:        fmt.Sprintf("comment_%d", time.Now().UnixNano()),
 		Content:   content,
 		Author:    author,
-		ParentID:  parentCommentID,
+		PostID:    post.ID,
 		CreatedAt: time.Now(),
+		Voters:    make(map[string]int),
 		Children:  make([]*Comment, 0),
 	}
+	if parent != nil {
+		comment.ParentID = parent.ID
+	}
 
 	post.mu.Lock()
 	defer post.mu.Unlock()
 
-	if parentCommentID == "" {
+	if parent == nil {
 		post.Comments = append(post.Comments, comment)
 	} else {
-		parent := findComment(post.Comments, parentCommentID)
-		if parent == nil {
-			return nil, fmt.Errorf("parent comment not found")
-		}
 		parent.mu.Lock()
+		if parent.Deleted {
+			parent.mu.Unlock()
+			return nil, newError(ErrConflict, "cannot reply to a deleted comment")
+		}
 		parent.Children = append(parent.Children, comment)
 		parent.mu.Unlock()
 	}
 
+	e.comments[comment.ID] = comment
+
 	return comment, nil
 }
 
-func findComment(comments []*Comment, commentID string) *Comment {
-	for _, comment := range comments {
-		if comment.ID == commentID {
-			return comment
-		}
-		if found := findComment(comment.Children, commentID); found != nil {
-			return found
+// GetComment returns a single comment by ID
+func (e *RedditEngine) GetComment(commentID string) (*Comment, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.lookupComment(commentID)
+}
+
+// GetCommentContext returns a comment together with up to context of its
+// ancestors, ordered from the highest ancestor down to the comment itself,
+// as seen by viewer. Comments by users the viewer blocked are "[blocked]"
+// placeholders.
+func (e *RedditEngine) GetCommentContext(commentID string, context int, viewer string) ([]*Comment, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	comment, err := e.lookupComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+	post, err := e.lookupPost(comment.PostID)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkCanViewPost(post, viewer); err != nil {
+		return nil, err
+	}
+
+	thread := []*Comment{comment}
+	for len(thread) <= context && comment.ParentID != "" {
+		parent, ok := e.comments[comment.ParentID]
+		if !ok {
+			break
 		}
+		thread = append([]*Comment{parent}, thread...)
+		comment = parent
 	}
+
+	blocked := e.blockedBy(viewer)
+	for i, comment := range thread {
+		thread[i], _ = viewWithBlocks(comment, blocked)
+	}
+	return thread, nil
+}
+
+// EditComment replaces the text of a comment. Only the author may edit, and
+// deleted comments cannot be edited.
+func (e *RedditEngine) EditComment(commentID, editor, content string) (*Comment, error) {
+	if err := e.checkNotSuspended(editor); err != nil {
+		return nil, err
+	}
+
+	comment, err := e.GetComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment.mu.Lock()
+	defer comment.mu.Unlock()
+
+	if comment.Deleted {
+		return nil, newError(ErrConflict, "comment has been deleted")
+	}
+	if comment.Author != editor {
+		return nil, newError(ErrForbidden, "only the author can edit this comment")
+	}
+
+	comment.Content = content
+	comment.EditedAt = time.Now()
+	return comment, nil
+}
+
+// DeleteComment replaces a comment's author and text with "[deleted]". The
+// comment stays in the tree so its replies remain reachable. Only the author
+// may delete.
+func (e *RedditEngine) DeleteComment(commentID, requester string) error {
+	if err := e.checkNotSuspended(requester); err != nil {
+		return err
+	}
+
+	comment, err := e.GetComment(commentID)
+	if err != nil {
+		return err
+	}
+
+	comment.mu.Lock()
+	defer comment.mu.Unlock()
+
+	if comment.Deleted {
+		return newError(ErrConflict, "comment has already been deleted")
+	}
+	if comment.Author != requester {
+		return newError(ErrForbidden, "only the author can delete this comment")
+	}
+
+	comment.Deleted = true
+	comment.Author = deletedMarker
+	comment.Content = deletedMarker
 	return nil
 }
 
 // Voting and Karma Methods
-func (e *RedditEngine) VotePost(postID string, upvote bool) error {
+
+// VotePost records voter's vote on a post. Each user holds at most one vote
+// per post: repeating a vote is a no-op, voting the other way switches it and
+// VoteNone withdraws it. The author's karma moves by the change in the vote.
+func (e *RedditEngine) VotePost(postID, voter string, direction int) (*VoteResult, error) {
+	if err := e.checkNotSuspended(voter); err != nil {
+		return nil, err
+	}
+
+	var post *Post
+	if direction < VoteDown || direction > VoteUp {
+		return nil, &ValidationError{Field: "vote direction", Reason: fmt.Sprintf("%d is not one of -1, 0 or 1", direction)}
+	}
+
 	e.mu.RLock()
-	post, ok := e.posts[postID]
+	_, err := e.lookupUser(voter)
+	if err == nil {
+		post, err = e.lookupPost(postID)
+	}
+	if err == nil {
+		err = e.checkCanVote(voter, post.Subreddit)
+	}
 	e.mu.RUnlock()
 
-	if !ok {
-		return fmt.Errorf("post not found")
+	if err != nil {
+		return nil, err
 	}
 
 	post.mu.Lock()
-	if upvote {
-		post.Votes += 2
-		e.updateKarma(post.Author, 1)
-	} else {
-		post.Votes--
-		e.updateKarma(post.Author, -1)
+	if post.Deleted {
+		post.mu.Unlock()
+		return nil, newError(ErrConflict, "post has been deleted")
+	}
+	if post.VotesFrozen {
+		post.mu.Unlock()
+		return nil, newError(ErrConflict, "votes on this post are frozen")
 	}
+	delta := applyVote(post.Voters, &post.Upvotes, &post.Downvotes, voter, direction)
+	post.Votes = post.Upvotes - post.Downvotes
+	result := &VoteResult{
+		Upvotes:   post.Upvotes,
+		Downvotes: post.Downvotes,
+		Score:     post.Votes,
+	}
+	author := post.Author
 	post.mu.Unlock()
 
-	return nil
+	if delta != 0 {
+		e.updateKarma(author, delta, 0)
+	}
+
+	return result, nil
 }
 
-func (e *RedditEngine) updateKarma(username string, value int) {
+// VoteComment records voter's vote on a comment with the same rules as
+// VotePost. The change is credited to the author's comment karma.
+func (e *RedditEngine) VoteComment(commentID, voter string, direction int) (*VoteResult, error) {
+	if err := e.checkNotSuspended(voter); err != nil {
+		return nil, err
+	}
+
+	var comment *Comment
+	if direction < VoteDown || direction > VoteUp {
+		return nil, &ValidationError{Field: "vote direction", Reason: fmt.Sprintf("%d is not one of -1, 0 or 1", direction)}
+	}
+
+	e.mu.RLock()
+	_, err := e.lookupUser(voter)
+	if err == nil {
+		comment, err = e.lookupComment(commentID)
+	}
+	if err == nil {
+		var post *Post
+		if post, err = e.lookupPost(comment.PostID); err == nil {
+			err = e.checkCanVote(voter, post.Subreddit)
+		}
+		if err == nil {
+			post.mu.RLock()
+			if post.VotesFrozen {
+				err = newError(ErrConflict, "votes in this thread are frozen")
+			}
+			post.mu.RUnlock()
+		}
+	}
+	e.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	comment.mu.Lock()
+	if comment.Deleted {
+		comment.mu.Unlock()
+		return nil, newError(ErrConflict, "comment has been deleted")
+	}
+	delta := applyVote(comment.Voters, &comment.Upvotes, &comment.Downvotes, voter, direction)
+	comment.Votes = comment.Upvotes - comment.Downvotes
+	result := &VoteResult{
+		Upvotes:   comment.Upvotes,
+		Downvotes: comment.Downvotes,
+		Score:     comment.Votes,
+	}
+	author := comment.Author
+	comment.mu.Unlock()
+
+	if delta != 0 {
+		e.updateKarma(author, 0, delta)
+	}
+
+	return result, nil
+}
+
+// applyVote updates a vote ledger and its counters, returning the change in
+// score (between -2 and 2) caused by the vote. Callers must hold the lock of
+// the item owning the ledger.
+func applyVote(voters map[string]int, upvotes, downvotes *int, voter string, direction int) int {
+	previous := voters[voter]
+	if previous == direction {
+		return 0
+	}
+
+	switch previous {
+	case VoteUp:
+		*upvotes--
+	case VoteDown:
+		*downvotes--
+	}
+
+	switch direction {
+	case VoteUp:
+		*upvotes++
+		voters[voter] = VoteUp
+	case VoteDown:
+		*downvotes++
+		voters[voter] = VoteDown
+	default:
+		delete(voters, voter)
+	}
+
+	return direction - previous
+}
+
+func (e *RedditEngine) updateKarma(username string, postDelta, commentDelta int) {
 	e.mu.RLock()
 	user, ok := e.users[username]
 	e.mu.RUnlock()
 
 	if ok {
 		user.mu.Lock()
-		user.Karma += value
+		user.PostKarma += postDelta
+		user.CommentKarma += commentDelta
 		user.mu.Unlock()
 	}
 }
 
 // Feed Generation
-func (e *RedditEngine) GetUserFeed(username string) ([]*Post, error) {
+
+// GetUserFeed returns the posts of every subreddit the user has joined and
+// can still see, ordered by sortMode. window restricts top and controversial feeds. Removed
+// and filtered posts are left out unless the user moderates them.
+func (e *RedditEngine) GetUserFeed(username string, sortMode SortMode, window TimeWindow) ([]*Post, error) {
 	e.mu.RLock()
-	user, ok := e.users[username]
+	user, err := e.lookupUser(username)
 	e.mu.RUnlock()
 
-	if !ok {
-		return nil, fmt.Errorf("user not found")
+	if err != nil {
+		return nil, err
 	}
 
-	var feed []*Post
+	// Copy what the feed needs from the user first; e.mu is taken before
+	// user.mu everywhere else
 	user.mu.RLock()
-	defer user.mu.RUnlock()
-
+	joined := make([]string, 0, len(user.Subreddits))
 	for subredditName := range user.Subreddits {
+		joined = append(joined, subredditName)
+	}
+	blocked := make(map[string]bool, len(user.Blocked))
+	for target := range user.Blocked {
+		blocked[target] = true
+	}
+	hidden := make(map[string]time.Time, len(user.Hidden))
+	for id, at := range user.Hidden {
+		hidden[id] = at
+	}
+	user.mu.RUnlock()
+
+	var feed []*Post
+	for _, subredditName := range joined {
 		e.mu.RLock()
-		subreddit := e.subreddits[subredditName]
+		subreddit, ok := e.subreddits[subredditName]
 		e.mu.RUnlock()
 
+		// Skip memberships of subreddits that no longer exist
+		if !ok {
+			continue
+		}
+
 		subreddit.mu.RLock()
-		feed = append(feed, subreddit.Posts...)
+		if subreddit.canView(username) {
+			feed = append(feed, subreddit.Posts...)
+		}
 		subreddit.mu.RUnlock()
 	}
 
-	sortPosts(feed)
-	return feed, nil
+	feed = withoutAuthors(feed, blocked)
+	feed = withoutHidden(feed, hidden)
+	return sortPosts(e.visiblePosts(feed, username), sortMode, window), nil
+}
+
+// GetSubredditPosts returns the posts of a single subreddit ordered by
+// sortMode, as seen by viewer (who may be anonymous). Sticky posts come
+// first. Private subreddits are forbidden to viewers who cannot see them.
+func (e *RedditEngine) GetSubredditPosts(subredditName, viewer string, sortMode SortMode, window TimeWindow) ([]*Post, error) {
+	e.mu.RLock()
+	subreddit, err := e.lookupSubreddit(subredditName)
+	e.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	if err := subreddit.checkCanView(viewer); err != nil {
+		subreddit.mu.RUnlock()
+		return nil, err
+	}
+	posts := make([]*Post, len(subreddit.Posts))
+	copy(posts, subreddit.Posts)
+	stickied := make([]*Post, len(subreddit.Stickied))
+	copy(stickied, subreddit.Stickied)
+	subreddit.mu.RUnlock()
+
+	sorted := sortPosts(e.visiblePosts(posts, viewer), sortMode, window)
+	return stickyFirst(sorted, e.visiblePosts(stickied, viewer)), nil
 }
 
 // Direct Message Methods
 func (e *RedditEngine) SendDirectMessage(from, to, content string) (*DirectMessage, error) {
+	if err := e.checkNotSuspended(from); err != nil {
+		return nil, err
+	}
+
+	if err := requireText("message", content); err != nil {
+		return nil, err
+	}
+
 	e.mu.RLock() // Use RLock instead of Lock for checking users
-	_, fromExists := e.users[from]
-	_, toExists := e.users[to]
+	sender, err := e.lookupUser(from)
+	if err == nil {
+		err = e.checkNotBlocked(from, to)
+	}
+	if err == nil {
+		err = e.takeRateLimit(sender, RateLimitMessages, nil)
+	}
 	e.mu.RUnlock()
 
-	if !fromExists {
-		return nil, fmt.Errorf("sender not found")
-	}
-	if !toExists {
-		return nil, fmt.Errorf("recipient not found")
+	if err != nil {
+		return nil, err
 	}
 
 	dm := &DirectMessage{
@@ -364,9 +1062,22 @@ func (e *RedditEngine) SendDirectMessage(from, to, content string) (*DirectMessa
 }
 
 func (e *RedditEngine) ReplyToDirectMessage(originalMsgID, from, content string) (*DirectMessage, error) {
+	if err := e.checkNotSuspended(from); err != nil {
+		return nil, err
+	}
+
+	if err := requireText("message", content); err != nil {
+		return nil, err
+	}
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	sender, err := e.lookupUser(from)
+	if err != nil {
+		return nil, err
+	}
+
 	var originalDM *DirectMessage
 	for _, messages := range e.directMessages {
 		for _, dm := range messages {
@@ -378,13 +1089,27 @@ func (e *RedditEngine) ReplyToDirectMessage(originalMsgID, from, content string)
 	}
 
 	if originalDM == nil {
-		return nil, fmt.Errorf("original message not found")
+		return nil, &NotFoundError{Kind: "message", ID: originalMsgID}
+	}
+
+	// Only the two people in the conversation can reply to it
+	recipient := originalDM.From
+	if from == originalDM.From {
+		recipient = originalDM.To
+	} else if from != originalDM.To {
+		return nil, &ValidationError{Field: "message", Reason: fmt.Sprintf("%s is not part of this conversation", from)}
+	}
+	if err := e.checkNotBlocked(from, recipient); err != nil {
+		return nil, err
+	}
+	if err := e.takeRateLimit(sender, RateLimitMessages, nil); err != nil {
+		return nil, err
 	}
 
 	reply := &DirectMessage{
 		ID:        fmt.Sprintf("dm_%d", time.Now().UnixNano()),
 		From:      from,
-		To:        originalDM.From,
+		To:        recipient,
 		Content:   content,
 		CreatedAt: time.Now(),
 	}
@@ -400,21 +1125,17 @@ func (e *RedditEngine) GetDirectMessages(username string) ([]*DirectMessage, err
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
-	if _, ok := e.users[username]; !ok {
-		return nil, fmt.Errorf("user not found")
+	user, err := e.lookupUser(username)
+	if err != nil {
+		return nil, err
 	}
 
-	return e.directMessages[username], nil
-}
-
-// Helper Functions
-func sortPosts(posts []*Post) {
-	n := len(posts)
-	for i := 0; i < n-1; i++ {
-		for j := 0; j < n-i-1; j++ {
-			if posts[j].CreatedAt.Before(posts[j+1].CreatedAt) {
-				posts[j], posts[j+1] = posts[j+1], posts[j]
-			}
+	// Messages from blocked users stay out of the inbox
+	messages := make([]*DirectMessage, 0, len(e.directMessages[username]))
+	for _, dm := range e.directMessages[username] {
+		if !user.hasBlocked(dm.From) {
+			messages = append(messages, dm)
 		}
 	}
+	return messages, nil
 }
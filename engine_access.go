@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SubredditType controls who may read and post in a subreddit
+type SubredditType string
+
+const (
+	SubredditPublic     SubredditType = "public"     // anyone can read, join and post
+	SubredditRestricted SubredditType = "restricted" // anyone can read, approved users post
+	SubredditPrivate    SubredditType = "private"    // only approved users can see it
+)
+
+// ParseSubredditType converts a type name into a SubredditType. An empty
+// string selects SubredditPublic.
+func ParseSubredditType(value string) (SubredditType, error) {
+	switch subType := SubredditType(value); subType {
+	case "":
+		return SubredditPublic, nil
+	case SubredditPublic, SubredditRestricted, SubredditPrivate:
+		return subType, nil
+	}
+	return "", &ValidationError{Field: "type", Reason: fmt.Sprintf("unknown subreddit type %q", value)}
+}
+
+// JoinRequest is a user asking to join a private subreddit
+type JoinRequest struct {
+	Username  string
+	Message   string
+	CreatedAt time.Time
+}
+
+// canView reports whether username may see the subreddit and its content.
+// Callers must hold subreddit.mu.
+func (s *Subreddit) canView(username string) bool {
+	return s.checkCanView(username) == nil
+}
+
+// checkCanView returns a forbidden error unless username may see the
+// subreddit. Nobody sees a banned subreddit. Private subreddits are open to
+// approved users and moderators only, and quarantined ones to moderators and
+// users who opted in. Callers must hold subreddit.mu.
+func (s *Subreddit) checkCanView(username string) error {
+	if s.Banned {
+		return newError(ErrForbidden, "%s is banned: %s", s.Name, s.BanReason)
+	}
+	moderator := s.hasModPermission(username, 0)
+	if s.Type == SubredditPrivate && !s.Approved[username] && !moderator {
+		return newError(ErrForbidden, "%s is private", s.Name)
+	}
+	if s.Quarantined && !s.QuarantineOptIns[username] && !moderator {
+		return newError(ErrForbidden, "%s is quarantined; opt in to view it", s.Name)
+	}
+	return nil
+}
+
+// checkCanSubmit returns a forbidden error unless username may post in the
+// subreddit. Callers must hold subreddit.mu.
+func (s *Subreddit) checkCanSubmit(username string) error {
+	if err := s.checkCanView(username); err != nil {
+		return err
+	}
+	if s.Type == SubredditRestricted && !s.Approved[username] && !s.hasModPermission(username, 0) {
+		return newError(ErrForbidden, "only approved users may post in %s", s.Name)
+	}
+	return nil
+}
+
+// CanViewSubreddit returns a forbidden error unless viewer (who may be
+// anonymous) may see the subreddit and its content
+func (e *RedditEngine) CanViewSubreddit(subredditName, viewer string) error {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+	return subreddit.checkCanView(viewer)
+}
+
+// checkCanViewPost returns a forbidden error unless viewer may see the
+// subreddit of post. Callers must hold e.mu.
+func (e *RedditEngine) checkCanViewPost(post *Post, viewer string) error {
+	subreddit, err := e.lookupSubreddit(post.Subreddit)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+	return subreddit.checkCanView(viewer)
+}
+
+// SetSubredditType changes the type of a subreddit. Existing members of a
+// subreddit made private lose access unless they are approved. Needs the
+// config permission.
+func (e *RedditEngine) SetSubredditType(subredditName, actor string, subType SubredditType) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	if err := requireText("type", string(subType)); err != nil {
+		return err
+	}
+	if _, err := ParseSubredditType(string(subType)); err != nil {
+		return err
+	}
+
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermConfig); err != nil {
+		return err
+	}
+	if subreddit.Type == subType {
+		return nil
+	}
+	subreddit.Type = subType
+	subreddit.logModAction(actor, LogEditSettings, subredditName, fmt.Sprintf("type %s", subType))
+	return nil
+}
+
+// ApproveUser lets username post in a restricted subreddit and see a private
+// one. In a private subreddit the approval doubles as an invite to join.
+// Needs the access permission.
+func (e *RedditEngine) ApproveUser(subredditName, actor, username string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	subreddit, err := e.lookupModTarget(subredditName, username)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermAccess); err != nil {
+		return err
+	}
+	if subreddit.Approved[username] {
+		return newError(ErrAlreadyExists, "%s is already approved in %s", username, subredditName)
+	}
+	subreddit.Approved[username] = true
+	subreddit.logModAction(actor, LogApproveUser, username, "")
+	return nil
+}
+
+// UnapproveUser takes back an approval. Needs the access permission.
+func (e *RedditEngine) UnapproveUser(subredditName, actor, username string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermAccess); err != nil {
+		return err
+	}
+	if !subreddit.Approved[username] {
+		return &NotFoundError{Kind: "approved user", ID: username}
+	}
+	delete(subreddit.Approved, username)
+	subreddit.logModAction(actor, LogUnapproveUser, username, "")
+	return nil
+}
+
+// GetApprovedUsers returns the approved users of a subreddit in alphabetical
+// order. Needs the access permission.
+func (e *RedditEngine) GetApprovedUsers(subredditName, actor string) ([]string, error) {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	if err := subreddit.checkModPermission(actor, PermAccess); err != nil {
+		subreddit.mu.RUnlock()
+		return nil, err
+	}
+	approved := make([]string, 0, len(subreddit.Approved))
+	for username := range subreddit.Approved {
+		approved = append(approved, username)
+	}
+	subreddit.mu.RUnlock()
+
+	sort.Strings(approved)
+	return approved, nil
+}
+
+// RequestToJoin asks the moderators of a private subreddit to let username
+// in. Each user can have one pending request per subreddit.
+func (e *RedditEngine) RequestToJoin(subredditName, username, message string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+
+	subreddit, err := e.lookupModTarget(subredditName, username)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkNotBanned(username); err != nil {
+		return err
+	}
+	if subreddit.Type != SubredditPrivate || subreddit.Approved[username] || subreddit.hasModPermission(username, 0) {
+		return newError(ErrConflict, "%s can join %s without a request", username, subredditName)
+	}
+	if _, pending := subreddit.JoinRequests[username]; pending {
+		return newError(ErrAlreadyExists, "%s has already asked to join %s", username, subredditName)
+	}
+	subreddit.JoinRequests[username] = &JoinRequest{
+		Username:  username,
+		Message:   message,
+		CreatedAt: time.Now(),
+	}
+	return nil
+}
+
+// GetJoinRequests returns the pending join requests of a subreddit, oldest
+// first. Needs the access permission.
+func (e *RedditEngine) GetJoinRequests(subredditName, actor string) ([]*JoinRequest, error) {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	if err := subreddit.checkModPermission(actor, PermAccess); err != nil {
+		subreddit.mu.RUnlock()
+		return nil, err
+	}
+	requests := make([]*JoinRequest, 0, len(subreddit.JoinRequests))
+	for _, request := range subreddit.JoinRequests {
+		requests = append(requests, request)
+	}
+	subreddit.mu.RUnlock()
+
+	sort.Slice(requests, func(i, j int) bool {
+		if !requests[i].CreatedAt.Equal(requests[j].CreatedAt) {
+			return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+		}
+		return requests[i].Username < requests[j].Username
+	})
+	return requests, nil
+}
+
+// ApproveJoinRequest approves the user behind a join request and makes them
+// a member. Needs the access permission.
+func (e *RedditEngine) ApproveJoinRequest(subredditName, actor, username string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	subreddit, err := e.lookupSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+	user, err := e.lookupUser(username)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	if err := subreddit.checkModPermission(actor, PermAccess); err != nil {
+		subreddit.mu.Unlock()
+		return err
+	}
+	if _, pending := subreddit.JoinRequests[username]; !pending {
+		subreddit.mu.Unlock()
+		return &NotFoundError{Kind: "join request", ID: username}
+	}
+	delete(subreddit.JoinRequests, username)
+	subreddit.Approved[username] = true
+	subreddit.Members[username] = true
+	subreddit.logModAction(actor, LogApproveJoinRequest, username, "")
+	subreddit.mu.Unlock()
+
+	user.mu.Lock()
+	user.Subreddits[subredditName] = true
+	user.mu.Unlock()
+
+	return nil
+}
+
+// DenyJoinRequest drops a join request. Needs the access permission.
+func (e *RedditEngine) DenyJoinRequest(subredditName, actor, username string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermAccess); err != nil {
+		return err
+	}
+	if _, pending := subreddit.JoinRequests[username]; !pending {
+		return &NotFoundError{Kind: "join request", ID: username}
+	}
+	delete(subreddit.JoinRequests, username)
+	subreddit.logModAction(actor, LogDenyJoinRequest, username, "")
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseSubredditType(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    SubredditType
+		wantErr error
+	}{
+		{"", SubredditPublic, nil},
+		{"public", SubredditPublic, nil},
+		{"restricted", SubredditRestricted, nil},
+		{"private", SubredditPrivate, nil},
+		{"secret", "", ErrValidation},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSubredditType(tt.value)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseSubredditType(%q) = %q, %v, want %q, %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSubredditTypes(t *testing.T) {
+	// wantErr holds the errors of viewing, joining, posting and commenting
+	tests := []struct {
+		subType      SubredditType
+		wantOutsider [4]error
+		wantApproved [4]error
+	}{
+		{SubredditPublic, [4]error{nil, nil, nil, nil}, [4]error{nil, nil, nil, nil}},
+		{SubredditRestricted, [4]error{nil, nil, ErrForbidden, nil}, [4]error{nil, nil, nil, nil}},
+		{SubredditPrivate, [4]error{ErrForbidden, ErrForbidden, ErrForbidden, ErrForbidden}, [4]error{nil, nil, nil, nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.subType), func(t *testing.T) {
+			e := newTestEngine(t, "owner", "outsider", "approved")
+			mustCreateSubreddit(t, e, "community", "owner", tt.subType)
+			post := mustCreatePost(t, e, "owner", "community", "welcome")
+			if err := e.ApproveUser("community", "owner", "approved"); err != nil {
+				t.Fatalf("ApproveUser: %v", err)
+			}
+
+			for username, want := range map[string][4]error{"outsider": tt.wantOutsider, "approved": tt.wantApproved} {
+				viewErr := e.CanViewSubreddit("community", username)
+				joinErr := e.JoinSubreddit(username, "community")
+				_, postErr := e.CreatePost("hello", "content", username, "community")
+				_, commentErr := e.AddComment("hi", username, post.ID, "")
+				got := [4]error{viewErr, joinErr, postErr, commentErr}
+				for i, action := range [4]string{"view", "join", "post", "comment"} {
+					if !errors.Is(got[i], want[i]) {
+						t.Errorf("%s %s error = %v, want %v", username, action, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPrivateSubredditListings(t *testing.T) {
+	e := newTestEngine(t, "owner", "member", "outsider")
+	mustCreateSubreddit(t, e, "open", "owner", SubredditPublic)
+	mustCreateSubreddit(t, e, "closed", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "closed", "members only")
+	if err := e.JoinSubreddit("member", "closed"); err != nil {
+		t.Fatalf("JoinSubreddit: %v", err)
+	}
+
+	if err := e.SetSubredditType("closed", "member", SubredditPrivate); !errors.Is(err, ErrForbidden) {
+		t.Errorf("SetSubredditType by a non-moderator error = %v, want ErrForbidden", err)
+	}
+	for _, subType := range []SubredditType{"", "secret"} {
+		if err := e.SetSubredditType("closed", "owner", subType); !errors.Is(err, ErrValidation) {
+			t.Errorf("SetSubredditType(%q) error = %v, want ErrValidation", subType, err)
+		}
+	}
+	if err := e.SetSubredditType("closed", "owner", SubredditPrivate); err != nil {
+		t.Fatalf("SetSubredditType: %v", err)
+	}
+
+	listed := func(viewer string) []string {
+		names := make([]string, 0)
+		for _, subreddit := range e.ListSubreddits("", SubredditSortOld, viewer) {
+			names = append(names, subreddit.Name)
+		}
+		return names
+	}
+	if got, want := listed("outsider"), []string{"open"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListSubreddits(outsider) = %v, want %v", got, want)
+	}
+	if got, want := listed("owner"), []string{"open", "closed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListSubreddits(owner) = %v, want %v", got, want)
+	}
+
+	// An existing member who is not approved loses access
+	feed, err := e.GetUserFeed("member", SortNew, TimeAll)
+	if err != nil {
+		t.Fatalf("GetUserFeed: %v", err)
+	}
+	if len(feed) != 0 {
+		t.Errorf("GetUserFeed = %v, want no posts from the private subreddit", postIDs(feed))
+	}
+	if _, err := e.GetComments(post.ID, "member"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetComments error = %v, want ErrForbidden", err)
+	}
+	if _, err := e.GetSubredditPosts("closed", "", SortNew, TimeAll); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetSubredditPosts(anonymous) error = %v, want ErrForbidden", err)
+	}
+
+	if err := e.ApproveUser("closed", "owner", "member"); err != nil {
+		t.Fatalf("ApproveUser: %v", err)
+	}
+	if feed, _ := e.GetUserFeed("member", SortNew, TimeAll); len(feed) != 1 {
+		t.Errorf("GetUserFeed after approval = %d posts, want 1", len(feed))
+	}
+}
+
+func TestJoinRequests(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "open", "owner", SubredditPublic)
+	mustCreateSubreddit(t, e, "closed", "owner", SubredditPrivate)
+
+	if err := e.RequestToJoin("open", "alice", "hi"); !errors.Is(err, ErrConflict) {
+		t.Errorf("RequestToJoin a public subreddit error = %v, want ErrConflict", err)
+	}
+	for _, username := range []string{"alice", "bob"} {
+		if err := e.RequestToJoin("closed", username, "let me in"); err != nil {
+			t.Fatalf("RequestToJoin(%q): %v", username, err)
+		}
+	}
+	if err := e.RequestToJoin("closed", "alice", "please"); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("repeat RequestToJoin error = %v, want ErrAlreadyExists", err)
+	}
+
+	if _, err := e.GetJoinRequests("closed", "alice"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetJoinRequests by a non-moderator error = %v, want ErrForbidden", err)
+	}
+	requests, err := e.GetJoinRequests("closed", "owner")
+	if err != nil {
+		t.Fatalf("GetJoinRequests: %v", err)
+	}
+	if len(requests) != 2 || requests[0].Username != "alice" || requests[1].Username != "bob" {
+		t.Errorf("GetJoinRequests = %d requests, want alice's then bob's", len(requests))
+	}
+
+	if err := e.ApproveJoinRequest("closed", "owner", "alice"); err != nil {
+		t.Fatalf("ApproveJoinRequest: %v", err)
+	}
+	if err := e.DenyJoinRequest("closed", "owner", "bob"); err != nil {
+		t.Fatalf("DenyJoinRequest: %v", err)
+	}
+	if err := e.DenyJoinRequest("closed", "owner", "bob"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("repeat DenyJoinRequest error = %v, want ErrNotFound", err)
+	}
+
+	members, err := e.GetSubredditMembers("closed", "alice")
+	if err != nil {
+		t.Fatalf("GetSubredditMembers: %v", err)
+	}
+	if !reflect.DeepEqual(members, []string{"alice"}) {
+		t.Errorf("GetSubredditMembers = %v, want [alice]", members)
+	}
+	if err := e.CanViewSubreddit("closed", "bob"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("CanViewSubreddit(denied user) error = %v, want ErrForbidden", err)
+	}
+}
@@ -14,11 +14,16 @@ type RedditEngineActor struct {
 
 type GetCommentsMessage struct {
 	PostID string
+	Viewer string
 }
 
-func NewRedditEngineActor() actor.Actor {
+// NewRedditEngineActor creates an engine actor with its own engine, which
+// enforces the given rate limits
+func NewRedditEngineActor(limits RateLimits) actor.Actor {
+	engine := NewRedditEngine()
+	engine.SetRateLimits(limits)
 	return &RedditEngineActor{
-		engine: NewRedditEngine(),
+		engine: engine,
 	}
 }
 
@@ -38,6 +43,22 @@ func PrintCommentTree(comments []*Comment, indent int) {
 	}
 }
 
+// listingSort and listingTime apply the listing defaults to message fields
+// left empty by the sender
+func listingSort(mode SortMode) SortMode {
+	if mode == "" {
+		return SortNew
+	}
+	return mode
+}
+
+func listingTime(window TimeWindow) TimeWindow {
+	if window == "" {
+		return TimeAll
+	}
+	return window
+}
+
 func (state *RedditEngineActor) Receive(context actor.Context) {
 	switch msg := context.Message().(type) {
 	case *RegisterUserMessage:
@@ -45,7 +66,154 @@ func (state *RedditEngineActor) Receive(context actor.Context) {
 		context.Respond(err)
 
 	case *CreateSubredditMessage:
-		err := state.engine.CreateSubreddit(msg.Name, msg.Description, msg.Creator)
+		err := state.engine.CreateSubreddit(msg.Name, msg.Description, msg.Creator, msg.Type)
+		context.Respond(err)
+
+	case *ListSubredditsMessage:
+		order := msg.Sort
+		if order == "" {
+			order = SubredditSortMembers
+		}
+		context.Respond(state.engine.ListSubreddits(msg.Prefix, order, msg.Viewer))
+
+	case *GetSubredditMessage:
+		subreddit, err := state.engine.GetSubreddit(msg.Name)
+		context.Respond(&struct {
+			Subreddit *Subreddit
+			Err       error
+		}{subreddit, err})
+
+	case *GetSubredditMembersMessage:
+		members, err := state.engine.GetSubredditMembers(msg.Subreddit, msg.Viewer)
+		context.Respond(&struct {
+			Members []string
+			Err     error
+		}{members, err})
+
+	case *SuspendUserMessage:
+		context.Respond(state.engine.SuspendUser(msg.Actor, msg.Username, msg.Reason, msg.Duration))
+
+	case *UnsuspendUserMessage:
+		context.Respond(state.engine.UnsuspendUser(msg.Actor, msg.Username))
+
+	case *BanSubredditMessage:
+		var err error
+		if msg.Ban {
+			err = state.engine.BanSubreddit(msg.Actor, msg.Subreddit, msg.Reason)
+		} else {
+			err = state.engine.UnbanSubreddit(msg.Actor, msg.Subreddit)
+		}
+		context.Respond(err)
+
+	case *DeleteSubredditMessage:
+		context.Respond(state.engine.DeleteSubreddit(msg.Actor, msg.Subreddit))
+
+	case *QuarantineSubredditMessage:
+		context.Respond(state.engine.QuarantineSubreddit(msg.Actor, msg.Subreddit, msg.Quarantine))
+
+	case *OptInToQuarantineMessage:
+		context.Respond(state.engine.OptInToQuarantine(msg.Username, msg.Subreddit))
+
+	case *SetSubredditTypeMessage:
+		context.Respond(state.engine.SetSubredditType(msg.Subreddit, msg.Actor, msg.Type))
+
+	case *SetRequirePostFlairMessage:
+		context.Respond(state.engine.SetRequirePostFlair(msg.Subreddit, msg.Actor, msg.Require))
+
+	case *AddFlairTemplateMessage:
+		template, err := state.engine.AddFlairTemplate(msg.Subreddit, msg.Actor, msg.Template)
+		context.Respond(&struct {
+			Template *FlairTemplate
+			Err      error
+		}{template, err})
+
+	case *RemoveFlairTemplateMessage:
+		context.Respond(state.engine.RemoveFlairTemplate(msg.Subreddit, msg.Actor, msg.TemplateID))
+
+	case *GetFlairTemplatesMessage:
+		templates, err := state.engine.GetFlairTemplates(msg.Subreddit, msg.Viewer, msg.Kind)
+		context.Respond(&struct {
+			Templates []*FlairTemplate
+			Err       error
+		}{templates, err})
+
+	case *SetPostFlairMessage:
+		context.Respond(state.engine.SetPostFlair(msg.PostID, msg.Actor, msg.TemplateID, msg.Text))
+
+	case *SetUserFlairMessage:
+		context.Respond(state.engine.SetUserFlair(msg.Subreddit, msg.Actor, msg.Username, msg.TemplateID, msg.Text))
+
+	case *GetApprovedUsersMessage:
+		approved, err := state.engine.GetApprovedUsers(msg.Subreddit, msg.Actor)
+		context.Respond(&struct {
+			Approved []string
+			Err      error
+		}{approved, err})
+
+	case *ApproveUserMessage:
+		var err error
+		if msg.Approve {
+			err = state.engine.ApproveUser(msg.Subreddit, msg.Actor, msg.Username)
+		} else {
+			err = state.engine.UnapproveUser(msg.Subreddit, msg.Actor, msg.Username)
+		}
+		context.Respond(err)
+
+	case *RequestToJoinMessage:
+		context.Respond(state.engine.RequestToJoin(msg.Subreddit, msg.Username, msg.Message))
+
+	case *GetJoinRequestsMessage:
+		requests, err := state.engine.GetJoinRequests(msg.Subreddit, msg.Actor)
+		context.Respond(&struct {
+			Requests []*JoinRequest
+			Err      error
+		}{requests, err})
+
+	case *ReviewJoinRequestMessage:
+		var err error
+		if msg.Approve {
+			err = state.engine.ApproveJoinRequest(msg.Subreddit, msg.Actor, msg.Username)
+		} else {
+			err = state.engine.DenyJoinRequest(msg.Subreddit, msg.Actor, msg.Username)
+		}
+		context.Respond(err)
+
+	case *GetModeratorsMessage:
+		moderators, err := state.engine.GetModerators(msg.Subreddit)
+		context.Respond(&struct {
+			Moderators []*Moderator
+			Err        error
+		}{moderators, err})
+
+	case *AddModeratorMessage:
+		err := state.engine.AddModerator(msg.Subreddit, msg.Actor, msg.Username, msg.Permissions)
+		context.Respond(err)
+
+	case *InviteModeratorMessage:
+		err := state.engine.InviteModerator(msg.Subreddit, msg.Actor, msg.Username, msg.Permissions)
+		context.Respond(err)
+
+	case *AcceptModInviteMessage:
+		err := state.engine.AcceptModeratorInvite(msg.Subreddit, msg.Username)
+		context.Respond(err)
+
+	case *RemoveModeratorMessage:
+		err := state.engine.RemoveModerator(msg.Subreddit, msg.Actor, msg.Username)
+		context.Respond(err)
+
+	case *GetRestrictionsMessage:
+		restrictions, err := state.engine.listRestrictions(msg.Kind, msg.Subreddit, msg.Actor)
+		context.Respond(&struct {
+			Restrictions []*Restriction
+			Err          error
+		}{restrictions, err})
+
+	case *RestrictUserMessage:
+		err := state.engine.restrictUser(msg.Kind, msg.Subreddit, msg.Actor, msg.Username, msg.Reason, msg.Duration)
+		context.Respond(err)
+
+	case *LiftRestrictionMessage:
+		err := state.engine.liftRestriction(msg.Kind, msg.Subreddit, msg.Actor, msg.Username)
 		context.Respond(err)
 
 	case *JoinSubredditMessage:
@@ -63,13 +231,55 @@ func (state *RedditEngineActor) Receive(context actor.Context) {
 
 	case *CreatePostMessage:
 		fmt.Printf("Engine: Creating post by %s\n", msg.Author)
-		post, err := state.engine.CreatePost(msg.Title, msg.Content, msg.Author, msg.Subreddit)
+		post, err := state.engine.SubmitPost(msg.Author, msg.Subreddit, PostSubmission{
+			Kind:            msg.Kind,
+			Title:           msg.Title,
+			Content:         msg.Content,
+			URL:             msg.URL,
+			Image:           msg.Image,
+			PollOptions:     msg.PollOptions,
+			PollDuration:    msg.PollDuration,
+			FlairTemplateID: msg.FlairTemplateID,
+			FlairText:       msg.FlairText,
+		})
 		fmt.Printf("Engine: Post creation result - Post: %v, Error: %v\n", post != nil, err)
 		context.Respond(&struct {
 			Post *Post
 			Err  error
 		}{post, err})
 
+	case *CrosspostMessage:
+		post, err := state.engine.CrosspostPost(msg.Author, msg.PostID, msg.Subreddit, msg.Title, msg.FlairTemplateID, msg.FlairText)
+		context.Respond(&struct {
+			Post *Post
+			Err  error
+		}{post, err})
+
+	case *GetCrosspostsMessage:
+		posts, err := state.engine.GetCrossposts(msg.PostID, msg.Viewer)
+		context.Respond(&struct {
+			Posts []*Post
+			Err   error
+		}{posts, err})
+
+	case *GetPostMessage:
+		post, err := state.engine.GetPost(msg.PostID)
+		context.Respond(&struct {
+			Post *Post
+			Err  error
+		}{post, err})
+
+	case *EditPostMessage:
+		post, err := state.engine.EditPost(msg.PostID, msg.Editor, msg.Content)
+		context.Respond(&struct {
+			Post *Post
+			Err  error
+		}{post, err})
+
+	case *DeletePostMessage:
+		err := state.engine.DeletePost(msg.PostID, msg.Requester)
+		context.Respond(err)
+
 	case *AddCommentMessage:
 		fmt.Printf("Engine: Adding comment by %s\n", msg.Author)
 		comment, err := state.engine.AddComment(msg.Content, msg.Author, msg.PostID, msg.ParentCommentID)
@@ -79,7 +289,7 @@ func (state *RedditEngineActor) Receive(context actor.Context) {
 		}{comment, err})
 
 	case *GetCommentsMessage:
-		comments, err := state.engine.GetComments(msg.PostID)
+		comments, err := state.engine.GetComments(msg.PostID, msg.Viewer)
 		if err != nil {
 			fmt.Printf("Error retrieving comments for post %s: %v\n", msg.PostID, err)
 			context.Respond(err)
@@ -89,18 +299,133 @@ func (state *RedditEngineActor) Receive(context actor.Context) {
 			context.Respond(comments)
 		}
 
+	case *GetCommentContextMessage:
+		thread, err := state.engine.GetCommentContext(msg.CommentID, msg.Context, msg.Viewer)
+		context.Respond(&struct {
+			Thread []*Comment
+			Err    error
+		}{thread, err})
+
+	case *EditCommentMessage:
+		comment, err := state.engine.EditComment(msg.CommentID, msg.Editor, msg.Content)
+		context.Respond(&struct {
+			Comment *Comment
+			Err     error
+		}{comment, err})
+
+	case *DeleteCommentMessage:
+		err := state.engine.DeleteComment(msg.CommentID, msg.Requester)
+		context.Respond(err)
+
+	case *ReportPostMessage:
+		err := state.engine.ReportPost(msg.PostID, msg.Reporter, msg.Reason)
+		context.Respond(err)
+
+	case *ReportCommentMessage:
+		err := state.engine.ReportComment(msg.CommentID, msg.Reporter, msg.Reason)
+		context.Respond(err)
+
+	case *ModeratePostMessage:
+		err := state.engine.ModeratePost(msg.PostID, msg.Actor, msg.Action, msg.Reason)
+		context.Respond(err)
+
+	case *ModerateCommentMessage:
+		err := state.engine.ModerateComment(msg.CommentID, msg.Actor, msg.Action, msg.Reason)
+		context.Respond(err)
+
+	case *GetModQueueMessage:
+		queue, err := state.engine.GetModQueue(msg.Subreddit, msg.Actor)
+		context.Respond(&struct {
+			Queue []*ModQueueItem
+			Err   error
+		}{queue, err})
+
+	case *StickyPostMessage:
+		var err error
+		if msg.Sticky {
+			err = state.engine.StickyPost(msg.PostID, msg.Actor)
+		} else {
+			err = state.engine.UnstickyPost(msg.PostID, msg.Actor)
+		}
+		context.Respond(err)
+
+	case *LockPostMessage:
+		var err error
+		if msg.Lock {
+			err = state.engine.LockPost(msg.PostID, msg.Actor, msg.FreezeVotes)
+		} else {
+			err = state.engine.UnlockPost(msg.PostID, msg.Actor)
+		}
+		context.Respond(err)
+
+	case *GetModLogMessage:
+		entries, err := state.engine.GetModLog(msg.Subreddit, msg.Actor, msg.Moderator, msg.Action)
+		context.Respond(&struct {
+			Entries []*ModLogEntry
+			Err     error
+		}{entries, err})
+
+	case *GetAutoModRulesMessage:
+		rules, err := state.engine.GetAutoModRules(msg.Subreddit, msg.Actor)
+		context.Respond(&struct {
+			Rules []*AutoModRule
+			Err   error
+		}{rules, err})
+
+	case *SetAutoModRulesMessage:
+		context.Respond(state.engine.SetAutoModRules(msg.Subreddit, msg.Actor, msg.Rules))
+
+	case *TestAutoModRulesMessage:
+		rules, err := state.engine.TestAutoModRules(msg.Subreddit, msg.Actor, msg.Sample)
+		context.Respond(&struct {
+			Rules []*AutoModRule
+			Err   error
+		}{rules, err})
+
 	case *VotePostMessage:
 		fmt.Printf("Engine: Processing vote for post %s\n", msg.PostID)
-		err := state.engine.VotePost(msg.PostID, msg.Upvote)
-		context.Respond(err)
+		result, err := state.engine.VotePost(msg.PostID, msg.Voter, msg.Direction)
+		context.Respond(&struct {
+			Result *VoteResult
+			Err    error
+		}{result, err})
+
+	case *VotePollMessage:
+		results, err := state.engine.VotePoll(msg.PostID, msg.Voter, msg.Option)
+		context.Respond(&struct {
+			Results *PollResults
+			Err     error
+		}{results, err})
+
+	case *VoteCommentMessage:
+		fmt.Printf("Engine: Processing vote for comment %s\n", msg.CommentID)
+		result, err := state.engine.VoteComment(msg.CommentID, msg.Voter, msg.Direction)
+		context.Respond(&struct {
+			Result *VoteResult
+			Err    error
+		}{result, err})
 
 	case *GetFeedMessage:
-		feed, err := state.engine.GetUserFeed(msg.Username)
+		feed, err := state.engine.GetUserFeed(msg.Username, listingSort(msg.Sort), listingTime(msg.Time))
 		context.Respond(&struct {
 			Feed []*Post
 			Err  error
 		}{feed, err})
 
+	case *GetSubredditPostsMessage:
+		posts, err := state.engine.GetSubredditPosts(msg.Subreddit, msg.Viewer, listingSort(msg.Sort), listingTime(msg.Time))
+		context.Respond(&struct {
+			Posts []*Post
+			Err   error
+		}{posts, err})
+
+	case *GetDomainPostsMessage:
+		posts, err := state.engine.GetDomainPosts(msg.Domain, msg.Viewer, listingSort(msg.Sort), listingTime(msg.Time))
+		context.Respond(&struct {
+			Posts []*Post
+			Err   error
+		}{posts, err})
+
 	case *SendDMMessage:
 		dm, err := state.engine.SendDirectMessage(msg.From, msg.To, msg.Content)
 		context.Respond(&struct {
@@ -122,26 +447,81 @@ func (state *RedditEngineActor) Receive(context actor.Context) {
 			Err   error
 		}{reply, err})
 
+	case *BlockUserMessage:
+		var err error
+		if msg.Block {
+			err = state.engine.BlockUser(msg.Username, msg.Target)
+		} else {
+			err = state.engine.UnblockUser(msg.Username, msg.Target)
+		}
+		context.Respond(err)
+
+	case *GetBlockedUsersMessage:
+		blocked, err := state.engine.GetBlockedUsers(msg.Username)
+		context.Respond(&struct {
+			Blocked []string
+			Err     error
+		}{blocked, err})
+
+	case *SaveItemMessage:
+		var err error
+		switch {
+		case msg.CommentID != "" && msg.Save:
+			err = state.engine.SaveComment(msg.Username, msg.CommentID)
+		case msg.CommentID != "":
+			err = state.engine.UnsaveComment(msg.Username, msg.CommentID)
+		case msg.Save:
+			err = state.engine.SavePost(msg.Username, msg.PostID)
+		default:
+			err = state.engine.UnsavePost(msg.Username, msg.PostID)
+		}
+		context.Respond(err)
+
+	case *HidePostMessage:
+		var err error
+		if msg.Hide {
+			err = state.engine.HidePost(msg.Username, msg.PostID)
+		} else {
+			err = state.engine.UnhidePost(msg.Username, msg.PostID)
+		}
+		context.Respond(err)
+
+	case *GetSavedItemsMessage:
+		items, err := state.engine.GetSavedItems(msg.Username, msg.Subreddit)
+		context.Respond(&struct {
+			Items []*SavedItem
+			Err   error
+		}{items, err})
+
+	case *GetHiddenPostsMessage:
+		posts, err := state.engine.GetHiddenPosts(msg.Username)
+		context.Respond(&struct {
+			Posts []*Post
+			Err   error
+		}{posts, err})
+
 	case *GetStatsMessage:
 		totalComments := 0
 		totalUpvotes := 0
 		totalDownvotes := 0
 		for _, post := range state.engine.posts {
 			totalComments += countComments(post.Comments)
-			if post.Votes > 0 {
-				totalUpvotes += post.Votes
-			} else {
-				totalDownvotes += -post.Votes
-			}
+			totalUpvotes += post.Upvotes
+			totalDownvotes += post.Downvotes
 		}
 
 		// Get top 10 users by karma
 		topUsers := make([]UserKarma, 0)
-		for username, user := range state.engine.users {
+		users := state.engine.ListUsers()
+		for _, user := range users {
+			user.mu.RLock()
 			topUsers = append(topUsers, UserKarma{
-				Username: username,
-				Karma:    user.Karma,
+				Username:     user.Username,
+				Karma:        user.TotalKarma(),
+				PostKarma:    user.PostKarma,
+				CommentKarma: user.CommentKarma,
 			})
+			user.mu.RUnlock()
 		}
 		// Sort users by karma
 		sort.Slice(topUsers, func(i, j int) bool {
@@ -158,7 +538,7 @@ func (state *RedditEngineActor) Receive(context actor.Context) {
 		}
 
 		context.Respond(&StatsResponse{
-			Users:          len(state.engine.users),
+			Users:          len(users),
 			Subreddits:     len(state.engine.subreddits),
 			Posts:          len(state.engine.posts),
 			Comments:       totalComments,
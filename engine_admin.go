@@ -0,0 +1,276 @@
+package main
+
+import (
+	"time"
+)
+
+// SetAdmin grants or revokes the site admin role. Admins may suspend users
+// and ban, delete or quarantine subreddits. The role is only granted to
+// registered accounts, so nobody can claim it by registering the name later.
+func (e *RedditEngine) SetAdmin(username string, admin bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !admin {
+		delete(e.admins, username)
+		return nil
+	}
+	if _, err := e.lookupUser(username); err != nil {
+		return err
+	}
+	e.admins[username] = true
+	return nil
+}
+
+// IsAdmin reports whether username is a site admin
+func (e *RedditEngine) IsAdmin(username string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.admins[username]
+}
+
+// checkAdmin returns a forbidden error unless actor is a site admin. Callers
+// must hold e.mu.
+func (e *RedditEngine) checkAdmin(actor string) error {
+	if !e.admins[actor] {
+		return newError(ErrForbidden, "%s is not an admin", actor)
+	}
+	return nil
+}
+
+// activeSuspension returns the suspension of the user while it is in force.
+// Callers must hold u.mu.
+func (u *User) activeSuspension() *Restriction {
+	if u.Suspension == nil || !u.Suspension.Active(time.Now()) {
+		return nil
+	}
+	return u.Suspension
+}
+
+// checkNotSuspended returns a forbidden error while username is suspended.
+// Every engine method that changes state calls it first. Unknown users pass
+// and are left to the caller's own lookup. Callers must not hold e.mu.
+func (e *RedditEngine) checkNotSuspended(username string) error {
+	e.mu.RLock()
+	user, ok := e.users[username]
+	e.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	user.mu.RLock()
+	defer user.mu.RUnlock()
+
+	if suspension := user.activeSuspension(); suspension != nil {
+		return newError(ErrForbidden, "%s is suspended%s", username, untilSuffix(suspension))
+	}
+	return nil
+}
+
+// SuspendUser suspends username site-wide for duration, or permanently when
+// duration is zero. Suspending someone already suspended replaces the
+// suspension. Admins cannot be suspended.
+func (e *RedditEngine) SuspendUser(actor, username, reason string, duration time.Duration) error {
+	if err := requireText("reason", reason); err != nil {
+		return err
+	}
+	if duration < 0 {
+		return &ValidationError{Field: "duration", Reason: "cannot be negative"}
+	}
+
+	e.mu.RLock()
+	err := e.checkAdmin(actor)
+	var user *User
+	if err == nil {
+		user, err = e.lookupUser(username)
+	}
+	if err == nil && e.admins[username] {
+		err = newError(ErrForbidden, "%s is an admin and cannot be suspended", username)
+	}
+	e.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	suspension := &Restriction{
+		Username: username,
+		Reason:   reason,
+		IssuedBy: actor,
+		IssuedAt: now,
+	}
+	if duration > 0 {
+		suspension.ExpiresAt = now.Add(duration)
+	}
+
+	user.mu.Lock()
+	user.Suspension = suspension
+	user.mu.Unlock()
+	return nil
+}
+
+// UnsuspendUser lifts the suspension of username
+func (e *RedditEngine) UnsuspendUser(actor, username string) error {
+	e.mu.RLock()
+	err := e.checkAdmin(actor)
+	var user *User
+	if err == nil {
+		user, err = e.lookupUser(username)
+	}
+	e.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	user.mu.Lock()
+	defer user.mu.Unlock()
+
+	if user.activeSuspension() == nil {
+		return &NotFoundError{Kind: "suspension", ID: username}
+	}
+	user.Suspension = nil
+	return nil
+}
+
+// lookupAdminTarget checks that actor is an admin and resolves the subreddit
+// an admin action targets
+func (e *RedditEngine) lookupAdminTarget(actor, subredditName string) (*Subreddit, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if err := e.checkAdmin(actor); err != nil {
+		return nil, err
+	}
+	return e.lookupSubreddit(subredditName)
+}
+
+// BanSubreddit shuts a subreddit down: it disappears from listings and
+// nobody can read or post in it until it is unbanned
+func (e *RedditEngine) BanSubreddit(actor, subredditName, reason string) error {
+	if err := requireText("reason", reason); err != nil {
+		return err
+	}
+
+	subreddit, err := e.lookupAdminTarget(actor, subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if subreddit.Banned {
+		return newError(ErrConflict, "%s is already banned", subredditName)
+	}
+	subreddit.Banned = true
+	subreddit.BanReason = reason
+	return nil
+}
+
+// UnbanSubreddit reopens a banned subreddit
+func (e *RedditEngine) UnbanSubreddit(actor, subredditName string) error {
+	subreddit, err := e.lookupAdminTarget(actor, subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if !subreddit.Banned {
+		return newError(ErrConflict, "%s is not banned", subredditName)
+	}
+	subreddit.Banned = false
+	subreddit.BanReason = ""
+	return nil
+}
+
+// QuarantineSubreddit hides a subreddit from listings and makes users opt in
+// before they can see it, or lifts the quarantine when quarantine is false
+func (e *RedditEngine) QuarantineSubreddit(actor, subredditName string, quarantine bool) error {
+	subreddit, err := e.lookupAdminTarget(actor, subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if subreddit.Quarantined == quarantine {
+		return nil
+	}
+	subreddit.Quarantined = quarantine
+	subreddit.QuarantineOptIns = make(map[string]bool)
+	return nil
+}
+
+// OptInToQuarantine lets username see a quarantined subreddit
+func (e *RedditEngine) OptInToQuarantine(username, subredditName string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+
+	subreddit, err := e.lookupModTarget(subredditName, username)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if !subreddit.Quarantined {
+		return newError(ErrConflict, "%s is not quarantined", subredditName)
+	}
+	subreddit.QuarantineOptIns[username] = true
+	return nil
+}
+
+// DeleteSubreddit removes a subreddit with all of its posts and comments and
+// every membership in it
+func (e *RedditEngine) DeleteSubreddit(actor, subredditName string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.checkAdmin(actor); err != nil {
+		return err
+	}
+	subreddit, err := e.lookupSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+
+	var forget func(comments []*Comment)
+	forget = func(comments []*Comment) {
+		for _, comment := range comments {
+			delete(e.comments, comment.ID)
+			comment.mu.RLock()
+			children := comment.Children
+			comment.mu.RUnlock()
+			forget(children)
+		}
+	}
+
+	subreddit.mu.Lock()
+	for _, post := range subreddit.Posts {
+		delete(e.posts, post.ID)
+		post.mu.RLock()
+		comments := post.Comments
+		original, crossposts := post.CrosspostOf, post.Crossposts
+		post.mu.RUnlock()
+		forget(comments)
+		unlinkCrossposts(post, original, crossposts)
+	}
+	subreddit.mu.Unlock()
+
+	for _, user := range e.users {
+		user.mu.Lock()
+		delete(user.Subreddits, subredditName)
+		user.mu.Unlock()
+	}
+
+	delete(e.subreddits, subredditName)
+	return nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newAdminTestEngine returns an engine where "admin" is a site admin
+func newAdminTestEngine(t *testing.T, usernames ...string) *RedditEngine {
+	t.Helper()
+	e := newTestEngine(t, append([]string{"admin"}, usernames...)...)
+	if err := e.SetAdmin("admin", true); err != nil {
+		t.Fatalf("SetAdmin: %v", err)
+	}
+	return e
+}
+
+func TestSetAdmin(t *testing.T) {
+	e := newTestEngine(t, "alice")
+
+	if err := e.SetAdmin("ghost", true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetAdmin(unknown user) error = %v, want ErrNotFound", err)
+	}
+	if e.IsAdmin("ghost") {
+		t.Error("an unregistered name became an admin")
+	}
+
+	if err := e.SetAdmin("alice", true); err != nil {
+		t.Fatalf("SetAdmin: %v", err)
+	}
+	if !e.IsAdmin("alice") {
+		t.Error("IsAdmin(alice) = false after granting the role")
+	}
+	if err := e.SetAdmin("alice", false); err != nil {
+		t.Fatalf("SetAdmin(revoke): %v", err)
+	}
+	if e.IsAdmin("alice") {
+		t.Error("IsAdmin(alice) = true after revoking the role")
+	}
+}
+
+func TestSuspendUser(t *testing.T) {
+	e := newAdminTestEngine(t, "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "bob", SubredditPublic)
+	post := mustCreatePost(t, e, "bob", "golang", "Go 1.23")
+
+	tests := []struct {
+		name     string
+		actor    string
+		username string
+		reason   string
+		duration time.Duration
+		wantErr  error
+	}{
+		{"by a regular user", "bob", "alice", "spam", 0, ErrForbidden},
+		{"an admin", "admin", "admin", "oops", 0, ErrForbidden},
+		{"an unknown user", "admin", "ghost", "spam", 0, ErrNotFound},
+		{"without a reason", "admin", "alice", "", 0, ErrValidation},
+		{"negative duration", "admin", "alice", "spam", -time.Hour, ErrValidation},
+	}
+	for _, tt := range tests {
+		if err := e.SuspendUser(tt.actor, tt.username, tt.reason, tt.duration); !errors.Is(err, tt.wantErr) {
+			t.Errorf("SuspendUser %s error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	mutations := map[string]func() error{
+		"create subreddit": func() error { return e.CreateSubreddit("rust", "", "alice", SubredditPublic) },
+		"join":             func() error { return e.JoinSubreddit("alice", "golang") },
+		"post": func() error {
+			_, err := e.CreatePost("title", "content", "alice", "golang")
+			return err
+		},
+		"comment": func() error {
+			_, err := e.AddComment("hi", "alice", post.ID, "")
+			return err
+		},
+		"vote": func() error {
+			_, err := e.VotePost(post.ID, "alice", VoteUp)
+			return err
+		},
+		"message": func() error {
+			_, err := e.SendDirectMessage("alice", "bob", "hello")
+			return err
+		},
+		"report": func() error { return e.ReportPost(post.ID, "alice", "spam") },
+	}
+
+	if err := e.SuspendUser("admin", "alice", "spam", time.Hour); err != nil {
+		t.Fatalf("SuspendUser: %v", err)
+	}
+	for name, mutate := range mutations {
+		if err := mutate(); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s while suspended error = %v, want ErrForbidden", name, err)
+		}
+	}
+
+	// Let the suspension run out
+	e.users["alice"].Suspension.ExpiresAt = time.Now().Add(-time.Second)
+	for name, mutate := range mutations {
+		if err := mutate(); err != nil {
+			t.Errorf("%s after the suspension expired error = %v, want nil", name, err)
+		}
+	}
+	if err := e.UnsuspendUser("admin", "alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UnsuspendUser after expiry error = %v, want ErrNotFound", err)
+	}
+
+	if err := e.SuspendUser("admin", "alice", "spam again", 0); err != nil {
+		t.Fatalf("SuspendUser(permanent): %v", err)
+	}
+	if err := e.UnsuspendUser("bob", "alice"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("UnsuspendUser by a regular user error = %v, want ErrForbidden", err)
+	}
+	if err := e.UnsuspendUser("admin", "alice"); err != nil {
+		t.Fatalf("UnsuspendUser: %v", err)
+	}
+	if err := e.LeaveSubreddit("alice", "golang"); err != nil {
+		t.Errorf("LeaveSubreddit after unsuspension: %v", err)
+	}
+}
+
+func TestBanSubreddit(t *testing.T) {
+	e := newAdminTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+
+	if err := e.BanSubreddit("owner", "golang", "abuse"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("BanSubreddit by a moderator error = %v, want ErrForbidden", err)
+	}
+	if err := e.BanSubreddit("admin", "golang", " "); !errors.Is(err, ErrValidation) {
+		t.Errorf("BanSubreddit without a reason error = %v, want ErrValidation", err)
+	}
+	if err := e.BanSubreddit("admin", "golang", "abuse"); err != nil {
+		t.Fatalf("BanSubreddit: %v", err)
+	}
+	if err := e.BanSubreddit("admin", "golang", "abuse"); !errors.Is(err, ErrConflict) {
+		t.Errorf("repeat BanSubreddit error = %v, want ErrConflict", err)
+	}
+
+	// Nobody, not even its moderators, sees a banned subreddit
+	for _, viewer := range []string{"owner", "alice"} {
+		if err := e.CanViewSubreddit("golang", viewer); !errors.Is(err, ErrForbidden) {
+			t.Errorf("CanViewSubreddit(%q) error = %v, want ErrForbidden", viewer, err)
+		}
+	}
+	if listed := e.ListSubreddits("", SubredditSortMembers, "owner"); len(listed) != 0 {
+		t.Errorf("ListSubreddits lists %d subreddits, want the banned one left out", len(listed))
+	}
+	if _, err := e.AddComment("hi", "alice", post.ID, ""); !errors.Is(err, ErrForbidden) {
+		t.Errorf("AddComment in a banned subreddit error = %v, want ErrForbidden", err)
+	}
+
+	if err := e.UnbanSubreddit("admin", "golang"); err != nil {
+		t.Fatalf("UnbanSubreddit: %v", err)
+	}
+	if err := e.UnbanSubreddit("admin", "golang"); !errors.Is(err, ErrConflict) {
+		t.Errorf("repeat UnbanSubreddit error = %v, want ErrConflict", err)
+	}
+	if err := e.CanViewSubreddit("golang", "alice"); err != nil {
+		t.Errorf("CanViewSubreddit after unbanning: %v", err)
+	}
+}
+
+func TestQuarantineSubreddit(t *testing.T) {
+	e := newAdminTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "edgy", "owner", SubredditPublic)
+
+	if err := e.OptInToQuarantine("alice", "edgy"); !errors.Is(err, ErrConflict) {
+		t.Errorf("OptInToQuarantine before the quarantine error = %v, want ErrConflict", err)
+	}
+	if err := e.QuarantineSubreddit("owner", "edgy", true); !errors.Is(err, ErrForbidden) {
+		t.Errorf("QuarantineSubreddit by a moderator error = %v, want ErrForbidden", err)
+	}
+	if err := e.QuarantineSubreddit("admin", "edgy", true); err != nil {
+		t.Fatalf("QuarantineSubreddit: %v", err)
+	}
+
+	if err := e.CanViewSubreddit("edgy", "alice"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("CanViewSubreddit before opting in error = %v, want ErrForbidden", err)
+	}
+	if err := e.CanViewSubreddit("edgy", "owner"); err != nil {
+		t.Errorf("CanViewSubreddit(moderator): %v", err)
+	}
+	if err := e.OptInToQuarantine("alice", "edgy"); err != nil {
+		t.Fatalf("OptInToQuarantine: %v", err)
+	}
+	if err := e.CanViewSubreddit("edgy", "alice"); err != nil {
+		t.Errorf("CanViewSubreddit after opting in: %v", err)
+	}
+	if listed := e.ListSubreddits("", SubredditSortMembers, "alice"); len(listed) != 0 {
+		t.Errorf("ListSubreddits lists %d subreddits, want the quarantined one left out", len(listed))
+	}
+
+	// Lifting the quarantine forgets the opt-ins
+	if err := e.QuarantineSubreddit("admin", "edgy", false); err != nil {
+		t.Fatalf("QuarantineSubreddit(lift): %v", err)
+	}
+	if err := e.QuarantineSubreddit("admin", "edgy", true); err != nil {
+		t.Fatalf("QuarantineSubreddit(again): %v", err)
+	}
+	if err := e.CanViewSubreddit("edgy", "alice"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("CanViewSubreddit after a new quarantine error = %v, want ErrForbidden", err)
+	}
+}
+
+func TestDeleteSubreddit(t *testing.T) {
+	e := newAdminTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+	comment, err := e.AddComment("hi", "alice", post.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	if err := e.JoinSubreddit("alice", "golang"); err != nil {
+		t.Fatalf("JoinSubreddit: %v", err)
+	}
+
+	if err := e.DeleteSubreddit("owner", "golang"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("DeleteSubreddit by a moderator error = %v, want ErrForbidden", err)
+	}
+	if err := e.DeleteSubreddit("admin", "golang"); err != nil {
+		t.Fatalf("DeleteSubreddit: %v", err)
+	}
+
+	if _, err := e.GetSubreddit("golang"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSubreddit after deletion error = %v, want ErrNotFound", err)
+	}
+	if _, err := e.GetPost(post.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetPost after deletion error = %v, want ErrNotFound", err)
+	}
+	if _, err := e.GetComment(comment.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetComment after deletion error = %v, want ErrNotFound", err)
+	}
+	if e.users["alice"].Subreddits["golang"] {
+		t.Error("alice is still a member of the deleted subreddit")
+	}
+	if err := e.DeleteSubreddit("admin", "golang"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("repeat DeleteSubreddit error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Password hashing parameters (PBKDF2-HMAC-SHA256)
+const (
+	passwordSaltSize       = 16
+	passwordHashIterations = 100000
+	passwordHashSize       = sha256.Size
+)
+
+// sessionTTL is how long a login token stays valid
+const sessionTTL = 24 * time.Hour
+
+// Session is a bearer token issued by Login
+type Session struct {
+	Token     string
+	Username  string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// hashPassword derives a key from password and salt with PBKDF2-HMAC-SHA256
+func hashPassword(password string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(password), salt, passwordHashIterations, passwordHashSize, sha256.New)
+}
+
+func newPasswordSalt() ([]byte, error) {
+	salt := make([]byte, passwordSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+func newSessionToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// Login checks a username and password and opens a new session
+func (e *RedditEngine) Login(username, password string) (*Session, error) {
+	e.mu.RLock()
+	user, ok := e.users[username]
+	e.mu.RUnlock()
+
+	if !ok {
+		// Hash anyway so unknown usernames take as long as wrong passwords
+		hashPassword(password, make([]byte, passwordSaltSize))
+		return nil, newError(ErrUnauthorized, "invalid username or password")
+	}
+
+	user.mu.RLock()
+	salt, expected := user.PasswordSalt, user.PasswordHash
+	user.mu.RUnlock()
+
+	if subtle.ConstantTimeCompare(hashPassword(password, salt), expected) != 1 {
+		return nil, newError(ErrUnauthorized, "invalid username or password")
+	}
+
+	token, err := newSessionToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &Session{
+		Token:     token,
+		Username:  username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(sessionTTL),
+	}
+
+	e.mu.Lock()
+	e.sessions[token] = session
+	e.mu.Unlock()
+
+	return session, nil
+}
+
+// Logout ends the session identified by token
+func (e *RedditEngine) Logout(token string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.sessions[token]; !ok {
+		return newError(ErrUnauthorized, "session not found")
+	}
+	delete(e.sessions, token)
+	return nil
+}
+
+// Authenticate returns the username owning a valid session token. Expired
+// sessions are removed.
+func (e *RedditEngine) Authenticate(token string) (string, error) {
+	e.mu.RLock()
+	session, ok := e.sessions[token]
+	e.mu.RUnlock()
+
+	if !ok {
+		return "", newError(ErrUnauthorized, "invalid session token")
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		e.mu.Lock()
+		delete(e.sessions, token)
+		e.mu.Unlock()
+		return "", newError(ErrUnauthorized, "session expired")
+	}
+
+	return session.Username, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHashPassword(t *testing.T) {
+	// PBKDF2-HMAC-SHA256 known answers, computed independently with Python's
+	// hashlib.pbkdf2_hmac("sha256", password, salt, 100000). Stored hashes stop
+	// verifying if any of these change.
+	tests := []struct {
+		password string
+		salt     string
+		want     string
+	}{
+		{"password", "salt", "0394a2ede332c9a13eb82e9b24631604c31df978b4e2f0fbd2c549944f9d79a5"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", "af70dc8ce4ccc6d39e35080f4af755133b266f3a8da78983844e1caf1fb9f76d"},
+		{"pass\x00word", "sa\x00lt", "687aadf77ba227691902e6100e4646d03f1d18c18bbfc493d3c4191145932b5e"},
+	}
+
+	for _, tt := range tests {
+		got := hex.EncodeToString(hashPassword(tt.password, []byte(tt.salt)))
+		if got != tt.want {
+			t.Errorf("hashPassword(%q, %q) = %s, want %s", tt.password, tt.salt, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterUser(t *testing.T) {
+	e := newTestEngine(t, "alice")
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     error
+	}{
+		{"new user", "bob", "secret", nil},
+		{"taken username", "alice", "secret", ErrAlreadyExists},
+		{"AutoModerator is taken", autoModerator, "secret", ErrAlreadyExists},
+		{"blank username", "  ", "secret", ErrValidation},
+		{"empty password", "carol", "", ErrValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := e.RegisterUser(tt.username, tt.password); !errors.Is(err, tt.want) {
+				t.Errorf("RegisterUser(%q) = %v, want %v", tt.username, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	e := newTestEngine(t, "alice")
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     error
+	}{
+		{"right password", "alice", testPassword, nil},
+		{"wrong password", "alice", "hunter3", ErrUnauthorized},
+		{"unknown user", "bob", testPassword, ErrUnauthorized},
+		{"AutoModerator cannot log in", autoModerator, "", ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := e.Login(tt.username, tt.password)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Login(%q) = %v, want %v", tt.username, err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			username, err := e.Authenticate(session.Token)
+			if err != nil || username != tt.username {
+				t.Errorf("Authenticate = %q, %v, want %q", username, err, tt.username)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(e *RedditEngine, session *Session)
+		want  error
+	}{
+		{"open session", func(*RedditEngine, *Session) {}, nil},
+		{"logged out", func(e *RedditEngine, session *Session) {
+			if err := e.Logout(session.Token); err != nil {
+				t.Fatalf("Logout: %v", err)
+			}
+		}, ErrUnauthorized},
+		{"expired", func(e *RedditEngine, session *Session) {
+			session.ExpiresAt = time.Now().Add(-time.Second)
+		}, ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, "alice")
+			session, err := e.Login("alice", testPassword)
+			if err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+			tt.setup(e, session)
+
+			if _, err := e.Authenticate(session.Token); !errors.Is(err, tt.want) {
+				t.Errorf("Authenticate = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	e := newTestEngine(t)
+	if _, err := e.Authenticate("no-such-token"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Authenticate(unknown token) = %v, want %v", err, ErrUnauthorized)
+	}
+	if err := e.Logout("no-such-token"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Logout(unknown token) = %v, want %v", err, ErrUnauthorized)
+	}
+}
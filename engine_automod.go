@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// autoModerator is the system account that carries out AutoModerator rules
+const autoModerator = "AutoModerator"
+
+// AutoModAction is what an AutoModerator rule does to the content it matches
+type AutoModAction string
+
+const (
+	AutoModRemove  AutoModAction = "remove"  // remove the item
+	AutoModFilter  AutoModAction = "filter"  // hide the item and hold it in the mod queue
+	AutoModFlair   AutoModAction = "flair"   // set the flair of a post
+	AutoModComment AutoModAction = "comment" // reply to the item as AutoModerator
+)
+
+// AutoModRule matches new posts and comments in a subreddit. Every condition
+// that is set must hold for the rule to trigger, and at least one must be set.
+type AutoModRule struct {
+	Name              string
+	Type              string // "post", "comment" or "" for both
+	TitleRegex        string // never matches comments, which have no title
+	BodyRegex         string
+	MinAccountAgeDays int  // triggers for accounts younger than this
+	MinKarma          *int // triggers for authors with less total karma
+	Domains           []string
+	Action            AutoModAction
+	Flair             string // flair set by the flair action
+	Comment           string // reply posted by the comment action
+
+	titleRe *regexp.Regexp
+	bodyRe  *regexp.Regexp
+}
+
+// compile validates the rule and prepares its regular expressions
+func (r *AutoModRule) compile() error {
+	if err := requireText("rule name", r.Name); err != nil {
+		return err
+	}
+	field := func(name string) string {
+		return fmt.Sprintf("rule %q %s", r.Name, name)
+	}
+
+	switch r.Type {
+	case "", "post", "comment":
+	default:
+		return &ValidationError{Field: field("type"), Reason: fmt.Sprintf("unknown type %q", r.Type)}
+	}
+
+	var err error
+	if r.TitleRegex != "" {
+		if r.Type == "comment" {
+			return &ValidationError{Field: field("title_regex"), Reason: "comments have no title"}
+		}
+		if r.titleRe, err = regexp.Compile(r.TitleRegex); err != nil {
+			return &ValidationError{Field: field("title_regex"), Reason: err.Error()}
+		}
+	}
+	if r.BodyRegex != "" {
+		if r.bodyRe, err = regexp.Compile(r.BodyRegex); err != nil {
+			return &ValidationError{Field: field("body_regex"), Reason: err.Error()}
+		}
+	}
+	if r.MinAccountAgeDays < 0 {
+		return &ValidationError{Field: field("min_account_age_days"), Reason: "must not be negative"}
+	}
+	for i, domain := range r.Domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" {
+			return &ValidationError{Field: field("domains"), Reason: "domain must not be empty"}
+		}
+		r.Domains[i] = domain
+	}
+	if r.titleRe == nil && r.bodyRe == nil && r.MinAccountAgeDays == 0 && r.MinKarma == nil && len(r.Domains) == 0 {
+		return &ValidationError{Field: field("conditions"), Reason: "rule needs at least one condition"}
+	}
+
+	switch r.Action {
+	case AutoModRemove, AutoModFilter:
+	case AutoModFlair:
+		if r.Type != "post" {
+			return &ValidationError{Field: field("action"), Reason: "flair rules must have type post"}
+		}
+		if err := requireText(field("flair"), r.Flair); err != nil {
+			return err
+		}
+	case AutoModComment:
+		if err := requireText(field("comment"), r.Comment); err != nil {
+			return err
+		}
+	default:
+		return &ValidationError{Field: field("action"), Reason: fmt.Sprintf("unknown action %q", r.Action)}
+	}
+	return nil
+}
+
+// AutoModSample is the content an AutoModerator rule is checked against
+type AutoModSample struct {
+	Kind    string // "post" or "comment"
+	Title   string
+	Content string
+	URL     string // link posts
+	Author  string
+}
+
+// urlPattern finds the links whose domains rules can match
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// linkedDomains returns the lower-cased hosts of every link in text
+func linkedDomains(text string) []string {
+	var domains []string
+	for _, link := range urlPattern.FindAllString(text, -1) {
+		if parsed, err := url.Parse(link); err == nil && parsed.Hostname() != "" {
+			domains = append(domains, strings.ToLower(parsed.Hostname()))
+		}
+	}
+	return domains
+}
+
+// matchesDomain reports whether host is domain or one of its subdomains
+func matchesDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// matches reports whether the rule triggers for sample written by author.
+// Callers must hold author.mu.
+func (r *AutoModRule) matches(sample AutoModSample, author *User, now time.Time) bool {
+	if r.Type != "" && r.Type != sample.Kind {
+		return false
+	}
+	if r.titleRe != nil && (sample.Kind != "post" || !r.titleRe.MatchString(sample.Title)) {
+		return false
+	}
+	if r.bodyRe != nil && !r.bodyRe.MatchString(sample.Content) {
+		return false
+	}
+	if r.MinAccountAgeDays > 0 && now.Sub(author.CreatedAt) >= time.Duration(r.MinAccountAgeDays)*24*time.Hour {
+		return false
+	}
+	if r.MinKarma != nil && author.TotalKarma() >= *r.MinKarma {
+		return false
+	}
+	if len(r.Domains) > 0 {
+		linked := false
+		for _, host := range linkedDomains(sample.Title + " " + sample.Content + " " + sample.URL) {
+			for _, domain := range r.Domains {
+				if matchesDomain(host, domain) {
+					linked = true
+				}
+			}
+		}
+		if !linked {
+			return false
+		}
+	}
+	return true
+}
+
+// triggeredRules returns the rules of a subreddit that sample triggers, in
+// rule order. Content by moderators is never matched. Callers must hold
+// subreddit.mu.
+func (s *Subreddit) triggeredRules(sample AutoModSample, author *User) []*AutoModRule {
+	if author.Username == autoModerator || s.hasModPermission(author.Username, 0) {
+		return nil
+	}
+
+	author.mu.RLock()
+	defer author.mu.RUnlock()
+
+	now := time.Now()
+	var triggered []*AutoModRule
+	for _, rule := range s.AutoModRules {
+		if rule.matches(sample, author, now) {
+			triggered = append(triggered, rule)
+		}
+	}
+	return triggered
+}
+
+// applyAutoModerator runs the rules of the post's subreddit against a new
+// post, or against comment when it is not nil. Callers must hold e.mu for
+// writing.
+func (e *RedditEngine) applyAutoModerator(author *User, post *Post, comment *Comment) {
+	subreddit, ok := e.subreddits[post.Subreddit]
+	if !ok {
+		return
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	sample := AutoModSample{Kind: "post", Title: post.Title, Content: post.Content, URL: post.URL, Author: author.Username}
+	target, status, itemMu := post.ID, &post.ModStatus, &post.mu
+	if comment != nil {
+		sample = AutoModSample{Kind: "comment", Content: comment.Content, Author: author.Username}
+		target, status, itemMu = comment.ID, &comment.ModStatus, &comment.mu
+	}
+
+	for _, rule := range subreddit.triggeredRules(sample, author) {
+		reason := fmt.Sprintf("rule %q", rule.Name)
+		switch rule.Action {
+		case AutoModRemove:
+			itemMu.Lock()
+			status.apply(ModRemove, autoModerator)
+			itemMu.Unlock()
+			subreddit.logModAction(autoModerator, LogRemove, target, reason)
+		case AutoModFilter:
+			itemMu.Lock()
+			filtered := !status.Removed
+			status.Filtered = filtered
+			itemMu.Unlock()
+			if filtered {
+				subreddit.logModAction(autoModerator, LogFilter, target, reason)
+			}
+		case AutoModFlair:
+			post.mu.Lock()
+			post.Flair = Flair{Text: rule.Flair}
+			post.mu.Unlock()
+		case AutoModComment:
+			// Replies to a comment go under it, replies to a post go on top
+			e.insertComment(post, comment, autoModerator, rule.Comment)
+		}
+	}
+}
+
+// SetAutoModRules replaces the AutoModerator rules of a subreddit. Needs the
+// config permission.
+func (e *RedditEngine) SetAutoModRules(subredditName, actor string, rules []*AutoModRule) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	for _, rule := range rules {
+		if err := rule.compile(); err != nil {
+			return err
+		}
+		if names[rule.Name] {
+			return &ValidationError{Field: "rule name", Reason: fmt.Sprintf("%q is used more than once", rule.Name)}
+		}
+		names[rule.Name] = true
+	}
+
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermConfig); err != nil {
+		return err
+	}
+	subreddit.AutoModRules = rules
+	subreddit.logModAction(actor, LogEditAutoMod, subredditName, fmt.Sprintf("%d rules", len(rules)))
+	return nil
+}
+
+// GetAutoModRules returns the AutoModerator rules of a subreddit. Needs the
+// config permission.
+func (e *RedditEngine) GetAutoModRules(subredditName, actor string) ([]*AutoModRule, error) {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	if err := subreddit.checkModPermission(actor, PermConfig); err != nil {
+		return nil, err
+	}
+	rules := make([]*AutoModRule, len(subreddit.AutoModRules))
+	copy(rules, subreddit.AutoModRules)
+	return rules, nil
+}
+
+// TestAutoModRules returns the rules sample would trigger without changing
+// anything. Without a sample author the sample is checked as if written by a
+// brand-new account with no karma. Authors AutoModerator never acts on, such
+// as moderators, are refused rather than reported as triggering nothing.
+// Needs the config permission.
+func (e *RedditEngine) TestAutoModRules(subredditName, actor string, sample AutoModSample) ([]*AutoModRule, error) {
+	switch sample.Kind {
+	case "":
+		sample.Kind = "post"
+	case "post", "comment":
+	default:
+		return nil, &ValidationError{Field: "kind", Reason: fmt.Sprintf("unknown kind %q", sample.Kind)}
+	}
+
+	e.mu.RLock()
+	author := &User{CreatedAt: time.Now()}
+	var err error
+	if sample.Author != "" {
+		author, err = e.lookupUser(sample.Author)
+	}
+	var subreddit *Subreddit
+	if err == nil {
+		subreddit, err = e.lookupSubreddit(subredditName)
+	}
+	e.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	if err := subreddit.checkModPermission(actor, PermConfig); err != nil {
+		return nil, err
+	}
+	if author.Username == autoModerator || subreddit.hasModPermission(author.Username, 0) {
+		return nil, &ValidationError{Field: "author", Reason: fmt.Sprintf("AutoModerator never acts on content by %s in %s", author.Username, subredditName)}
+	}
+	return subreddit.triggeredRules(sample, author), nil
+}
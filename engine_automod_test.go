@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAutoModRuleCompile(t *testing.T) {
+	karma := 5
+
+	tests := []struct {
+		name string
+		rule AutoModRule
+		want error
+	}{
+		{"body regex", AutoModRule{Name: "spam", BodyRegex: "(?i)buy now", Action: AutoModRemove}, nil},
+		{"karma", AutoModRule{Name: "karma", MinKarma: &karma, Action: AutoModFilter}, nil},
+		{"post flair", AutoModRule{Name: "flair", Type: "post", TitleRegex: "^Q:", Action: AutoModFlair, Flair: "Question"}, nil},
+		{"no name", AutoModRule{BodyRegex: "x", Action: AutoModRemove}, ErrValidation},
+		{"unknown type", AutoModRule{Name: "r", Type: "poll", BodyRegex: "x", Action: AutoModRemove}, ErrValidation},
+		{"comment title", AutoModRule{Name: "r", Type: "comment", TitleRegex: "x", Action: AutoModRemove}, ErrValidation},
+		{"bad regex", AutoModRule{Name: "r", BodyRegex: "(", Action: AutoModRemove}, ErrValidation},
+		{"negative age", AutoModRule{Name: "r", MinAccountAgeDays: -1, Action: AutoModRemove}, ErrValidation},
+		{"empty domain", AutoModRule{Name: "r", Domains: []string{" "}, Action: AutoModRemove}, ErrValidation},
+		{"no condition", AutoModRule{Name: "r", Action: AutoModRemove}, ErrValidation},
+		{"flair on comments", AutoModRule{Name: "r", BodyRegex: "x", Action: AutoModFlair, Flair: "f"}, ErrValidation},
+		{"flair without text", AutoModRule{Name: "r", Type: "post", BodyRegex: "x", Action: AutoModFlair}, ErrValidation},
+		{"comment without text", AutoModRule{Name: "r", BodyRegex: "x", Action: AutoModComment}, ErrValidation},
+		{"unknown action", AutoModRule{Name: "r", BodyRegex: "x", Action: "ban"}, ErrValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.compile(); !errors.Is(err, tt.want) {
+				t.Errorf("compile = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoModerator(t *testing.T) {
+	tests := []struct {
+		name         string
+		rule         AutoModRule
+		author       string
+		submission   PostSubmission
+		wantRemoved  bool
+		wantFiltered bool
+		wantFlair    string
+		wantComments int
+	}{
+		{
+			name:        "remove on body",
+			rule:        AutoModRule{Name: "spam", BodyRegex: "(?i)buy now", Action: AutoModRemove},
+			author:      "alice",
+			submission:  PostSubmission{Title: "Deal", Content: "BUY NOW"},
+			wantRemoved: true,
+		},
+		{
+			name:       "body does not match",
+			rule:       AutoModRule{Name: "spam", BodyRegex: "(?i)buy now", Action: AutoModRemove},
+			author:     "alice",
+			submission: PostSubmission{Title: "Deal", Content: "just looking"},
+		},
+		{
+			name:         "filter new accounts",
+			rule:         AutoModRule{Name: "new", MinAccountAgeDays: 7, Action: AutoModFilter},
+			author:       "alice",
+			submission:   PostSubmission{Title: "Hello"},
+			wantFiltered: true,
+		},
+		{
+			name:       "moderators are exempt",
+			rule:       AutoModRule{Name: "new", MinAccountAgeDays: 7, Action: AutoModFilter},
+			author:     "owner",
+			submission: PostSubmission{Title: "Hello"},
+		},
+		{
+			name:        "linked subdomain",
+			rule:        AutoModRule{Name: "links", Domains: []string{"spam.example"}, Action: AutoModRemove},
+			author:      "alice",
+			submission:  PostSubmission{Kind: PostLink, Title: "Look", URL: "https://www.spam.example/offer"},
+			wantRemoved: true,
+		},
+		{
+			name:       "other domain",
+			rule:       AutoModRule{Name: "links", Domains: []string{"spam.example"}, Action: AutoModRemove},
+			author:     "alice",
+			submission: PostSubmission{Kind: PostLink, Title: "Look", URL: "https://notspam.example/offer"},
+		},
+		{
+			name:       "flair on title",
+			rule:       AutoModRule{Name: "questions", Type: "post", TitleRegex: `\?$`, Action: AutoModFlair, Flair: "Question"},
+			author:     "alice",
+			submission: PostSubmission{Title: "Is Go fast?"},
+			wantFlair:  "Question",
+		},
+		{
+			name:         "reply",
+			rule:         AutoModRule{Name: "welcome", MinAccountAgeDays: 1, Action: AutoModComment, Comment: "Welcome!"},
+			author:       "alice",
+			submission:   PostSubmission{Title: "First post"},
+			wantComments: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, "owner", "alice")
+			mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+			rule := tt.rule
+			if err := e.SetAutoModRules("golang", "owner", []*AutoModRule{&rule}); err != nil {
+				t.Fatalf("SetAutoModRules: %v", err)
+			}
+
+			post, err := e.SubmitPost(tt.author, "golang", tt.submission)
+			if err != nil {
+				t.Fatalf("SubmitPost: %v", err)
+			}
+			if post.Removed != tt.wantRemoved || post.Filtered != tt.wantFiltered {
+				t.Errorf("removed, filtered = %v, %v, want %v, %v", post.Removed, post.Filtered, tt.wantRemoved, tt.wantFiltered)
+			}
+			if post.Flair.Text != tt.wantFlair {
+				t.Errorf("flair = %q, want %q", post.Flair.Text, tt.wantFlair)
+			}
+			if len(post.Comments) != tt.wantComments {
+				t.Errorf("%d comments, want %d", len(post.Comments), tt.wantComments)
+			}
+		})
+	}
+}
+
+// backdateAccount makes username's account as old as age
+func backdateAccount(e *RedditEngine, username string, age time.Duration) {
+	e.users[username].CreatedAt = time.Now().Add(-age)
+}
+
+func TestTestAutoModRules(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	backdateAccount(e, "bob", 30*24*time.Hour)
+
+	newAccounts := &AutoModRule{Name: "new", MinAccountAgeDays: 7, Action: AutoModFilter}
+	spam := &AutoModRule{Name: "spam", BodyRegex: "(?i)buy now", Action: AutoModRemove}
+	if err := e.SetAutoModRules("golang", "owner", []*AutoModRule{newAccounts, spam}); err != nil {
+		t.Fatalf("SetAutoModRules: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		actor   string
+		sample  AutoModSample
+		want    []*AutoModRule
+		wantErr error
+	}{
+		{"default author is new", "owner", AutoModSample{Title: "Hi"}, []*AutoModRule{newAccounts}, nil},
+		{"default author and spam", "owner", AutoModSample{Content: "buy now"}, []*AutoModRule{newAccounts, spam}, nil},
+		{"new author", "owner", AutoModSample{Title: "Hi", Author: "alice"}, []*AutoModRule{newAccounts}, nil},
+		{"old author", "owner", AutoModSample{Title: "Hi", Author: "bob"}, nil, nil},
+		{"moderator author", "owner", AutoModSample{Content: "buy now", Author: "owner"}, nil, ErrValidation},
+		{"AutoModerator author", "owner", AutoModSample{Content: "buy now", Author: autoModerator}, nil, ErrValidation},
+		{"unknown author", "owner", AutoModSample{Author: "nobody"}, nil, ErrNotFound},
+		{"unknown kind", "owner", AutoModSample{Kind: "poll"}, nil, ErrValidation},
+		{"not a moderator", "alice", AutoModSample{Title: "Hi"}, nil, ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.TestAutoModRules("golang", tt.actor, tt.sample)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TestAutoModRules = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("triggered %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoModeratorIsNotListed(t *testing.T) {
+	e := newTestEngine(t, "alice")
+	for _, user := range e.ListUsers() {
+		if user.Username == autoModerator {
+			t.Errorf("ListUsers includes %s", autoModerator)
+		}
+	}
+	if users := e.ListUsers(); len(users) != 1 {
+		t.Errorf("ListUsers returned %d users, want 1", len(users))
+	}
+}
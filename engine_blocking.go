@@ -0,0 +1,229 @@
+package main
+
+import (
+	"sort"
+)
+
+// BlockUser stops target from messaging username or replying to their posts
+// and comments, and hides target's posts and comments from username
+func (e *RedditEngine) BlockUser(username, target string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+	if username == target {
+		return &ValidationError{Field: "username", Reason: "cannot block yourself"}
+	}
+
+	e.mu.RLock()
+	user, err := e.lookupUser(username)
+	if err == nil {
+		_, err = e.lookupUser(target)
+	}
+	e.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	user.mu.Lock()
+	defer user.mu.Unlock()
+
+	if user.Blocked[target] {
+		return newError(ErrAlreadyExists, "%s has already blocked %s", username, target)
+	}
+	user.Blocked[target] = true
+	return nil
+}
+
+// UnblockUser lifts a block
+func (e *RedditEngine) UnblockUser(username, target string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+
+	e.mu.RLock()
+	user, err := e.lookupUser(username)
+	e.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	user.mu.Lock()
+	defer user.mu.Unlock()
+
+	if !user.Blocked[target] {
+		return &NotFoundError{Kind: "block", ID: target}
+	}
+	delete(user.Blocked, target)
+	return nil
+}
+
+// GetBlockedUsers returns the users username has blocked in alphabetical
+// order
+func (e *RedditEngine) GetBlockedUsers(username string) ([]string, error) {
+	e.mu.RLock()
+	user, err := e.lookupUser(username)
+	e.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	user.mu.RLock()
+	blocked := make([]string, 0, len(user.Blocked))
+	for target := range user.Blocked {
+		blocked = append(blocked, target)
+	}
+	user.mu.RUnlock()
+
+	sort.Strings(blocked)
+	return blocked, nil
+}
+
+// hasBlocked reports whether user has blocked username
+func (u *User) hasBlocked(username string) bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.Blocked[username]
+}
+
+// checkNotBlocked returns a forbidden error when either user has blocked the
+// other. Callers must hold e.mu.
+func (e *RedditEngine) checkNotBlocked(from, to string) error {
+	sender, err := e.lookupUser(from)
+	if err != nil {
+		return err
+	}
+	recipient, err := e.lookupUser(to)
+	if err != nil {
+		return err
+	}
+
+	if recipient.hasBlocked(from) {
+		return newError(ErrForbidden, "%s has blocked %s", to, from)
+	}
+	if sender.hasBlocked(to) {
+		return newError(ErrForbidden, "%s has blocked %s; unblock them first", from, to)
+	}
+	return nil
+}
+
+// checkCanReply returns a forbidden error when author and the user they reply
+// to have blocked each other, so blocked users cannot reach the blocker
+// through replies either. The reply goes to parent when it is not nil and to
+// post otherwise; deleted items have no author to protect. Callers must hold
+// e.mu.
+func (e *RedditEngine) checkCanReply(author string, post *Post, parent *Comment) error {
+	var recipient string
+	var deleted bool
+	if parent != nil {
+		parent.mu.RLock()
+		recipient, deleted = parent.Author, parent.Deleted
+		parent.mu.RUnlock()
+	} else {
+		post.mu.RLock()
+		recipient, deleted = post.Author, post.Deleted
+		post.mu.RUnlock()
+	}
+
+	if _, ok := e.users[recipient]; deleted || !ok || recipient == author {
+		return nil
+	}
+	return e.checkNotBlocked(author, recipient)
+}
+
+// blockedMarker replaces the author and text of comments by blocked users
+const blockedMarker = "[blocked]"
+
+// blockedBy returns a copy of the users username has blocked; anonymous and
+// unknown users block nobody. Callers must hold e.mu.
+func (e *RedditEngine) blockedBy(username string) map[string]bool {
+	user, ok := e.users[username]
+	if !ok {
+		return nil
+	}
+
+	user.mu.RLock()
+	defer user.mu.RUnlock()
+
+	blocked := make(map[string]bool, len(user.Blocked))
+	for target := range user.Blocked {
+		blocked[target] = true
+	}
+	return blocked
+}
+
+// viewWithBlocks returns comment and its replies as seen by someone who
+// blocked the authors in blocked. Their comments become placeholders with
+// Blocked set, and placeholders without visible replies are left out of the
+// replies. Comments with a placeholder anywhere below them are copied, so
+// the shared thread never changes. visible is false when the comment itself
+// is a placeholder without visible replies.
+func viewWithBlocks(comment *Comment, blocked map[string]bool) (view *Comment, visible bool) {
+	if len(blocked) == 0 {
+		return comment, true
+	}
+
+	comment.mu.RLock()
+	defer comment.mu.RUnlock()
+
+	changed := blocked[comment.Author]
+	children := make([]*Comment, 0, len(comment.Children))
+	for _, child := range comment.Children {
+		childView, childVisible := viewWithBlocks(child, blocked)
+		if childView != child || !childVisible {
+			changed = true
+		}
+		if childVisible {
+			children = append(children, childView)
+		}
+	}
+	if !changed {
+		return comment, true
+	}
+
+	view = &Comment{
+		ID:        comment.ID,
+		Content:   comment.Content,
+		Author:    comment.Author,
+		PostID:    comment.PostID,
+		ParentID:  comment.ParentID,
+		CreatedAt: comment.CreatedAt,
+		EditedAt:  comment.EditedAt,
+		Deleted:   comment.Deleted,
+		Votes:     comment.Votes,
+		Upvotes:   comment.Upvotes,
+		Downvotes: comment.Downvotes,
+		Voters:    make(map[string]int, len(comment.Voters)),
+		Children:  children,
+		ModStatus: comment.ModStatus,
+	}
+	for voter, direction := range comment.Voters {
+		view.Voters[voter] = direction
+	}
+	view.Reports = append([]*Report(nil), comment.Reports...)
+	if blocked[comment.Author] {
+		view.Author = blockedMarker
+		view.Content = blockedMarker
+		view.Blocked = true
+	}
+	return view, !view.Blocked || len(children) > 0
+}
+
+// withoutAuthors drops the posts written by any of authors
+func withoutAuthors(posts []*Post, authors map[string]bool) []*Post {
+	if len(authors) == 0 {
+		return posts
+	}
+	kept := make([]*Post, 0, len(posts))
+	for _, post := range posts {
+		post.mu.RLock()
+		blocked := authors[post.Author]
+		post.mu.RUnlock()
+		if !blocked {
+			kept = append(kept, post)
+		}
+	}
+	return kept
+}
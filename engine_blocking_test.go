@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBlockUser(t *testing.T) {
+	e := newTestEngine(t, "alice", "bob")
+
+	tests := []struct {
+		name   string
+		target string
+		want   error
+	}{
+		{"block", "bob", nil},
+		{"block again", "bob", ErrAlreadyExists},
+		{"block yourself", "alice", ErrValidation},
+		{"unknown user", "nobody", ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := e.BlockUser("alice", tt.target); !errors.Is(err, tt.want) {
+				t.Errorf("BlockUser(%q) = %v, want %v", tt.target, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockedReplies(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob", "carol")
+	e.SetRateLimits(SimulationRateLimits)
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+
+	alicePost := mustCreatePost(t, e, "alice", "golang", "Alice's post")
+	bobPost := mustCreatePost(t, e, "bob", "golang", "Bob's post")
+	deletedPost := mustCreatePost(t, e, "alice", "golang", "Deleted")
+	mustComment := func(author, postID string) *Comment {
+		t.Helper()
+		comment, err := e.AddComment("comment by "+author, author, postID, "")
+		if err != nil {
+			t.Fatalf("AddComment: %v", err)
+		}
+		return comment
+	}
+	carolComment := mustComment("carol", alicePost.ID)
+	aliceComment := mustComment("alice", bobPost.ID)
+	bobComment := mustComment("bob", bobPost.ID)
+
+	if err := e.DeletePost(deletedPost.ID, "alice"); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+	if err := e.BlockUser("alice", "bob"); err != nil {
+		t.Fatalf("BlockUser: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		author string
+		postID string
+		parent *Comment
+		want   error
+	}{
+		{"blocked user replies to post", "bob", alicePost.ID, nil, ErrForbidden},
+		{"blocked user replies to comment", "bob", bobPost.ID, aliceComment, ErrForbidden},
+		{"blocker replies to post", "alice", bobPost.ID, nil, ErrForbidden},
+		{"blocker replies to comment", "alice", bobPost.ID, bobComment, ErrForbidden},
+		{"blocked user replies to someone else", "bob", alicePost.ID, carolComment, nil},
+		{"blocked user replies to themselves", "bob", bobPost.ID, bobComment, nil},
+		{"blocked user replies to deleted post", "bob", deletedPost.ID, nil, nil},
+		{"bystander", "carol", alicePost.ID, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parentID := ""
+			if tt.parent != nil {
+				parentID = tt.parent.ID
+			}
+			if _, err := e.AddComment("reply", tt.author, tt.postID, parentID); !errors.Is(err, tt.want) {
+				t.Errorf("AddComment = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockedMessages(t *testing.T) {
+	e := newTestEngine(t, "alice", "bob", "carol")
+	if err := e.BlockUser("alice", "bob"); err != nil {
+		t.Fatalf("BlockUser: %v", err)
+	}
+
+	tests := []struct {
+		from string
+		to   string
+		want error
+	}{
+		{"bob", "alice", ErrForbidden},
+		{"alice", "bob", ErrForbidden},
+		{"carol", "alice", nil},
+		{"bob", "carol", nil},
+		{"bob", "nobody", ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		if _, err := e.SendDirectMessage(tt.from, tt.to, "hi"); !errors.Is(err, tt.want) {
+			t.Errorf("SendDirectMessage(%s to %s) = %v, want %v", tt.from, tt.to, err, tt.want)
+		}
+	}
+}
+
+func TestBlockedAuthorsLeaveFeed(t *testing.T) {
+	e := newTestEngine(t, "alice", "bob", "carol")
+	mustCreateSubreddit(t, e, "golang", "carol", SubredditPublic)
+	if err := e.JoinSubreddit("alice", "golang"); err != nil {
+		t.Fatalf("JoinSubreddit: %v", err)
+	}
+	bobPost := mustCreatePost(t, e, "bob", "golang", "Bob's post")
+	carolPost := mustCreatePost(t, e, "carol", "golang", "Carol's post")
+
+	tests := []struct {
+		name   string
+		change func() error
+		want   []string
+	}{
+		{"nobody blocked", nil, []string{carolPost.ID, bobPost.ID}},
+		{"block", func() error { return e.BlockUser("alice", "bob") }, []string{carolPost.ID}},
+		{"unblock", func() error { return e.UnblockUser("alice", "bob") }, []string{carolPost.ID, bobPost.ID}},
+	}
+
+	// The cases run in order and each change stays in place for the next
+	for _, tt := range tests {
+		if tt.change != nil {
+			if err := tt.change(); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+		feed, err := e.GetUserFeed("alice", SortNew, "")
+		if err != nil {
+			t.Fatalf("GetUserFeed: %v", err)
+		}
+		if got := postIDs(feed); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: feed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBlockedComments(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob", "carol")
+	e.SetRateLimits(SimulationRateLimits)
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Thread")
+
+	reply := func(author string, parent *Comment) *Comment {
+		t.Helper()
+		parentID := ""
+		if parent != nil {
+			parentID = parent.ID
+		}
+		comment, err := e.AddComment("comment by "+author, author, post.ID, parentID)
+		if err != nil {
+			t.Fatalf("AddComment: %v", err)
+		}
+		return comment
+	}
+	bobWithReply := reply("bob", nil)
+	carolReply := reply("carol", bobWithReply)
+	reply("bob", nil)
+	carolWithReply := reply("carol", nil)
+	bobReply := reply("bob", carolWithReply)
+
+	if err := e.BlockUser("alice", "bob"); err != nil {
+		t.Fatalf("BlockUser: %v", err)
+	}
+
+	// describe renders a thread as "author(replies...)"
+	var describe func(comments []*Comment) string
+	describe = func(comments []*Comment) string {
+		out := ""
+		for _, comment := range comments {
+			out += comment.Author + "(" + describe(comment.Children) + ")"
+		}
+		return out
+	}
+
+	tests := []struct {
+		viewer string
+		want   string
+	}{
+		{"alice", "[blocked](carol())carol()"},
+		{"carol", "bob(carol())bob()carol(bob())"},
+		{"", "bob(carol())bob()carol(bob())"},
+	}
+
+	for _, tt := range tests {
+		comments, err := e.GetComments(post.ID, tt.viewer)
+		if err != nil {
+			t.Fatalf("GetComments: %v", err)
+		}
+		if got := describe(comments); got != tt.want {
+			t.Errorf("GetComments as %q = %s, want %s", tt.viewer, got, tt.want)
+		}
+	}
+
+	thread, err := e.GetCommentContext(carolReply.ID, 1, "alice")
+	if err != nil {
+		t.Fatalf("GetCommentContext: %v", err)
+	}
+	if len(thread) != 2 || !thread[0].Blocked || thread[0].Content != blockedMarker || thread[1].ID != carolReply.ID {
+		t.Errorf("GetCommentContext = %s, want a placeholder above carol's reply", describe(thread))
+	}
+
+	// The shared thread is untouched
+	if bobWithReply.Blocked || bobWithReply.Author != "bob" || len(carolWithReply.Children) != 1 || carolWithReply.Children[0] != bobReply {
+		t.Error("GetComments changed the stored comments")
+	}
+}
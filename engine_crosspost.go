@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// CrosspostPost shares a post into another subreddit as a new post with its
+// own votes and comments. An empty title reuses the original's. Crossposting
+// a crosspost shares its original, so every crosspost points at the post that
+// was first submitted. The crosspost obeys the posting rules of the target
+// subreddit like any other post.
+func (e *RedditEngine) CrosspostPost(author, postID, subredditName, title, flairTemplateID, flairText string) (*Post, error) {
+	if err := e.checkNotSuspended(author); err != nil {
+		return nil, err
+	}
+
+	original, err := e.GetPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	original.mu.RLock()
+	root := original.CrosspostOf
+	original.mu.RUnlock()
+	if root != nil {
+		original = root
+	}
+
+	e.mu.RLock()
+	err = e.checkCanCrosspost(original, author)
+	e.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	original.mu.RLock()
+	deleted, removed := original.Deleted, original.hidden()
+	if title == "" {
+		title = original.Title
+	}
+	original.mu.RUnlock()
+
+	switch {
+	case deleted:
+		return nil, newError(ErrConflict, "post has been deleted")
+	case removed:
+		return nil, newError(ErrConflict, "post has been removed")
+	case original.Subreddit == subredditName:
+		return nil, &ValidationError{Field: "subreddit", Reason: fmt.Sprintf("post is already in %s", subredditName)}
+	}
+
+	post := &Post{
+		ID:          fmt.Sprintf("post_%d", time.Now().UnixNano()),
+		Kind:        PostCrosspost,
+		Title:       title,
+		Author:      author,
+		Subreddit:   subredditName,
+		CreatedAt:   time.Now(),
+		CrosspostOf: original,
+		Voters:      make(map[string]int),
+		Comments:    make([]*Comment, 0),
+	}
+	if err := e.publishPost(post, flairTemplateID, flairText); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// checkCanCrosspost checks that username can see the original post and that
+// its subreddit is neither private nor quarantined, so crossposts never
+// carry content out of a subreddit closed to the public. Callers must hold
+// e.mu.
+func (e *RedditEngine) checkCanCrosspost(original *Post, username string) error {
+	subreddit, err := e.lookupSubreddit(original.Subreddit)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	if err := subreddit.checkCanView(username); err != nil {
+		return err
+	}
+	if subreddit.Type == SubredditPrivate {
+		return newError(ErrForbidden, "posts in private subreddit %s cannot be crossposted", subreddit.Name)
+	}
+	if subreddit.Quarantined {
+		return newError(ErrForbidden, "posts in quarantined subreddit %s cannot be crossposted", subreddit.Name)
+	}
+	return nil
+}
+
+// unlinkCrossposts forgets the crosspost links of a post that is going away:
+// its own crossposts lose their original, and it leaves the crossposts of
+// its original
+func unlinkCrossposts(post, original *Post, crossposts []*Post) {
+	for _, crosspost := range crossposts {
+		crosspost.mu.Lock()
+		crosspost.CrosspostOf = nil
+		crosspost.mu.Unlock()
+	}
+	if original == nil {
+		return
+	}
+
+	original.mu.Lock()
+	defer original.mu.Unlock()
+	for i, crosspost := range original.Crossposts {
+		if crosspost == post {
+			original.Crossposts = append(original.Crossposts[:i], original.Crossposts[i+1:]...)
+			break
+		}
+	}
+}
+
+// visibleCrossposts returns the crossposts of original that viewer can see,
+// leaving out deleted ones. Callers must hold e.mu.
+func (e *RedditEngine) visibleCrossposts(original *Post, viewer string) []*Post {
+	original.mu.RLock()
+	crossposts := append([]*Post(nil), original.Crossposts...)
+	original.mu.RUnlock()
+
+	visible := make([]*Post, 0, len(crossposts))
+	for _, post := range crossposts {
+		post.mu.RLock()
+		deleted, removed := post.Deleted, post.hidden()
+		post.mu.RUnlock()
+		if !deleted && e.canSeeItem(post, removed, viewer) {
+			visible = append(visible, post)
+		}
+	}
+	return visible
+}
+
+// CrosspostLinks returns what viewer may see of the crosspost links of a
+// post: the original when post is a crosspost, and the number of its own
+// crossposts viewer can see. The original is nil for posts that are not
+// crossposts and for crossposts whose original was deleted with its
+// subreddit. canSeeOriginal is false when the original was removed or viewer
+// cannot see it.
+func (e *RedditEngine) CrosspostLinks(post *Post, viewer string) (original *Post, canSeeOriginal bool, count int) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	post.mu.RLock()
+	original = post.CrosspostOf
+	post.mu.RUnlock()
+
+	if original != nil {
+		original.mu.RLock()
+		removed := original.hidden()
+		original.mu.RUnlock()
+		canSeeOriginal = e.canSeeItem(original, removed, viewer)
+	}
+	return original, canSeeOriginal, len(e.visibleCrossposts(post, viewer))
+}
+
+// GetCrossposts returns the crossposts of a post that viewer can see, newest
+// first
+func (e *RedditEngine) GetCrossposts(postID, viewer string) ([]*Post, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	original, err := e.lookupPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkCanViewPost(original, viewer); err != nil {
+		return nil, err
+	}
+
+	visible := e.visibleCrossposts(original, viewer)
+	sort.Slice(visible, func(i, j int) bool {
+		return visible[i].CreatedAt.After(visible[j].CreatedAt)
+	})
+	return visible, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCrosspostPost(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	if err := e.SetAdmin("owner", true); err != nil {
+		t.Fatalf("SetAdmin: %v", err)
+	}
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	mustCreateSubreddit(t, e, "rust", "owner", SubredditPublic)
+	mustCreateSubreddit(t, e, "secret", "owner", SubredditPrivate)
+	mustCreateSubreddit(t, e, "edgy", "owner", SubredditPublic)
+
+	public := mustCreatePost(t, e, "owner", "golang", "Public")
+	private := mustCreatePost(t, e, "owner", "secret", "Private")
+	quarantined := mustCreatePost(t, e, "owner", "edgy", "Quarantined")
+	removed := mustCreatePost(t, e, "owner", "golang", "Removed")
+	deleted := mustCreatePost(t, e, "owner", "golang", "Deleted")
+
+	// alice can see all of them, but only the public ones may travel
+	if err := e.ApproveUser("secret", "owner", "alice"); err != nil {
+		t.Fatalf("ApproveUser: %v", err)
+	}
+	if err := e.QuarantineSubreddit("owner", "edgy", true); err != nil {
+		t.Fatalf("QuarantineSubreddit: %v", err)
+	}
+	if err := e.OptInToQuarantine("alice", "edgy"); err != nil {
+		t.Fatalf("OptInToQuarantine: %v", err)
+	}
+	if err := e.ModeratePost(removed.ID, "owner", ModRemove, "spam"); err != nil {
+		t.Fatalf("ModeratePost: %v", err)
+	}
+	if err := e.DeletePost(deleted.ID, "owner"); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		postID    string
+		subreddit string
+		want      error
+	}{
+		{"public post", public.ID, "rust", nil},
+		{"same subreddit", public.ID, "golang", ErrValidation},
+		{"private subreddit", private.ID, "rust", ErrForbidden},
+		{"quarantined subreddit", quarantined.ID, "rust", ErrForbidden},
+		{"removed post", removed.ID, "rust", ErrConflict},
+		{"deleted post", deleted.ID, "rust", ErrConflict},
+		{"unknown post", "post_0", "rust", ErrNotFound},
+		{"unknown subreddit", public.ID, "python", ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := e.CrosspostPost("alice", tt.postID, tt.subreddit, "", "", "")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("CrosspostPost = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrosspostOfCrosspost(t *testing.T) {
+	e := newTestEngine(t, "owner")
+	for _, name := range []string{"golang", "rust", "python"} {
+		mustCreateSubreddit(t, e, name, "owner", SubredditPublic)
+	}
+	original := mustCreatePost(t, e, "owner", "golang", "Original")
+
+	first, err := e.CrosspostPost("owner", original.ID, "rust", "", "", "")
+	if err != nil {
+		t.Fatalf("CrosspostPost: %v", err)
+	}
+	second, err := e.CrosspostPost("owner", first.ID, "python", "Shared again", "", "")
+	if err != nil {
+		t.Fatalf("CrosspostPost: %v", err)
+	}
+
+	if second.CrosspostOf != original {
+		t.Errorf("crosspost of a crosspost points at %v, want the original", second.CrosspostOf)
+	}
+	if first.Title != original.Title || second.Title != "Shared again" {
+		t.Errorf("titles = %q, %q, want %q, %q", first.Title, second.Title, original.Title, "Shared again")
+	}
+	if _, err := e.CrosspostPost("owner", first.ID, "golang", "", "", ""); !errors.Is(err, ErrValidation) {
+		t.Errorf("crossposting back into the original's subreddit = %v, want %v", err, ErrValidation)
+	}
+}
+
+func TestCrosspostLinks(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	if err := e.SetAdmin("owner", true); err != nil {
+		t.Fatalf("SetAdmin: %v", err)
+	}
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	mustCreateSubreddit(t, e, "rust", "owner", SubredditPublic)
+	mustCreateSubreddit(t, e, "secret", "owner", SubredditPrivate)
+	if err := e.ApproveUser("secret", "owner", "alice"); err != nil {
+		t.Fatalf("ApproveUser: %v", err)
+	}
+
+	original := mustCreatePost(t, e, "owner", "golang", "Original")
+	public, err := e.CrosspostPost("alice", original.ID, "rust", "", "", "")
+	if err != nil {
+		t.Fatalf("CrosspostPost: %v", err)
+	}
+	if _, err := e.CrosspostPost("alice", original.ID, "secret", "", "", ""); err != nil {
+		t.Fatalf("CrosspostPost: %v", err)
+	}
+
+	remove := func() {
+		if err := e.ModeratePost(original.ID, "owner", ModRemove, "spam"); err != nil {
+			t.Fatalf("ModeratePost: %v", err)
+		}
+	}
+	deleteSubreddit := func() {
+		if err := e.DeleteSubreddit("owner", "golang"); err != nil {
+			t.Fatalf("DeleteSubreddit: %v", err)
+		}
+	}
+
+	// The cases run in order and each change stays in place for the next
+	tests := []struct {
+		name         string
+		change       func()
+		post         *Post
+		viewer       string
+		wantOriginal *Post
+		wantCanSee   bool
+		wantCount    int
+	}{
+		{"original, approved viewer", nil, original, "alice", nil, false, 2},
+		{"original, other viewer", nil, original, "bob", nil, false, 1},
+		{"original, anonymous", nil, original, "", nil, false, 1},
+		{"crosspost", nil, public, "bob", original, true, 0},
+		{"removed original", remove, public, "bob", original, false, 0},
+		{"removed original, moderator", nil, public, "owner", original, true, 0},
+		{"deleted original", deleteSubreddit, public, "owner", nil, false, 0},
+	}
+
+	for _, tt := range tests {
+		if tt.change != nil {
+			tt.change()
+		}
+		gotOriginal, gotCanSee, gotCount := e.CrosspostLinks(tt.post, tt.viewer)
+		if gotOriginal != tt.wantOriginal || gotCanSee != tt.wantCanSee || gotCount != tt.wantCount {
+			t.Errorf("%s: CrosspostLinks = %p, %v, %d, want %p, %v, %d", tt.name,
+				gotOriginal, gotCanSee, gotCount, tt.wantOriginal, tt.wantCanSee, tt.wantCount)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors classifying every engine failure. Check them with
+// errors.Is; the REST layer maps each to an HTTP status and error code.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrForbidden     = errors.New("forbidden")
+	ErrValidation    = errors.New("validation failed")
+	ErrConflict      = errors.New("conflict")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrRateLimited   = errors.New("rate limited")
+)
+
+// EngineError is an error message tagged with one of the sentinel kinds
+type EngineError struct {
+	Kind    error
+	Message string
+}
+
+func (e *EngineError) Error() string {
+	return e.Message
+}
+
+func (e *EngineError) Unwrap() error {
+	return e.Kind
+}
+
+// newError builds an EngineError of the given kind
+func newError(kind error, format string, args ...interface{}) error {
+	return &EngineError{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return ErrNotFound
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+func (e *MembershipError) Unwrap() error {
+	return ErrForbidden
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
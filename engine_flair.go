@@ -0,0 +1,358 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FlairKind says whether a flair template labels posts or users
+type FlairKind string
+
+const (
+	FlairPost FlairKind = "post"
+	FlairUser FlairKind = "user"
+)
+
+const maxFlairLength = 64
+
+// flairColorPattern matches the "#rrggbb" colours of flair templates
+var flairColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// FlairTemplate is a flair moderators offer in their subreddit. Users may
+// replace the text of editable templates with their own; mod-only templates
+// can only be assigned by moderators with the flair permission.
+type FlairTemplate struct {
+	ID       string
+	Kind     FlairKind
+	Text     string
+	Color    string // "#rrggbb", or empty for the default colour
+	Editable bool
+	ModOnly  bool
+}
+
+// Flair is a label on a post or on a user within one subreddit. TemplateID is
+// empty for flair set by AutoModerator.
+type Flair struct {
+	TemplateID string
+	Text       string
+	Color      string
+}
+
+// ParseFlairKind converts a kind name into a FlairKind. An empty string
+// selects FlairPost.
+func ParseFlairKind(value string) (FlairKind, error) {
+	switch kind := FlairKind(value); kind {
+	case "":
+		return FlairPost, nil
+	case FlairPost, FlairUser:
+		return kind, nil
+	}
+	return "", &ValidationError{Field: "flair kind", Reason: fmt.Sprintf("unknown flair kind %q", value)}
+}
+
+func validateFlairText(text string) error {
+	if err := requireText("flair text", text); err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(text) > maxFlairLength {
+		return &ValidationError{Field: "flair text", Reason: fmt.Sprintf("is longer than %d characters", maxFlairLength)}
+	}
+	return nil
+}
+
+// findFlairTemplate returns the template with the given ID and its index.
+// Callers must hold s.mu.
+func (s *Subreddit) findFlairTemplate(id string) (*FlairTemplate, int) {
+	for i, template := range s.FlairTemplates {
+		if template.ID == id {
+			return template, i
+		}
+	}
+	return nil, -1
+}
+
+// resolveFlair builds the flair actor picked from template templateID, with
+// text replacing the template's text when it is editable. Callers must hold
+// s.mu.
+func (s *Subreddit) resolveFlair(kind FlairKind, actor, templateID, text string) (Flair, error) {
+	template, _ := s.findFlairTemplate(templateID)
+	if template == nil || template.Kind != kind {
+		return Flair{}, &NotFoundError{Kind: string(kind) + " flair template", ID: templateID}
+	}
+	if template.ModOnly && !s.hasModPermission(actor, PermFlair) {
+		return Flair{}, newError(ErrForbidden, "flair %q is reserved for moderators", template.Text)
+	}
+
+	flair := Flair{TemplateID: template.ID, Text: template.Text, Color: template.Color}
+	if text != "" && text != template.Text {
+		if !template.Editable {
+			return Flair{}, &ValidationError{Field: "flair text", Reason: fmt.Sprintf("flair %q cannot be edited", template.Text)}
+		}
+		if err := validateFlairText(text); err != nil {
+			return Flair{}, err
+		}
+		flair.Text = text
+	}
+	return flair, nil
+}
+
+// resolvePostFlair returns the flair a new post by author starts with,
+// enforcing the subreddit's post flair requirement. Callers must hold s.mu.
+func (s *Subreddit) resolvePostFlair(author, templateID, text string) (Flair, error) {
+	if templateID == "" {
+		if s.RequirePostFlair {
+			return Flair{}, &ValidationError{Field: "flair", Reason: fmt.Sprintf("posts in %s need a flair", s.Name)}
+		}
+		if text != "" {
+			return Flair{}, &ValidationError{Field: "flair text", Reason: "needs a flair template"}
+		}
+		return Flair{}, nil
+	}
+	return s.resolveFlair(FlairPost, author, templateID, text)
+}
+
+// AddFlairTemplate offers a new post or user flair in a subreddit. Needs the
+// flair permission.
+func (e *RedditEngine) AddFlairTemplate(subredditName, actor string, template FlairTemplate) (*FlairTemplate, error) {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return nil, err
+	}
+
+	kind, err := ParseFlairKind(string(template.Kind))
+	if err != nil {
+		return nil, err
+	}
+	if err := validateFlairText(template.Text); err != nil {
+		return nil, err
+	}
+	if template.Color != "" && !flairColorPattern.MatchString(template.Color) {
+		return nil, &ValidationError{Field: "flair color", Reason: fmt.Sprintf("%q is not a #rrggbb colour", template.Color)}
+	}
+
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermFlair); err != nil {
+		return nil, err
+	}
+
+	added := &FlairTemplate{
+		ID:       fmt.Sprintf("flair_%d", time.Now().UnixNano()),
+		Kind:     kind,
+		Text:     template.Text,
+		Color:    strings.ToLower(template.Color),
+		Editable: template.Editable,
+		ModOnly:  template.ModOnly,
+	}
+	subreddit.FlairTemplates = append(subreddit.FlairTemplates, added)
+	subreddit.logModAction(actor, LogEditFlair, added.ID, fmt.Sprintf("added %s flair %q", kind, added.Text))
+
+	copied := *added
+	return &copied, nil
+}
+
+// RemoveFlairTemplate stops offering a flair. Posts and users keep flair
+// already picked from it. Needs the flair permission.
+func (e *RedditEngine) RemoveFlairTemplate(subredditName, actor, templateID string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermFlair); err != nil {
+		return err
+	}
+	template, i := subreddit.findFlairTemplate(templateID)
+	if template == nil {
+		return &NotFoundError{Kind: "flair template", ID: templateID}
+	}
+	subreddit.FlairTemplates = append(subreddit.FlairTemplates[:i], subreddit.FlairTemplates[i+1:]...)
+	subreddit.logModAction(actor, LogEditFlair, templateID, fmt.Sprintf("removed %s flair %q", template.Kind, template.Text))
+	return nil
+}
+
+// GetFlairTemplates returns the flair templates of one kind in the order
+// they were added. Mod-only templates are left out unless viewer has the
+// flair permission.
+func (e *RedditEngine) GetFlairTemplates(subredditName, viewer string, kind FlairKind) ([]*FlairTemplate, error) {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	if err := subreddit.checkCanView(viewer); err != nil {
+		return nil, err
+	}
+
+	moderator := subreddit.hasModPermission(viewer, PermFlair)
+	templates := make([]*FlairTemplate, 0, len(subreddit.FlairTemplates))
+	for _, template := range subreddit.FlairTemplates {
+		if template.Kind == kind && (moderator || !template.ModOnly) {
+			copied := *template
+			templates = append(templates, &copied)
+		}
+	}
+	return templates, nil
+}
+
+// SetRequirePostFlair controls whether new posts must pick a post flair.
+// Needs the config permission.
+func (e *RedditEngine) SetRequirePostFlair(subredditName, actor string, require bool) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermConfig); err != nil {
+		return err
+	}
+	if subreddit.RequirePostFlair == require {
+		return nil
+	}
+	subreddit.RequirePostFlair = require
+	subreddit.logModAction(actor, LogEditSettings, subredditName, fmt.Sprintf("require post flair %t", require))
+	return nil
+}
+
+// SetPostFlair changes the flair of a post to template templateID, or clears
+// it when templateID is empty. The author may flair their own post;
+// moderators with the flair permission may flair any post and clear flair
+// the subreddit requires.
+func (e *RedditEngine) SetPostFlair(postID, actor, templateID, text string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	post, err := e.GetPost(postID)
+	if err != nil {
+		return err
+	}
+	subreddit, err := e.GetSubreddit(post.Subreddit)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+	post.mu.Lock()
+	defer post.mu.Unlock()
+
+	moderator := subreddit.hasModPermission(actor, PermFlair)
+	if post.Deleted {
+		return newError(ErrConflict, "post has been deleted")
+	}
+	if post.Author != actor && !moderator {
+		return newError(ErrForbidden, "only the author or a moderator can flair this post")
+	}
+
+	var flair Flair
+	if templateID != "" {
+		if flair, err = subreddit.resolveFlair(FlairPost, actor, templateID, text); err != nil {
+			return err
+		}
+	} else if subreddit.RequirePostFlair && !moderator {
+		return &ValidationError{Field: "flair", Reason: fmt.Sprintf("posts in %s need a flair", subreddit.Name)}
+	}
+
+	post.Flair = flair
+	if moderator && post.Author != actor {
+		subreddit.logModAction(actor, LogEditFlair, postID, fmt.Sprintf("post flair %q", flair.Text))
+	}
+	return nil
+}
+
+// SetUserFlair sets the flair username shows in a subreddit to template
+// templateID, or clears it when templateID is empty. Users may pick their
+// own flair; moderators with the flair permission may set anyone's.
+func (e *RedditEngine) SetUserFlair(subredditName, actor, username, templateID, text string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	subreddit, err := e.lookupModTarget(subredditName, username)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	moderator := subreddit.hasModPermission(actor, PermFlair)
+	if actor != username && !moderator {
+		return newError(ErrForbidden, "only moderators can set the flair of other users")
+	}
+	if err := subreddit.checkCanView(actor); err != nil {
+		return err
+	}
+
+	if templateID == "" {
+		delete(subreddit.UserFlair, username)
+	} else {
+		flair, err := subreddit.resolveFlair(FlairUser, actor, templateID, text)
+		if err != nil {
+			return err
+		}
+		subreddit.UserFlair[username] = flair
+	}
+	if actor != username {
+		subreddit.logModAction(actor, LogEditFlair, username, fmt.Sprintf("user flair %q", subreddit.UserFlair[username].Text))
+	}
+	return nil
+}
+
+// GetUserFlairs returns the user flair of everyone who has one in a
+// subreddit, by username
+func (e *RedditEngine) GetUserFlairs(subredditName string) (map[string]Flair, error) {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	flairs := make(map[string]Flair, len(subreddit.UserFlair))
+	for username, flair := range subreddit.UserFlair {
+		flairs[username] = flair
+	}
+	return flairs, nil
+}
+
+// postsWithFlair keeps the posts whose flair text is text, ignoring case
+func postsWithFlair(posts []*Post, text string) []*Post {
+	kept := make([]*Post, 0, len(posts))
+	for _, post := range posts {
+		post.mu.RLock()
+		matches := strings.EqualFold(post.Flair.Text, text)
+		post.mu.RUnlock()
+		if matches {
+			kept = append(kept, post)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mustAddFlairTemplate adds a flair template as the subreddit's creator
+func mustAddFlairTemplate(t *testing.T, e *RedditEngine, subredditName, creator string, template FlairTemplate) *FlairTemplate {
+	t.Helper()
+	added, err := e.AddFlairTemplate(subredditName, creator, template)
+	if err != nil {
+		t.Fatalf("AddFlairTemplate(%q): %v", template.Text, err)
+	}
+	return added
+}
+
+func TestAddFlairTemplate(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+
+	tests := []struct {
+		name     string
+		actor    string
+		template FlairTemplate
+		wantErr  error
+	}{
+		{"post flair", "owner", FlairTemplate{Text: "Question", Color: "#FF8800"}, nil},
+		{"user flair", "owner", FlairTemplate{Kind: FlairUser, Text: "Gopher", Editable: true}, nil},
+		{"mod-only flair", "owner", FlairTemplate{Text: "Announcement", ModOnly: true}, nil},
+		{"by a non-moderator", "alice", FlairTemplate{Text: "Mine"}, ErrForbidden},
+		{"unknown kind", "owner", FlairTemplate{Kind: "comment", Text: "Hot take"}, ErrValidation},
+		{"blank text", "owner", FlairTemplate{Text: "  "}, ErrValidation},
+		{"text too long", "owner", FlairTemplate{Text: strings.Repeat("x", maxFlairLength+1)}, ErrValidation},
+		{"bad colour", "owner", FlairTemplate{Text: "Red", Color: "red"}, ErrValidation},
+	}
+
+	for _, tt := range tests {
+		if _, err := e.AddFlairTemplate("golang", tt.actor, tt.template); !errors.Is(err, tt.wantErr) {
+			t.Errorf("AddFlairTemplate %s error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	texts := func(viewer string, kind FlairKind) []string {
+		templates, err := e.GetFlairTemplates("golang", viewer, kind)
+		if err != nil {
+			t.Fatalf("GetFlairTemplates: %v", err)
+		}
+		names := make([]string, len(templates))
+		for i, template := range templates {
+			names[i] = template.Text
+		}
+		return names
+	}
+	if got, want := texts("owner", FlairPost), []string{"Question", "Announcement"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("post flair seen by a moderator = %v, want %v", got, want)
+	}
+	if got, want := texts("alice", FlairPost), []string{"Question"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("post flair seen by a user = %v, want %v", got, want)
+	}
+	if got, want := texts("alice", FlairUser), []string{"Gopher"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("user flair = %v, want %v", got, want)
+	}
+	if templates, _ := e.GetFlairTemplates("golang", "alice", FlairPost); templates[0].Color != "#ff8800" {
+		t.Errorf("colour = %q, want it lower-cased", templates[0].Color)
+	}
+}
+
+func TestPostFlair(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	e.SetRateLimits(SimulationRateLimits)
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	question := mustAddFlairTemplate(t, e, "golang", "owner", FlairTemplate{Text: "Question"})
+	custom := mustAddFlairTemplate(t, e, "golang", "owner", FlairTemplate{Text: "Custom", Editable: true})
+	official := mustAddFlairTemplate(t, e, "golang", "owner", FlairTemplate{Text: "Official", ModOnly: true})
+	gopher := mustAddFlairTemplate(t, e, "golang", "owner", FlairTemplate{Kind: FlairUser, Text: "Gopher"})
+
+	submit := func(author, templateID, text string) (*Post, error) {
+		return e.SubmitPost(author, "golang", PostSubmission{Title: "post", FlairTemplateID: templateID, FlairText: text})
+	}
+
+	tests := []struct {
+		name       string
+		author     string
+		templateID string
+		text       string
+		wantErr    error
+		wantText   string
+	}{
+		{"no flair", "alice", "", "", nil, ""},
+		{"template", "alice", question.ID, "", nil, "Question"},
+		{"edited text", "alice", custom.ID, "Release notes", nil, "Release notes"},
+		{"text of a fixed template", "alice", question.ID, "Answer", ErrValidation, ""},
+		{"text without a template", "alice", "", "Loose", ErrValidation, ""},
+		{"mod-only by a user", "alice", official.ID, "", ErrForbidden, ""},
+		{"mod-only by a moderator", "owner", official.ID, "", nil, "Official"},
+		{"user flair on a post", "alice", gopher.ID, "", ErrNotFound, ""},
+		{"unknown template", "alice", "flair_missing", "", ErrNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		post, err := submit(tt.author, tt.templateID, tt.text)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: SubmitPost error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && post.Flair.Text != tt.wantText {
+			t.Errorf("%s: flair = %q, want %q", tt.name, post.Flair.Text, tt.wantText)
+		}
+	}
+
+	// Once flair is required only moderators may post or leave posts without it
+	post, err := submit("alice", question.ID, "")
+	if err != nil {
+		t.Fatalf("SubmitPost: %v", err)
+	}
+	if err := e.SetRequirePostFlair("golang", "alice", true); !errors.Is(err, ErrForbidden) {
+		t.Errorf("SetRequirePostFlair by a user error = %v, want ErrForbidden", err)
+	}
+	if err := e.SetRequirePostFlair("golang", "owner", true); err != nil {
+		t.Fatalf("SetRequirePostFlair: %v", err)
+	}
+	if _, err := submit("alice", "", ""); !errors.Is(err, ErrValidation) {
+		t.Errorf("SubmitPost without the required flair error = %v, want ErrValidation", err)
+	}
+
+	changes := []struct {
+		name       string
+		actor      string
+		templateID string
+		wantErr    error
+		wantText   string
+	}{
+		{"author changes it", "alice", custom.ID, nil, "Custom"},
+		{"another user", "bob", question.ID, ErrForbidden, "Custom"},
+		{"author clears required flair", "alice", "", ErrValidation, "Custom"},
+		{"moderator sets mod-only flair", "owner", official.ID, nil, "Official"},
+		{"moderator clears it", "owner", "", nil, ""},
+	}
+	for _, tt := range changes {
+		if err := e.SetPostFlair(post.ID, tt.actor, tt.templateID, ""); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: SetPostFlair error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if post.Flair.Text != tt.wantText {
+			t.Errorf("%s: flair = %q, want %q", tt.name, post.Flair.Text, tt.wantText)
+		}
+	}
+}
+
+func TestRemovedFlairTemplateKeepsFlair(t *testing.T) {
+	e := newTestEngine(t, "owner")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	question := mustAddFlairTemplate(t, e, "golang", "owner", FlairTemplate{Text: "Question"})
+	post, err := e.SubmitPost("owner", "golang", PostSubmission{Title: "post", FlairTemplateID: question.ID})
+	if err != nil {
+		t.Fatalf("SubmitPost: %v", err)
+	}
+
+	if err := e.RemoveFlairTemplate("golang", "owner", question.ID); err != nil {
+		t.Fatalf("RemoveFlairTemplate: %v", err)
+	}
+	if err := e.RemoveFlairTemplate("golang", "owner", question.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("repeat RemoveFlairTemplate error = %v, want ErrNotFound", err)
+	}
+	if post.Flair.Text != "Question" {
+		t.Errorf("flair = %q after removing its template, want Question", post.Flair.Text)
+	}
+	if _, err := e.SubmitPost("owner", "golang", PostSubmission{Title: "post", FlairTemplateID: question.ID}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SubmitPost with a removed template error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUserFlair(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	gopher := mustAddFlairTemplate(t, e, "golang", "owner", FlairTemplate{Kind: FlairUser, Text: "Gopher", Editable: true, Color: "#00add8"})
+	staff := mustAddFlairTemplate(t, e, "golang", "owner", FlairTemplate{Kind: FlairUser, Text: "Staff", ModOnly: true})
+	question := mustAddFlairTemplate(t, e, "golang", "owner", FlairTemplate{Text: "Question"})
+
+	// The changes are made in order
+	tests := []struct {
+		name       string
+		actor      string
+		username   string
+		templateID string
+		text       string
+		wantErr    error
+	}{
+		{"own flair", "alice", "alice", gopher.ID, "", nil},
+		{"edited text", "bob", "bob", gopher.ID, "Rustacean at heart", nil},
+		{"someone else's", "alice", "bob", gopher.ID, "", ErrForbidden},
+		{"mod-only flair", "alice", "alice", staff.ID, "", ErrForbidden},
+		{"post flair on a user", "alice", "alice", question.ID, "", ErrNotFound},
+		{"moderator sets it", "owner", "owner", staff.ID, "", nil},
+		{"moderator clears bob's", "owner", "bob", "", "", nil},
+	}
+
+	for _, tt := range tests {
+		if err := e.SetUserFlair("golang", tt.actor, tt.username, tt.templateID, tt.text); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: SetUserFlair error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	flairs, err := e.GetUserFlairs("golang")
+	if err != nil {
+		t.Fatalf("GetUserFlairs: %v", err)
+	}
+	want := map[string]Flair{
+		"alice": {TemplateID: gopher.ID, Text: "Gopher", Color: "#00add8"},
+		"owner": {TemplateID: staff.ID, Text: "Staff"},
+	}
+	if !reflect.DeepEqual(flairs, want) {
+		t.Errorf("GetUserFlairs = %+v, want %+v", flairs, want)
+	}
+}
+
+func TestPostsWithFlair(t *testing.T) {
+	posts := []*Post{
+		{ID: "a", Flair: Flair{Text: "Question"}},
+		{ID: "b", Flair: Flair{Text: "News"}},
+		{ID: "c"},
+		{ID: "d", Flair: Flair{Text: "question"}},
+	}
+
+	if got, want := postIDs(postsWithFlair(posts, "QUESTION")), []string{"a", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("postsWithFlair = %v, want %v", got, want)
+	}
+	if got := postIDs(postsWithFlair(posts, "Meta")); len(got) != 0 {
+		t.Errorf("postsWithFlair(unused) = %v, want none", got)
+	}
+}
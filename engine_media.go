@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"  // GIF decoder
+	_ "image/jpeg" // JPEG decoder
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	DefaultMaxImageSize = 10 << 20 // bytes
+	maxImagePixels      = 40_000_000
+	thumbnailSize       = 140 // longest side of a thumbnail in pixels
+)
+
+// imageExtensions lists the accepted image types and the extension their
+// files are stored with
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// mediaNamePattern matches the names of files in a MediaStore
+var mediaNamePattern = regexp.MustCompile(`^[0-9a-f]{64}(\.jpg|\.png|\.gif|_thumb\.png)$`)
+
+// Media describes an image held in a MediaStore
+type Media struct {
+	Hash     string // hex SHA-256 of the image, which names its files
+	MIMEType string
+	Size     int64
+	Width    int
+	Height   int
+}
+
+// FileName returns the name of the original image in the store
+func (m *Media) FileName() string {
+	return m.Hash + imageExtensions[m.MIMEType]
+}
+
+// ThumbnailName returns the name of the PNG thumbnail in the store
+func (m *Media) ThumbnailName() string {
+	return m.Hash + "_thumb.png"
+}
+
+// MediaStore keeps uploaded images on local disk, addressed by the hash of
+// their content so the same image uploaded twice is stored once. Every image
+// is stored alongside a thumbnail.
+type MediaStore struct {
+	dir     string
+	maxSize int64
+}
+
+// NewMediaStore opens a store in dir, creating the directory when needed.
+// Images larger than maxSize bytes are rejected.
+func NewMediaStore(dir string, maxSize int64) (*MediaStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating media directory: %w", err)
+	}
+	return &MediaStore{dir: dir, maxSize: maxSize}, nil
+}
+
+// MaxSize returns the size in bytes of the largest image the store accepts
+func (m *MediaStore) MaxSize() int64 {
+	return m.maxSize
+}
+
+// Put validates an uploaded image and stores it with its thumbnail
+func (m *MediaStore) Put(data []byte) (*Media, error) {
+	if len(data) == 0 {
+		return nil, &ValidationError{Field: "image", Reason: "cannot be empty"}
+	}
+	if int64(len(data)) > m.maxSize {
+		return nil, &ValidationError{Field: "image", Reason: fmt.Sprintf("is larger than %d bytes", m.maxSize)}
+	}
+
+	mimeType := http.DetectContentType(data)
+	if _, ok := imageExtensions[mimeType]; !ok {
+		return nil, &ValidationError{Field: "image", Reason: fmt.Sprintf("type %s is not supported", mimeType)}
+	}
+
+	// Check the dimensions before decoding so a small file cannot claim a
+	// huge canvas
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || "image/"+format != mimeType {
+		return nil, &ValidationError{Field: "image", Reason: "is not a valid image"}
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, &ValidationError{Field: "image", Reason: fmt.Sprintf("has more than %d pixels", maxImagePixels)}
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, &ValidationError{Field: "image", Reason: "is not a valid image"}
+	}
+
+	sum := sha256.Sum256(data)
+	media := &Media{
+		Hash:     hex.EncodeToString(sum[:]),
+		MIMEType: mimeType,
+		Size:     int64(len(data)),
+		Width:    config.Width,
+		Height:   config.Height,
+	}
+
+	var thumb bytes.Buffer
+	if err := png.Encode(&thumb, thumbnail(img, thumbnailSize)); err != nil {
+		return nil, fmt.Errorf("encoding thumbnail: %w", err)
+	}
+	if err := m.write(media.FileName(), data); err != nil {
+		return nil, err
+	}
+	if err := m.write(media.ThumbnailName(), thumb.Bytes()); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+// Open opens a stored file by name for reading
+func (m *MediaStore) Open(name string) (*os.File, error) {
+	if !mediaNamePattern.MatchString(name) {
+		return nil, &NotFoundError{Kind: "media", ID: name}
+	}
+	file, err := os.Open(filepath.Join(m.dir, name))
+	if os.IsNotExist(err) {
+		return nil, &NotFoundError{Kind: "media", ID: name}
+	}
+	return file, err
+}
+
+// Delete removes an image and its thumbnail from the store. Files that are
+// already gone are ignored.
+func (m *MediaStore) Delete(media *Media) error {
+	for _, name := range []string{media.FileName(), media.ThumbnailName()} {
+		if err := os.Remove(filepath.Join(m.dir, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("deleting %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// write stores data under name unless the file already exists. Files are
+// written to a temporary name and renamed so readers never see a partial
+// file.
+func (m *MediaStore) write(name string, data []byte) error {
+	path := filepath.Join(m.dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(m.dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("storing %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("storing %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("storing %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("storing %s: %w", name, err)
+	}
+	return nil
+}
+
+// thumbnail scales img down so its longest side is at most size pixels.
+// Each thumbnail pixel averages the block of source pixels it covers.
+func thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+
+	tw, th := size, h*size/w
+	if h > w {
+		tw, th = w*size/h, size
+	}
+	tw, th = max(tw, 1), max(th, 1)
+
+	thumb := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := bounds.Min.Y+y*h/th, bounds.Min.Y+max((y+1)*h/th, y*h/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := bounds.Min.X+x*w/tw, bounds.Min.X+max((x+1)*w/tw, x*w/tw+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			i := thumb.PixOffset(x, y)
+			thumb.Pix[i+0] = uint8((r / n) >> 8)
+			thumb.Pix[i+1] = uint8((g / n) >> 8)
+			thumb.Pix[i+2] = uint8((b / n) >> 8)
+			thumb.Pix[i+3] = uint8((a / n) >> 8)
+		}
+	}
+	return thumb
+}
@@ -0,0 +1,554 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ModPermission is a set of moderator permissions
+type ModPermission uint8
+
+const (
+	PermPosts  ModPermission = 1 << iota // approve, remove, sticky and lock content
+	PermConfig                           // edit settings, rules and AutoModerator
+	PermAccess                           // ban, mute and approve users
+	PermFlair                            // manage flair templates and assign flair
+	PermMail                             // handle messages to the moderators
+
+	PermAll = PermPosts | PermConfig | PermAccess | PermFlair | PermMail
+)
+
+// modPermissionNames lists the permission names used over the API, in order
+var modPermissionNames = []struct {
+	name string
+	perm ModPermission
+}{
+	{"posts", PermPosts},
+	{"config", PermConfig},
+	{"access", PermAccess},
+	{"flair", PermFlair},
+	{"mail", PermMail},
+}
+
+// ParseModPermissions converts permission names into a ModPermission. "all"
+// grants every permission; an empty list also means full permissions.
+func ParseModPermissions(names []string) (ModPermission, error) {
+	if len(names) == 0 {
+		return PermAll, nil
+	}
+
+	var perms ModPermission
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "all" {
+			perms |= PermAll
+			continue
+		}
+		found := false
+		for _, entry := range modPermissionNames {
+			if entry.name == name {
+				perms |= entry.perm
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, &ValidationError{Field: "permissions", Reason: "unknown permission " + name}
+		}
+	}
+	return perms, nil
+}
+
+// Names returns the API names of the permissions in p
+func (p ModPermission) Names() []string {
+	if p == PermAll {
+		return []string{"all"}
+	}
+	names := make([]string, 0)
+	for _, entry := range modPermissionNames {
+		if p&entry.perm != 0 {
+			names = append(names, entry.name)
+		}
+	}
+	return names
+}
+
+// Moderator is a user moderating a subreddit. Subreddit.Moderators is kept
+// in order of seniority, the creator first.
+type Moderator struct {
+	Username    string
+	Permissions ModPermission
+	AddedBy     string
+	AddedAt     time.Time
+}
+
+// ModInvite is a pending invitation to moderate a subreddit
+type ModInvite struct {
+	Username    string
+	Permissions ModPermission
+	InvitedBy   string
+	InvitedAt   time.Time
+}
+
+// findModerator returns the moderator entry of username and its seniority
+// rank, or nil and -1. Callers must hold subreddit.mu.
+func (s *Subreddit) findModerator(username string) (*Moderator, int) {
+	for i, mod := range s.Moderators {
+		if mod.Username == username {
+			return mod, i
+		}
+	}
+	return nil, -1
+}
+
+// hasModPermission reports whether username moderates the subreddit with
+// every permission in perm. Callers must hold subreddit.mu.
+func (s *Subreddit) hasModPermission(username string, perm ModPermission) bool {
+	mod, _ := s.findModerator(username)
+	return mod != nil && mod.Permissions&perm == perm
+}
+
+// checkModPermission returns a forbidden error unless username moderates the
+// subreddit with perm. Callers must hold subreddit.mu.
+func (s *Subreddit) checkModPermission(username string, perm ModPermission) error {
+	if !s.hasModPermission(username, perm) {
+		if perm == 0 {
+			return newError(ErrForbidden, "%s does not moderate %s", username, s.Name)
+		}
+		return newError(ErrForbidden, "%s lacks the %s moderator permission in %s", username, strings.Join(perm.Names(), "+"), s.Name)
+	}
+	return nil
+}
+
+// IsModerator reports whether username moderates the subreddit
+func (e *RedditEngine) IsModerator(subredditName, username string) bool {
+	return e.HasModPermission(subredditName, username, 0)
+}
+
+// HasModPermission reports whether username moderates the subreddit with
+// every permission in perm
+func (e *RedditEngine) HasModPermission(subredditName, username string, perm ModPermission) bool {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return false
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+	return subreddit.hasModPermission(username, perm)
+}
+
+// GetModerators returns the moderators of a subreddit in order of seniority
+func (e *RedditEngine) GetModerators(subredditName string) ([]*Moderator, error) {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	moderators := make([]*Moderator, len(subreddit.Moderators))
+	copy(moderators, subreddit.Moderators)
+	return moderators, nil
+}
+
+// lookupModTarget resolves the subreddit and the user a moderator action
+// targets
+func (e *RedditEngine) lookupModTarget(subredditName, username string) (*Subreddit, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	subreddit, err := e.lookupSubreddit(subredditName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.lookupUser(username); err != nil {
+		return nil, err
+	}
+	return subreddit, nil
+}
+
+// AddModerator makes username a moderator right away. Only moderators with
+// full permissions may add moderators.
+func (e *RedditEngine) AddModerator(subredditName, actor, username string, perms ModPermission) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	subreddit, err := e.lookupModTarget(subredditName, username)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermAll); err != nil {
+		return err
+	}
+	if mod, _ := subreddit.findModerator(username); mod != nil {
+		return newError(ErrAlreadyExists, "%s already moderates %s", username, subredditName)
+	}
+
+	subreddit.Moderators = append(subreddit.Moderators, &Moderator{
+		Username:    username,
+		Permissions: perms,
+		AddedBy:     actor,
+		AddedAt:     time.Now(),
+	})
+	delete(subreddit.ModInvites, username)
+	subreddit.logModAction(actor, LogAddModerator, username, strings.Join(perms.Names(), ","))
+	return nil
+}
+
+// InviteModerator invites username to moderate the subreddit. The invite
+// takes effect once accepted. Only moderators with full permissions may
+// invite.
+func (e *RedditEngine) InviteModerator(subredditName, actor, username string, perms ModPermission) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	subreddit, err := e.lookupModTarget(subredditName, username)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermAll); err != nil {
+		return err
+	}
+	if mod, _ := subreddit.findModerator(username); mod != nil {
+		return newError(ErrAlreadyExists, "%s already moderates %s", username, subredditName)
+	}
+
+	subreddit.ModInvites[username] = &ModInvite{
+		Username:    username,
+		Permissions: perms,
+		InvitedBy:   actor,
+		InvitedAt:   time.Now(),
+	}
+	subreddit.logModAction(actor, LogInviteModerator, username, strings.Join(perms.Names(), ","))
+	return nil
+}
+
+// AcceptModeratorInvite turns a pending invitation into a moderator position
+func (e *RedditEngine) AcceptModeratorInvite(subredditName, username string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+
+	subreddit, err := e.lookupModTarget(subredditName, username)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	invite, ok := subreddit.ModInvites[username]
+	if !ok {
+		return &NotFoundError{Kind: "moderator invite", ID: username}
+	}
+	delete(subreddit.ModInvites, username)
+
+	subreddit.Moderators = append(subreddit.Moderators, &Moderator{
+		Username:    username,
+		Permissions: invite.Permissions,
+		AddedBy:     invite.InvitedBy,
+		AddedAt:     time.Now(),
+	})
+	subreddit.logModAction(username, LogAcceptInvite, username, "")
+	return nil
+}
+
+// GetModeratorInvites returns the pending moderator invitations of a
+// subreddit, oldest first. Only moderators with full permissions may list
+// them.
+func (e *RedditEngine) GetModeratorInvites(subredditName, actor string) ([]*ModInvite, error) {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	if err := subreddit.checkModPermission(actor, PermAll); err != nil {
+		return nil, err
+	}
+
+	invites := make([]*ModInvite, 0, len(subreddit.ModInvites))
+	for _, invite := range subreddit.ModInvites {
+		invites = append(invites, invite)
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].InvitedAt.Before(invites[j].InvitedAt)
+	})
+	return invites, nil
+}
+
+// RemoveModerator takes away username's moderator position. Moderators can
+// always step down; removing someone else needs full permissions and only
+// works on moderators added later than the actor.
+func (e *RedditEngine) RemoveModerator(subredditName, actor, username string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	_, targetRank := subreddit.findModerator(username)
+	if targetRank < 0 {
+		return &NotFoundError{Kind: "moderator", ID: username}
+	}
+
+	if actor != username {
+		if err := subreddit.checkModPermission(actor, PermAll); err != nil {
+			return err
+		}
+		if _, actorRank := subreddit.findModerator(actor); actorRank > targetRank {
+			return newError(ErrForbidden, "%s cannot remove %s, who has moderated %s longer", actor, username, subredditName)
+		}
+	}
+
+	subreddit.Moderators = append(subreddit.Moderators[:targetRank], subreddit.Moderators[targetRank+1:]...)
+	subreddit.logModAction(actor, LogRemoveModerator, username, "")
+	return nil
+}
+
+// RestrictionKind tells bans and mutes apart
+type RestrictionKind string
+
+const (
+	// RestrictionBan keeps a user from posting, commenting, voting and
+	// joining
+	RestrictionBan RestrictionKind = "ban"
+	// RestrictionMute keeps a user from posting and commenting; they can
+	// still vote and stay a member
+	RestrictionMute RestrictionKind = "mute"
+)
+
+// restrictionLogActions maps each kind to the mod log actions recording
+// when it is issued and lifted
+var restrictionLogActions = map[RestrictionKind][2]ModLogAction{
+	RestrictionBan:  {LogBanUser, LogUnbanUser},
+	RestrictionMute: {LogMuteUser, LogUnmuteUser},
+}
+
+// Restriction is a ban or mute of a user in a subreddit, or a site-wide
+// suspension. A zero ExpiresAt makes it permanent.
+type Restriction struct {
+	Username  string
+	Reason    string
+	IssuedBy  string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// Active reports whether the restriction is still in force at now
+func (r *Restriction) Active(now time.Time) bool {
+	return r.ExpiresAt.IsZero() || now.Before(r.ExpiresAt)
+}
+
+// restrictions returns the records of the given kind. Callers must hold
+// subreddit.mu.
+func (s *Subreddit) restrictions(kind RestrictionKind) map[string]*Restriction {
+	if kind == RestrictionMute {
+		return s.Mutes
+	}
+	return s.Bans
+}
+
+// activeRestriction returns username's restriction of the given kind if it
+// has not expired. Expired records are ignored here and pruned by the next
+// change to the list, so they lapse without anyone lifting them. Callers
+// must hold subreddit.mu.
+func (s *Subreddit) activeRestriction(kind RestrictionKind, username string) *Restriction {
+	restriction, ok := s.restrictions(kind)[username]
+	if !ok || !restriction.Active(time.Now()) {
+		return nil
+	}
+	return restriction
+}
+
+// pruneRestrictions drops expired records. Callers must hold subreddit.mu
+// for writing.
+func (s *Subreddit) pruneRestrictions(kind RestrictionKind) {
+	now := time.Now()
+	records := s.restrictions(kind)
+	for username, restriction := range records {
+		if !restriction.Active(now) {
+			delete(records, username)
+		}
+	}
+}
+
+// checkNotBanned returns a forbidden error while username is banned from
+// the subreddit. Callers must hold subreddit.mu.
+func (s *Subreddit) checkNotBanned(username string) error {
+	if ban := s.activeRestriction(RestrictionBan, username); ban != nil {
+		return newError(ErrForbidden, "%s is banned from %s%s", username, s.Name, untilSuffix(ban))
+	}
+	return nil
+}
+
+// checkCanContribute returns a forbidden error while username is banned or
+// muted in the subreddit. Callers must hold subreddit.mu.
+func (s *Subreddit) checkCanContribute(username string) error {
+	if err := s.checkNotBanned(username); err != nil {
+		return err
+	}
+	if mute := s.activeRestriction(RestrictionMute, username); mute != nil {
+		return newError(ErrForbidden, "%s is muted in %s%s", username, s.Name, untilSuffix(mute))
+	}
+	return nil
+}
+
+func untilSuffix(r *Restriction) string {
+	if r.ExpiresAt.IsZero() {
+		return ""
+	}
+	return " until " + r.ExpiresAt.Format(time.RFC3339)
+}
+
+// BanUser bans username from the subreddit for duration, or permanently
+// when duration is zero. Banning someone already banned replaces the ban.
+// Needs the access permission.
+func (e *RedditEngine) BanUser(subredditName, actor, username, reason string, duration time.Duration) error {
+	return e.restrictUser(RestrictionBan, subredditName, actor, username, reason, duration)
+}
+
+// MuteUser mutes username in the subreddit with the same rules as BanUser
+func (e *RedditEngine) MuteUser(subredditName, actor, username, reason string, duration time.Duration) error {
+	return e.restrictUser(RestrictionMute, subredditName, actor, username, reason, duration)
+}
+
+// UnbanUser lifts username's ban. Needs the access permission.
+func (e *RedditEngine) UnbanUser(subredditName, actor, username string) error {
+	return e.liftRestriction(RestrictionBan, subredditName, actor, username)
+}
+
+// UnmuteUser lifts username's mute. Needs the access permission.
+func (e *RedditEngine) UnmuteUser(subredditName, actor, username string) error {
+	return e.liftRestriction(RestrictionMute, subredditName, actor, username)
+}
+
+// GetBans returns the active bans of a subreddit, oldest first. Needs the
+// access permission.
+func (e *RedditEngine) GetBans(subredditName, actor string) ([]*Restriction, error) {
+	return e.listRestrictions(RestrictionBan, subredditName, actor)
+}
+
+// GetMutes returns the active mutes of a subreddit, oldest first. Needs the
+// access permission.
+func (e *RedditEngine) GetMutes(subredditName, actor string) ([]*Restriction, error) {
+	return e.listRestrictions(RestrictionMute, subredditName, actor)
+}
+
+func (e *RedditEngine) restrictUser(kind RestrictionKind, subredditName, actor, username, reason string, duration time.Duration) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	if err := requireText("reason", reason); err != nil {
+		return err
+	}
+	if duration < 0 {
+		return &ValidationError{Field: "duration", Reason: "cannot be negative"}
+	}
+
+	subreddit, err := e.lookupModTarget(subredditName, username)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermAccess); err != nil {
+		return err
+	}
+	if mod, _ := subreddit.findModerator(username); mod != nil {
+		return newError(ErrForbidden, "%s moderates %s and cannot be given a %s", username, subredditName, kind)
+	}
+
+	now := time.Now()
+	restriction := &Restriction{
+		Username: username,
+		Reason:   reason,
+		IssuedBy: actor,
+		IssuedAt: now,
+	}
+	if duration > 0 {
+		restriction.ExpiresAt = now.Add(duration)
+	}
+
+	subreddit.pruneRestrictions(kind)
+	subreddit.restrictions(kind)[username] = restriction
+	subreddit.logModAction(actor, restrictionLogActions[kind][0], username, reason)
+	return nil
+}
+
+func (e *RedditEngine) liftRestriction(kind RestrictionKind, subredditName, actor, username string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermAccess); err != nil {
+		return err
+	}
+
+	subreddit.pruneRestrictions(kind)
+	records := subreddit.restrictions(kind)
+	if _, ok := records[username]; !ok {
+		return &NotFoundError{Kind: string(kind), ID: username}
+	}
+	delete(records, username)
+	subreddit.logModAction(actor, restrictionLogActions[kind][1], username, "")
+	return nil
+}
+
+func (e *RedditEngine) listRestrictions(kind RestrictionKind, subredditName, actor string) ([]*Restriction, error) {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	if err := subreddit.checkModPermission(actor, PermAccess); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	list := make([]*Restriction, 0)
+	for _, restriction := range subreddit.restrictions(kind) {
+		if restriction.Active(now) {
+			list = append(list, restriction)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].IssuedAt.Before(list[j].IssuedAt)
+	})
+	return list, nil
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// moderatorNames returns the usernames of the moderators of a subreddit in
+// order of seniority
+func moderatorNames(t *testing.T, e *RedditEngine, subredditName string) []string {
+	t.Helper()
+	moderators, err := e.GetModerators(subredditName)
+	if err != nil {
+		t.Fatalf("GetModerators(%q): %v", subredditName, err)
+	}
+	names := make([]string, len(moderators))
+	for i, mod := range moderators {
+		names[i] = mod.Username
+	}
+	return names
+}
+
+func TestParseModPermissions(t *testing.T) {
+	tests := []struct {
+		names     []string
+		want      ModPermission
+		wantNames []string
+		wantErr   error
+	}{
+		{nil, PermAll, []string{"all"}, nil},
+		{[]string{"all"}, PermAll, []string{"all"}, nil},
+		{[]string{"posts"}, PermPosts, []string{"posts"}, nil},
+		{[]string{" Flair", "access"}, PermAccess | PermFlair, []string{"access", "flair"}, nil},
+		{[]string{"posts", "config", "access", "flair", "mail"}, PermAll, []string{"all"}, nil},
+		{[]string{"posts", "wiki"}, 0, nil, ErrValidation},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseModPermissions(tt.names)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseModPermissions(%q) = %v, %v, want %v, %v", tt.names, got, err, tt.want, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got.Names(), tt.wantNames) {
+			t.Errorf("ParseModPermissions(%q).Names() = %v, want %v", tt.names, got.Names(), tt.wantNames)
+		}
+	}
+}
+
+func TestModeratorPermissions(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+
+	if got := moderatorNames(t, e, "golang"); !reflect.DeepEqual(got, []string{"owner"}) {
+		t.Fatalf("moderators of a new subreddit = %v, want the creator", got)
+	}
+	if !e.HasModPermission("golang", "owner", PermAll) {
+		t.Error("the creator lacks full permissions")
+	}
+
+	if err := e.AddModerator("golang", "owner", "alice", PermPosts|PermFlair); err != nil {
+		t.Fatalf("AddModerator: %v", err)
+	}
+
+	tests := []struct {
+		username string
+		perm     ModPermission
+		want     bool
+	}{
+		{"alice", 0, true},
+		{"alice", PermPosts, true},
+		{"alice", PermPosts | PermFlair, true},
+		{"alice", PermAccess, false},
+		{"alice", PermAll, false},
+		{"bob", 0, false},
+		{"bob", PermPosts, false},
+	}
+	for _, tt := range tests {
+		if got := e.HasModPermission("golang", tt.username, tt.perm); got != tt.want {
+			t.Errorf("HasModPermission(%q, %v) = %v, want %v", tt.username, tt.perm.Names(), got, tt.want)
+		}
+	}
+	if e.HasModPermission("rust", "owner", 0) {
+		t.Error("HasModPermission in an unknown subreddit = true, want false")
+	}
+
+	// Only moderators with full permissions manage the team
+	if err := e.AddModerator("golang", "alice", "bob", PermPosts); !errors.Is(err, ErrForbidden) {
+		t.Errorf("AddModerator by a partial moderator error = %v, want ErrForbidden", err)
+	}
+	if err := e.AddModerator("golang", "bob", "bob", PermAll); !errors.Is(err, ErrForbidden) {
+		t.Errorf("AddModerator by a non-moderator error = %v, want ErrForbidden", err)
+	}
+	if err := e.AddModerator("golang", "owner", "alice", PermAll); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("AddModerator of a moderator error = %v, want ErrAlreadyExists", err)
+	}
+	if err := e.AddModerator("golang", "owner", "ghost", PermAll); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddModerator of an unknown user error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestModeratorInvites(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+
+	if err := e.AcceptModeratorInvite("golang", "alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AcceptModeratorInvite without an invite error = %v, want ErrNotFound", err)
+	}
+	if err := e.InviteModerator("golang", "bob", "alice", PermAll); !errors.Is(err, ErrForbidden) {
+		t.Errorf("InviteModerator by a non-moderator error = %v, want ErrForbidden", err)
+	}
+	if err := e.InviteModerator("golang", "owner", "alice", PermPosts); err != nil {
+		t.Fatalf("InviteModerator: %v", err)
+	}
+
+	invites, err := e.GetModeratorInvites("golang", "owner")
+	if err != nil {
+		t.Fatalf("GetModeratorInvites: %v", err)
+	}
+	if len(invites) != 1 || invites[0].Username != "alice" || invites[0].InvitedBy != "owner" {
+		t.Errorf("GetModeratorInvites = %d invites, want alice invited by owner", len(invites))
+	}
+	if _, err := e.GetModeratorInvites("golang", "alice"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetModeratorInvites by the invitee error = %v, want ErrForbidden", err)
+	}
+
+	// The invite grants nothing until it is accepted
+	if e.IsModerator("golang", "alice") {
+		t.Error("alice moderates before accepting the invite")
+	}
+	if err := e.AcceptModeratorInvite("golang", "alice"); err != nil {
+		t.Fatalf("AcceptModeratorInvite: %v", err)
+	}
+	if !e.HasModPermission("golang", "alice", PermPosts) || e.HasModPermission("golang", "alice", PermAccess) {
+		t.Error("alice does not hold exactly the invited permissions")
+	}
+	if invites, _ := e.GetModeratorInvites("golang", "owner"); len(invites) != 0 {
+		t.Errorf("GetModeratorInvites after accepting = %d invites, want 0", len(invites))
+	}
+	if err := e.InviteModerator("golang", "owner", "alice", PermAll); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("InviteModerator of a moderator error = %v, want ErrAlreadyExists", err)
+	}
+}
+
+func TestRemoveModerator(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob", "carol")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	for _, username := range []string{"alice", "bob", "carol"} {
+		if err := e.AddModerator("golang", "owner", username, PermAll); err != nil {
+			t.Fatalf("AddModerator(%q): %v", username, err)
+		}
+	}
+
+	// The removals run in order against the same team
+	tests := []struct {
+		name     string
+		actor    string
+		username string
+		wantErr  error
+		want     []string
+	}{
+		{"junior removes senior", "bob", "alice", ErrForbidden, []string{"owner", "alice", "bob", "carol"}},
+		{"senior removes junior", "alice", "bob", nil, []string{"owner", "alice", "carol"}},
+		{"stepping down", "carol", "carol", nil, []string{"owner", "alice"}},
+		{"not a moderator", "owner", "bob", ErrNotFound, []string{"owner", "alice"}},
+		{"creator removes", "owner", "alice", nil, []string{"owner"}},
+	}
+
+	for _, tt := range tests {
+		err := e.RemoveModerator("golang", tt.actor, tt.username)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: RemoveModerator error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if got := moderatorNames(t, e, "golang"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: moderators = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// contributions attempts each kind of contribution by username in
+// subredditName and returns the errors keyed by action
+func contributions(e *RedditEngine, username, subredditName, postID string) map[string]error {
+	errs := make(map[string]error)
+	_, errs["post"] = e.CreatePost("title", "content", username, subredditName)
+	_, errs["comment"] = e.AddComment("comment", username, postID, "")
+	_, errs["vote"] = e.VotePost(postID, username, VoteUp)
+	errs["join"] = e.JoinSubreddit(username, subredditName)
+	if errs["join"] == nil {
+		errs["join"] = e.LeaveSubreddit(username, subredditName)
+	}
+	return errs
+}
+
+func TestBansAndMutes(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	e.SetRateLimits(SimulationRateLimits)
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+
+	tests := []struct {
+		name     string
+		restrict func() error
+		lift     func() error
+		want     map[string]error
+	}{
+		{
+			name:     "ban",
+			restrict: func() error { return e.BanUser("golang", "owner", "alice", "spam", 0) },
+			lift:     func() error { return e.UnbanUser("golang", "owner", "alice") },
+			want:     map[string]error{"post": ErrForbidden, "comment": ErrForbidden, "vote": ErrForbidden, "join": ErrForbidden},
+		},
+		{
+			name:     "mute",
+			restrict: func() error { return e.MuteUser("golang", "owner", "alice", "flaming", time.Hour) },
+			lift:     func() error { return e.UnmuteUser("golang", "owner", "alice") },
+			want:     map[string]error{"post": ErrForbidden, "comment": ErrForbidden, "vote": nil, "join": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.restrict(); err != nil {
+				t.Fatalf("restrict: %v", err)
+			}
+			for action, err := range contributions(e, "alice", "golang", post.ID) {
+				if !errors.Is(err, tt.want[action]) {
+					t.Errorf("%s while restricted error = %v, want %v", action, err, tt.want[action])
+				}
+			}
+
+			if err := tt.lift(); err != nil {
+				t.Fatalf("lift: %v", err)
+			}
+			for action, err := range contributions(e, "alice", "golang", post.ID) {
+				if err != nil {
+					t.Errorf("%s after lifting error = %v, want nil", action, err)
+				}
+			}
+			if err := tt.lift(); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second lift error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestBanExpiry(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+
+	if err := e.BanUser("golang", "owner", "alice", "spam", time.Hour); err != nil {
+		t.Fatalf("BanUser(alice): %v", err)
+	}
+	if err := e.BanUser("golang", "owner", "bob", "spam", 0); err != nil {
+		t.Fatalf("BanUser(bob): %v", err)
+	}
+	ban := e.subreddits["golang"].Bans["alice"]
+	if ban.IssuedBy != "owner" || ban.Reason != "spam" || ban.ExpiresAt.Sub(ban.IssuedAt) != time.Hour {
+		t.Errorf("ban = %+v, want a one hour ban for spam issued by owner", *ban)
+	}
+
+	// Let alice's ban run out
+	ban.ExpiresAt = time.Now().Add(-time.Second)
+
+	if _, err := e.VotePost(post.ID, "alice", VoteUp); err != nil {
+		t.Errorf("VotePost after the ban expired: %v", err)
+	}
+	if _, err := e.VotePost(post.ID, "bob", VoteUp); !errors.Is(err, ErrForbidden) {
+		t.Errorf("VotePost under a permanent ban error = %v, want ErrForbidden", err)
+	}
+
+	bans, err := e.GetBans("golang", "owner")
+	if err != nil {
+		t.Fatalf("GetBans: %v", err)
+	}
+	if len(bans) != 1 || bans[0].Username != "bob" {
+		t.Errorf("GetBans = %d bans, want only bob", len(bans))
+	}
+	if err := e.UnbanUser("golang", "owner", "alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UnbanUser after expiry error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRestrictionErrors(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	if err := e.AddModerator("golang", "owner", "alice", PermPosts); err != nil {
+		t.Fatalf("AddModerator: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		actor    string
+		username string
+		reason   string
+		duration time.Duration
+		wantErr  error
+	}{
+		{"without the access permission", "alice", "bob", "spam", 0, ErrForbidden},
+		{"by a regular user", "bob", "alice", "spam", 0, ErrForbidden},
+		{"of a moderator", "owner", "alice", "spam", 0, ErrForbidden},
+		{"of an unknown user", "owner", "ghost", "spam", 0, ErrNotFound},
+		{"without a reason", "owner", "bob", " ", 0, ErrValidation},
+		{"negative duration", "owner", "bob", "spam", -time.Hour, ErrValidation},
+	}
+
+	for _, tt := range tests {
+		if err := e.BanUser("golang", tt.actor, tt.username, tt.reason, tt.duration); !errors.Is(err, tt.wantErr) {
+			t.Errorf("BanUser %s error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if _, err := e.GetBans("golang", "alice"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetBans without the access permission error = %v, want ErrForbidden", err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// ModLogAction names the kind of a moderator action in the mod log
+type ModLogAction string
+
+const (
+	LogAddModerator       ModLogAction = "add_moderator"
+	LogInviteModerator    ModLogAction = "invite_moderator"
+	LogAcceptInvite       ModLogAction = "accept_moderator_invite"
+	LogRemoveModerator    ModLogAction = "remove_moderator"
+	LogBanUser            ModLogAction = "ban_user"
+	LogUnbanUser          ModLogAction = "unban_user"
+	LogMuteUser           ModLogAction = "mute_user"
+	LogUnmuteUser         ModLogAction = "unmute_user"
+	LogApprove            ModLogAction = "approve"
+	LogRemove             ModLogAction = "remove"
+	LogIgnoreReports      ModLogAction = "ignore_reports"
+	LogSticky             ModLogAction = "sticky"
+	LogUnsticky           ModLogAction = "unsticky"
+	LogLock               ModLogAction = "lock"
+	LogUnlock             ModLogAction = "unlock"
+	LogFilter             ModLogAction = "filter"
+	LogEditAutoMod        ModLogAction = "edit_automod"
+	LogEditSettings       ModLogAction = "edit_settings"
+	LogApproveUser        ModLogAction = "approve_user"
+	LogUnapproveUser      ModLogAction = "unapprove_user"
+	LogApproveJoinRequest ModLogAction = "approve_join_request"
+	LogDenyJoinRequest    ModLogAction = "deny_join_request"
+	LogEditFlair          ModLogAction = "edit_flair"
+)
+
+// modLogActions lists every action the mod log can hold
+var modLogActions = map[ModLogAction]bool{
+	LogAddModerator:       true,
+	LogInviteModerator:    true,
+	LogAcceptInvite:       true,
+	LogRemoveModerator:    true,
+	LogBanUser:            true,
+	LogUnbanUser:          true,
+	LogMuteUser:           true,
+	LogUnmuteUser:         true,
+	LogApprove:            true,
+	LogRemove:             true,
+	LogIgnoreReports:      true,
+	LogSticky:             true,
+	LogUnsticky:           true,
+	LogLock:               true,
+	LogUnlock:             true,
+	LogFilter:             true,
+	LogEditAutoMod:        true,
+	LogEditSettings:       true,
+	LogApproveUser:        true,
+	LogUnapproveUser:      true,
+	LogApproveJoinRequest: true,
+	LogDenyJoinRequest:    true,
+	LogEditFlair:          true,
+}
+
+// ParseModLogAction converts a type query parameter into a ModLogAction. An
+// empty string matches every action.
+func ParseModLogAction(value string) (ModLogAction, error) {
+	action := ModLogAction(value)
+	if value != "" && !modLogActions[action] {
+		return "", &ValidationError{Field: "type", Reason: fmt.Sprintf("unknown mod log action %q", value)}
+	}
+	return action, nil
+}
+
+// ModLogEntry records one moderator action. Target is the username, post ID
+// or comment ID acted on.
+type ModLogEntry struct {
+	ID        string
+	Actor     string
+	Action    ModLogAction
+	Target    string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// logModAction appends an entry to the mod log. Entry IDs count up from 1
+// within each subreddit. Callers must hold subreddit.mu for writing.
+func (s *Subreddit) logModAction(actor string, action ModLogAction, target, reason string) {
+	s.ModLog = append(s.ModLog, &ModLogEntry{
+		ID:        fmt.Sprintf("modlog_%d", len(s.ModLog)+1),
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	})
+}
+
+// GetModLog returns the mod log of a subreddit, newest first. moderator and
+// action narrow the entries when not empty. Only moderators may read the log.
+func (e *RedditEngine) GetModLog(subredditName, actor, moderator string, action ModLogAction) ([]*ModLogEntry, error) {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	if err := subreddit.checkModPermission(actor, 0); err != nil {
+		return nil, err
+	}
+
+	entries := make([]*ModLogEntry, 0)
+	for i := len(subreddit.ModLog) - 1; i >= 0; i-- {
+		entry := subreddit.ModLog[i]
+		if moderator != "" && entry.Actor != moderator {
+			continue
+		}
+		if action != "" && entry.Action != action {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseModLogAction(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ModLogAction
+		wantErr error
+	}{
+		{"", "", nil},
+		{"ban_user", LogBanUser, nil},
+		{"edit_flair", LogEditFlair, nil},
+		{"delete_everything", "", ErrValidation},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseModLogAction(tt.value)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseModLogAction(%q) = %q, %v, want %q, %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestModLog(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "bob", "golang", "spam")
+
+	actions := []func() error{
+		func() error { return e.AddModerator("golang", "owner", "alice", PermPosts|PermAccess) },
+		func() error { return e.ModeratePost(post.ID, "alice", ModRemove, "rule 1") },
+		func() error { return e.BanUser("golang", "alice", "bob", "spammer", time.Hour) },
+		func() error { return e.UnbanUser("golang", "owner", "bob") },
+	}
+	for i, action := range actions {
+		if err := action(); err != nil {
+			t.Fatalf("action %d: %v", i, err)
+		}
+	}
+	// Refused actions leave no entry
+	if err := e.BanUser("golang", "bob", "alice", "revenge", 0); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("BanUser by a non-moderator error = %v, want ErrForbidden", err)
+	}
+
+	type entry struct {
+		Actor  string
+		Action ModLogAction
+		Target string
+		Reason string
+	}
+	tests := []struct {
+		name      string
+		moderator string
+		action    ModLogAction
+		want      []entry
+	}{
+		{"everything", "", "", []entry{
+			{"owner", LogUnbanUser, "bob", ""},
+			{"alice", LogBanUser, "bob", "spammer"},
+			{"alice", LogRemove, post.ID, "rule 1"},
+			{"owner", LogAddModerator, "alice", "posts,access"},
+		}},
+		{"by moderator", "alice", "", []entry{
+			{"alice", LogBanUser, "bob", "spammer"},
+			{"alice", LogRemove, post.ID, "rule 1"},
+		}},
+		{"by action", "", LogRemove, []entry{
+			{"alice", LogRemove, post.ID, "rule 1"},
+		}},
+		{"by moderator and action", "owner", LogBanUser, []entry{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := e.GetModLog("golang", "alice", tt.moderator, tt.action)
+			if err != nil {
+				t.Fatalf("GetModLog: %v", err)
+			}
+			got := make([]entry, len(entries))
+			for i, logged := range entries {
+				got[i] = entry{logged.Actor, logged.Action, logged.Target, logged.Reason}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetModLog = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	entries, err := e.GetModLog("golang", "owner", "", "")
+	if err != nil {
+		t.Fatalf("GetModLog: %v", err)
+	}
+	if first, last := entries[len(entries)-1], entries[0]; first.ID != "modlog_1" || last.ID != "modlog_4" {
+		t.Errorf("entry IDs run from %s to %s, want modlog_1 to modlog_4", first.ID, last.ID)
+	}
+	if _, err := e.GetModLog("golang", "bob", "", ""); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetModLog by a non-moderator error = %v, want ErrForbidden", err)
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// removedMarker replaces the author and text of removed content shown to
+// users who cannot moderate it
+const removedMarker = "[removed]"
+
+// ModAction is a moderator decision on a post or comment
+type ModAction string
+
+const (
+	ModApprove       ModAction = "approve"        // clear reports and keep the item
+	ModRemove        ModAction = "remove"         // hide the item from ordinary users
+	ModIgnoreReports ModAction = "ignore_reports" // keep future reports out of the queue
+)
+
+// ParseModAction converts an action name into a ModAction
+func ParseModAction(value string) (ModAction, error) {
+	switch action := ModAction(value); action {
+	case ModApprove, ModRemove, ModIgnoreReports:
+		return action, nil
+	}
+	return "", &ValidationError{Field: "action", Reason: fmt.Sprintf("unknown moderator action %q", value)}
+}
+
+// Report is a user flagging a post or comment for the moderators
+type Report struct {
+	Reporter  string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// ModStatus is the moderation state shared by posts and comments. Its
+// fields are guarded by the lock of the item embedding it.
+type ModStatus struct {
+	Reports       []*Report
+	IgnoreReports bool   // reports are still recorded but do not queue the item
+	Filtered      bool   // held for review by automatic moderation
+	Removed       bool   // hidden from everyone but the moderators
+	RemovedBy     string // moderator who removed the item
+	ApprovedBy    string // moderator who last approved the item
+}
+
+// inQueue reports whether the item waits for a moderator decision
+func (m *ModStatus) inQueue() bool {
+	if m.Removed {
+		return false
+	}
+	return m.Filtered || (len(m.Reports) > 0 && !m.IgnoreReports)
+}
+
+// hidden reports whether ordinary users should not see the item
+func (m *ModStatus) hidden() bool {
+	return m.Removed || m.Filtered
+}
+
+func (m *ModStatus) addReport(reporter, reason string) error {
+	for _, report := range m.Reports {
+		if report.Reporter == reporter {
+			return newError(ErrAlreadyExists, "%s has already reported this", reporter)
+		}
+	}
+	m.Reports = append(m.Reports, &Report{
+		Reporter:  reporter,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+func (m *ModStatus) apply(action ModAction, actor string) {
+	switch action {
+	case ModApprove:
+		m.Removed = false
+		m.RemovedBy = ""
+		m.Filtered = false
+		m.Reports = nil
+		m.ApprovedBy = actor
+	case ModRemove:
+		m.Removed = true
+		m.RemovedBy = actor
+		m.Filtered = false
+		m.ApprovedBy = ""
+	case ModIgnoreReports:
+		m.IgnoreReports = true
+	}
+}
+
+// ModQueueItem is a post or comment waiting for a moderator. Exactly one of
+// Post and Comment is set.
+type ModQueueItem struct {
+	Post    *Post
+	Comment *Comment
+}
+
+// ReportPost flags a post for the moderators of its subreddit. Each user can
+// report a post once, and only in subreddits they can see.
+func (e *RedditEngine) ReportPost(postID, reporter, reason string) error {
+	if err := e.checkNotSuspended(reporter); err != nil {
+		return err
+	}
+
+	if err := requireText("reason", reason); err != nil {
+		return err
+	}
+
+	var post *Post
+	e.mu.RLock()
+	_, err := e.lookupUser(reporter)
+	if err == nil {
+		post, err = e.lookupPost(postID)
+	}
+	if err == nil {
+		err = e.checkCanViewPost(post, reporter)
+	}
+	e.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	post.mu.Lock()
+	defer post.mu.Unlock()
+
+	if post.Deleted {
+		return newError(ErrConflict, "post has been deleted")
+	}
+	return post.addReport(reporter, reason)
+}
+
+// ReportComment flags a comment with the same rules as ReportPost
+func (e *RedditEngine) ReportComment(commentID, reporter, reason string) error {
+	if err := e.checkNotSuspended(reporter); err != nil {
+		return err
+	}
+
+	if err := requireText("reason", reason); err != nil {
+		return err
+	}
+
+	var comment *Comment
+	e.mu.RLock()
+	_, err := e.lookupUser(reporter)
+	if err == nil {
+		comment, err = e.lookupComment(commentID)
+	}
+	if err == nil {
+		var post *Post
+		if post, err = e.lookupPost(comment.PostID); err == nil {
+			err = e.checkCanViewPost(post, reporter)
+		}
+	}
+	e.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	comment.mu.Lock()
+	defer comment.mu.Unlock()
+
+	if comment.Deleted {
+		return newError(ErrConflict, "comment has been deleted")
+	}
+	return comment.addReport(reporter, reason)
+}
+
+// modActionLog maps moderator decisions to their mod log actions
+var modActionLog = map[ModAction]ModLogAction{
+	ModApprove:       LogApprove,
+	ModRemove:        LogRemove,
+	ModIgnoreReports: LogIgnoreReports,
+}
+
+// ModeratePost applies a moderator action to a post and records it in the
+// mod log with an optional reason. Needs the posts permission in the post's
+// subreddit.
+func (e *RedditEngine) ModeratePost(postID, actor string, action ModAction, reason string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	post, err := e.GetPost(postID)
+	if err != nil {
+		return err
+	}
+
+	return e.moderateItem(post.Subreddit, actor, action, postID, reason, func(subreddit *Subreddit) {
+		// Removed posts give up their sticky slot
+		if action == ModRemove {
+			subreddit.unsticky(post)
+		}
+
+		post.mu.Lock()
+		post.apply(action, actor)
+		post.mu.Unlock()
+	})
+}
+
+// ModerateComment applies a moderator action to a comment with the same
+// rules as ModeratePost
+func (e *RedditEngine) ModerateComment(commentID, actor string, action ModAction, reason string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	e.mu.RLock()
+	comment, err := e.lookupComment(commentID)
+	var post *Post
+	if err == nil {
+		post, err = e.lookupPost(comment.PostID)
+	}
+	e.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	return e.moderateItem(post.Subreddit, actor, action, commentID, reason, func(*Subreddit) {
+		comment.mu.Lock()
+		comment.apply(action, actor)
+		comment.mu.Unlock()
+	})
+}
+
+// moderateItem checks the posts permission, runs apply and logs the action
+// while holding the subreddit lock, so the log follows the order of changes
+func (e *RedditEngine) moderateItem(subredditName, actor string, action ModAction, target, reason string, apply func(*Subreddit)) error {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermPosts); err != nil {
+		return err
+	}
+	apply(subreddit)
+	subreddit.logModAction(actor, modActionLog[action], target, reason)
+	return nil
+}
+
+// GetModQueue returns the reported and filtered posts and comments of a
+// subreddit that still wait for a decision, newest first. Needs the posts
+// permission.
+func (e *RedditEngine) GetModQueue(subredditName, actor string) ([]*ModQueueItem, error) {
+	subreddit, err := e.GetSubreddit(subredditName)
+	if err != nil {
+		return nil, err
+	}
+
+	subreddit.mu.RLock()
+	if err := subreddit.checkModPermission(actor, PermPosts); err != nil {
+		subreddit.mu.RUnlock()
+		return nil, err
+	}
+	posts := make([]*Post, len(subreddit.Posts))
+	copy(posts, subreddit.Posts)
+	subreddit.mu.RUnlock()
+
+	type queuedItem struct {
+		item      *ModQueueItem
+		createdAt time.Time
+	}
+
+	var queue []queuedItem
+	var collect func(comments []*Comment)
+	collect = func(comments []*Comment) {
+		for _, comment := range comments {
+			comment.mu.RLock()
+			if !comment.Deleted && comment.inQueue() {
+				queue = append(queue, queuedItem{&ModQueueItem{Comment: comment}, comment.CreatedAt})
+			}
+			children := comment.Children
+			comment.mu.RUnlock()
+			collect(children)
+		}
+	}
+
+	for _, post := range posts {
+		post.mu.RLock()
+		if !post.Deleted && post.inQueue() {
+			queue = append(queue, queuedItem{&ModQueueItem{Post: post}, post.CreatedAt})
+		}
+		comments := post.Comments
+		post.mu.RUnlock()
+		collect(comments)
+	}
+
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].createdAt.After(queue[j].createdAt)
+	})
+
+	items := make([]*ModQueueItem, len(queue))
+	for i, entry := range queue {
+		items[i] = entry.item
+	}
+	return items, nil
+}
+
+// visiblePosts drops the removed and filtered posts viewer cannot moderate
+func (e *RedditEngine) visiblePosts(posts []*Post, viewer string) []*Post {
+	moderates := make(map[string]bool)
+	visible := make([]*Post, 0, len(posts))
+	for _, post := range posts {
+		post.mu.RLock()
+		hidden := post.hidden()
+		post.mu.RUnlock()
+
+		if hidden {
+			canSee, checked := moderates[post.Subreddit]
+			if !checked {
+				canSee = e.HasModPermission(post.Subreddit, viewer, PermPosts)
+				moderates[post.Subreddit] = canSee
+			}
+			if !canSee {
+				continue
+			}
+		}
+		visible = append(visible, post)
+	}
+	return visible
+}
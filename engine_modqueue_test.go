@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// queueIDs returns the IDs of the posts and comments in a mod queue
+func queueIDs(items []*ModQueueItem) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		if item.Post != nil {
+			ids[i] = item.Post.ID
+		} else {
+			ids[i] = item.Comment.ID
+		}
+	}
+	return ids
+}
+
+func TestParseModAction(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ModAction
+		wantErr error
+	}{
+		{"approve", ModApprove, nil},
+		{"remove", ModRemove, nil},
+		{"ignore_reports", ModIgnoreReports, nil},
+		{"", "", ErrValidation},
+		{"spam", "", ErrValidation},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseModAction(tt.value)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseModAction(%q) = %q, %v, want %q, %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestReports(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob", "outsider")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	mustCreateSubreddit(t, e, "secret", "owner", SubredditPrivate)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+	private := mustCreatePost(t, e, "owner", "secret", "plans")
+	comment, err := e.AddComment("first!", "owner", post.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	privateComment, err := e.AddComment("hush", "owner", private.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment(private): %v", err)
+	}
+	deleted := mustCreatePost(t, e, "owner", "golang", "gone")
+	if err := e.DeletePost(deleted.ID, "owner"); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+
+	// The reports are filed in order
+	tests := []struct {
+		name    string
+		report  func() error
+		wantErr error
+	}{
+		{"post", func() error { return e.ReportPost(post.ID, "alice", "spam") }, nil},
+		{"second reporter", func() error { return e.ReportPost(post.ID, "bob", "off topic") }, nil},
+		{"repeat report", func() error { return e.ReportPost(post.ID, "alice", "really spam") }, ErrAlreadyExists},
+		{"without a reason", func() error { return e.ReportPost(post.ID, "outsider", " ") }, ErrValidation},
+		{"unknown post", func() error { return e.ReportPost("t3_missing", "alice", "spam") }, ErrNotFound},
+		{"unknown reporter", func() error { return e.ReportPost(post.ID, "ghost", "spam") }, ErrNotFound},
+		{"deleted post", func() error { return e.ReportPost(deleted.ID, "alice", "spam") }, ErrConflict},
+		{"post in an unseen subreddit", func() error { return e.ReportPost(private.ID, "outsider", "spam") }, ErrForbidden},
+		{"comment", func() error { return e.ReportComment(comment.ID, "alice", "rude") }, nil},
+		{"repeat comment report", func() error { return e.ReportComment(comment.ID, "alice", "rude") }, ErrAlreadyExists},
+		{"comment in an unseen subreddit", func() error { return e.ReportComment(privateComment.ID, "outsider", "rude") }, ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		if err := tt.report(); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if len(post.Reports) != 2 || post.Reports[0].Reporter != "alice" || post.Reports[1].Reason != "off topic" {
+		t.Errorf("post has %d reports, want alice's and bob's", len(post.Reports))
+	}
+	if len(private.Reports) != 0 || len(privateComment.Reports) != 0 {
+		t.Error("reports from a user who cannot see the subreddit were recorded")
+	}
+}
+
+func TestModQueue(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	reported := mustCreatePost(t, e, "owner", "golang", "reported")
+	mustCreatePost(t, e, "owner", "golang", "quiet")
+	comment, err := e.AddComment("rude", "bob", reported.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	if err := e.ReportPost(reported.ID, "alice", "spam"); err != nil {
+		t.Fatalf("ReportPost: %v", err)
+	}
+	if err := e.ReportComment(comment.ID, "alice", "rude"); err != nil {
+		t.Fatalf("ReportComment: %v", err)
+	}
+
+	if _, err := e.GetModQueue("golang", "alice"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetModQueue by a non-moderator error = %v, want ErrForbidden", err)
+	}
+	if err := e.ModeratePost(reported.ID, "alice", ModRemove, ""); !errors.Is(err, ErrForbidden) {
+		t.Errorf("ModeratePost by a non-moderator error = %v, want ErrForbidden", err)
+	}
+
+	// The decisions are made in order; the queue lists the newest item first
+	tests := []struct {
+		name      string
+		moderate  func() error
+		wantQueue []string
+	}{
+		{"reported items", func() error { return nil }, []string{comment.ID, reported.ID}},
+		{"ignore the comment's reports", func() error {
+			return e.ModerateComment(comment.ID, "owner", ModIgnoreReports, "")
+		}, []string{reported.ID}},
+		{"new report on an ignored comment", func() error {
+			return e.ReportComment(comment.ID, "owner", "still rude")
+		}, []string{reported.ID}},
+		{"approve the post", func() error {
+			return e.ModeratePost(reported.ID, "owner", ModApprove, "")
+		}, []string{}},
+		{"report the approved post again", func() error {
+			return e.ReportPost(reported.ID, "bob", "spam")
+		}, []string{reported.ID}},
+		{"remove the post", func() error {
+			return e.ModeratePost(reported.ID, "owner", ModRemove, "rule 1")
+		}, []string{}},
+	}
+
+	for _, tt := range tests {
+		if err := tt.moderate(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		queue, err := e.GetModQueue("golang", "owner")
+		if err != nil {
+			t.Fatalf("%s: GetModQueue: %v", tt.name, err)
+		}
+		if got := queueIDs(queue); !reflect.DeepEqual(got, tt.wantQueue) {
+			t.Errorf("%s: GetModQueue = %v, want %v", tt.name, got, tt.wantQueue)
+		}
+	}
+
+	if !reported.Removed || reported.RemovedBy != "owner" {
+		t.Errorf("post removed %v by %q, want removed by owner", reported.Removed, reported.RemovedBy)
+	}
+}
+
+func TestRemovedPostVisibility(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	removed := mustCreatePost(t, e, "alice", "golang", "removed")
+	kept := mustCreatePost(t, e, "alice", "golang", "kept")
+	if err := e.ModeratePost(removed.ID, "owner", ModRemove, "rule 1"); err != nil {
+		t.Fatalf("ModeratePost: %v", err)
+	}
+
+	tests := []struct {
+		viewer string
+		want   []string
+	}{
+		{"owner", []string{kept.ID, removed.ID}},
+		{"alice", []string{kept.ID}},
+		{"", []string{kept.ID}},
+	}
+	for _, tt := range tests {
+		posts, err := e.GetSubredditPosts("golang", tt.viewer, SortNew, TimeAll)
+		if err != nil {
+			t.Fatalf("GetSubredditPosts(viewer %q): %v", tt.viewer, err)
+		}
+		if got := postIDs(posts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetSubredditPosts(viewer %q) = %v, want %v", tt.viewer, got, tt.want)
+		}
+	}
+
+	// Approving restores the post for everyone
+	if err := e.ModeratePost(removed.ID, "owner", ModApprove, ""); err != nil {
+		t.Fatalf("ModeratePost(approve): %v", err)
+	}
+	posts, err := e.GetSubredditPosts("golang", "alice", SortNew, TimeAll)
+	if err != nil {
+		t.Fatalf("GetSubredditPosts: %v", err)
+	}
+	if got, want := postIDs(posts), []string{kept.ID, removed.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetSubredditPosts after approval = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 6
+	DefaultPollDuration = 3 * 24 * time.Hour
+	maxPollDuration     = 7 * 24 * time.Hour
+)
+
+// Poll is the survey of a poll post. Every user has one vote, which they may
+// change until the poll closes. Guarded by the mutex of its post.
+type Poll struct {
+	Options  []string
+	ClosesAt time.Time
+	Votes    map[string]int // voter -> option index
+}
+
+// PollResults is a poll as one viewer sees it. Tallies stay hidden until the
+// viewer has voted or the poll has closed.
+type PollResults struct {
+	Options    []string
+	Tallies    []int // votes per option, nil while hidden
+	TotalVotes int
+	Choice     int // the viewer's option, or -1
+	ClosesAt   time.Time
+	Closed     bool
+}
+
+// newPoll validates the options and duration of a new poll. A zero duration
+// selects DefaultPollDuration.
+func newPoll(options []string, duration time.Duration, now time.Time) (*Poll, error) {
+	if len(options) < minPollOptions || len(options) > maxPollOptions {
+		return nil, &ValidationError{Field: "poll options", Reason: fmt.Sprintf("a poll needs %d to %d options", minPollOptions, maxPollOptions)}
+	}
+	if duration == 0 {
+		duration = DefaultPollDuration
+	}
+	if duration < 0 || duration > maxPollDuration {
+		return nil, &ValidationError{Field: "poll duration", Reason: fmt.Sprintf("must be between 0 and %s", maxPollDuration)}
+	}
+
+	seen := make(map[string]bool)
+	poll := &Poll{
+		ClosesAt: now.Add(duration),
+		Votes:    make(map[string]int),
+	}
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if err := requireText("poll option", option); err != nil {
+			return nil, err
+		}
+		if seen[strings.ToLower(option)] {
+			return nil, &ValidationError{Field: "poll options", Reason: fmt.Sprintf("%q is listed twice", option)}
+		}
+		seen[strings.ToLower(option)] = true
+		poll.Options = append(poll.Options, option)
+	}
+	return poll, nil
+}
+
+// Closed reports whether voting has ended
+func (p *Poll) Closed(now time.Time) bool {
+	return !now.Before(p.ClosesAt)
+}
+
+// results returns the poll as viewer sees it. Callers must hold the post's
+// mu.
+func (p *Poll) results(viewer string, now time.Time) *PollResults {
+	results := &PollResults{
+		Options:    append([]string(nil), p.Options...),
+		TotalVotes: len(p.Votes),
+		Choice:     -1,
+		ClosesAt:   p.ClosesAt,
+		Closed:     p.Closed(now),
+	}
+	choice, voted := p.Votes[viewer]
+	if voted {
+		results.Choice = choice
+	}
+	if voted || results.Closed {
+		results.Tallies = make([]int, len(p.Options))
+		for _, option := range p.Votes {
+			results.Tallies[option]++
+		}
+	}
+	return results
+}
+
+// VotePoll records voter's choice of option, counted from zero, in a poll
+// post, replacing any earlier vote. Votes are refused once the poll has
+// closed or the post's votes are frozen.
+func (e *RedditEngine) VotePoll(postID, voter string, option int) (*PollResults, error) {
+	if err := e.checkNotSuspended(voter); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	_, err := e.lookupUser(voter)
+	var post *Post
+	if err == nil {
+		post, err = e.lookupPost(postID)
+	}
+	if err == nil {
+		err = e.checkCanVote(voter, post.Subreddit)
+	}
+	e.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	post.mu.Lock()
+	defer post.mu.Unlock()
+
+	now := time.Now()
+	switch {
+	case post.Poll == nil:
+		return nil, &NotFoundError{Kind: "poll", ID: postID}
+	case post.Deleted:
+		return nil, newError(ErrConflict, "post has been deleted")
+	case post.VotesFrozen:
+		return nil, newError(ErrConflict, "votes on this post are frozen")
+	case post.Poll.Closed(now):
+		return nil, newError(ErrConflict, "the poll closed at %s", post.Poll.ClosesAt.Format(time.RFC3339))
+	case option < 0 || option >= len(post.Poll.Options):
+		return nil, &ValidationError{Field: "option", Reason: fmt.Sprintf("%d is not between 0 and %d", option, len(post.Poll.Options)-1)}
+	}
+
+	post.Poll.Votes[voter] = option
+	return post.Poll.results(voter, now), nil
+}
+
+// GetPollResults returns the poll of a post as viewer sees it
+func (e *RedditEngine) GetPollResults(postID, viewer string) (*PollResults, error) {
+	post, err := e.GetPost(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	post.mu.RLock()
+	defer post.mu.RUnlock()
+
+	if post.Poll == nil {
+		return nil, &NotFoundError{Kind: "poll", ID: postID}
+	}
+	return post.Poll.results(viewer, time.Now()), nil
+}
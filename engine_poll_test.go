@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewPoll(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name         string
+		options      []string
+		duration     time.Duration
+		wantErr      error
+		wantOptions  []string
+		wantClosesAt time.Time
+	}{
+		{"default duration", []string{"yes", "no"}, 0, nil, []string{"yes", "no"}, now.Add(DefaultPollDuration)},
+		{"longest duration", []string{"a", "b", "c"}, maxPollDuration, nil, []string{"a", "b", "c"}, now.Add(maxPollDuration)},
+		{"trimmed options", []string{" tabs ", "spaces"}, time.Hour, nil, []string{"tabs", "spaces"}, now.Add(time.Hour)},
+		{"one option", []string{"yes"}, 0, ErrValidation, nil, time.Time{}},
+		{"seven options", []string{"1", "2", "3", "4", "5", "6", "7"}, 0, ErrValidation, nil, time.Time{}},
+		{"duplicate options", []string{"Yes", "yes "}, 0, ErrValidation, nil, time.Time{}},
+		{"blank option", []string{"yes", " "}, 0, ErrValidation, nil, time.Time{}},
+		{"negative duration", []string{"yes", "no"}, -time.Hour, ErrValidation, nil, time.Time{}},
+		{"too long", []string{"yes", "no"}, maxPollDuration + time.Hour, ErrValidation, nil, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poll, err := newPoll(tt.options, tt.duration, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("newPoll error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(poll.Options, tt.wantOptions) || !poll.ClosesAt.Equal(tt.wantClosesAt) {
+				t.Errorf("newPoll = options %q closing %v, want %q closing %v", poll.Options, poll.ClosesAt, tt.wantOptions, tt.wantClosesAt)
+			}
+		})
+	}
+}
+
+func TestVotePoll(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post, err := e.SubmitPost("owner", "golang", PostSubmission{
+		Kind:        PostPoll,
+		Title:       "Tabs or spaces?",
+		PollOptions: []string{"tabs", "spaces", "gofmt decides"},
+	})
+	if err != nil {
+		t.Fatalf("SubmitPost: %v", err)
+	}
+
+	// The votes are cast in order; bob never votes
+	tests := []struct {
+		name       string
+		voter      string
+		option     int
+		wantErr    error
+		wantTallies  []int
+		wantChoice int
+		wantTotal  int
+	}{
+		{"first vote", "alice", 0, nil, []int{1, 0, 0}, 0, 1},
+		{"changed vote", "alice", 2, nil, []int{0, 0, 1}, 2, 1},
+		{"second voter", "owner", 2, nil, []int{0, 0, 2}, 2, 2},
+		{"option out of range", "alice", 3, ErrValidation, nil, 0, 2},
+		{"negative option", "alice", -1, ErrValidation, nil, 0, 2},
+		{"unknown voter", "ghost", 0, ErrNotFound, nil, 0, 2},
+	}
+
+	for _, tt := range tests {
+		results, err := e.VotePoll(post.ID, tt.voter, tt.option)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: VotePoll error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if err == nil && (!reflect.DeepEqual(results.Tallies, tt.wantTallies) || results.Choice != tt.wantChoice) {
+			t.Errorf("%s: VotePoll = tallies %v choice %d, want %v %d", tt.name, results.Tallies, results.Choice, tt.wantTallies, tt.wantChoice)
+		}
+
+		// Tallies stay hidden from users who have not voted
+		seen, err := e.GetPollResults(post.ID, "bob")
+		if err != nil {
+			t.Fatalf("%s: GetPollResults: %v", tt.name, err)
+		}
+		if seen.Tallies != nil || seen.Choice != -1 || seen.TotalVotes != tt.wantTotal {
+			t.Errorf("%s: bob sees tallies %v choice %d total %d, want hidden tallies and %d votes",
+				tt.name, seen.Tallies, seen.Choice, seen.TotalVotes, tt.wantTotal)
+		}
+	}
+
+	// Closing the poll reveals the tallies and ends voting
+	post.Poll.ClosesAt = time.Now().Add(-time.Second)
+	seen, err := e.GetPollResults(post.ID, "bob")
+	if err != nil {
+		t.Fatalf("GetPollResults: %v", err)
+	}
+	if !seen.Closed || !reflect.DeepEqual(seen.Tallies, []int{0, 0, 2}) {
+		t.Errorf("closed poll = closed %v tallies %v, want closed with [0 0 2]", seen.Closed, seen.Tallies)
+	}
+	if _, err := e.VotePoll(post.ID, "bob", 0); !errors.Is(err, ErrConflict) {
+		t.Errorf("VotePoll on a closed poll error = %v, want ErrConflict", err)
+	}
+}
+
+func TestVotePollErrors(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	e.SetRateLimits(SimulationRateLimits)
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	poll := func() *Post {
+		t.Helper()
+		post, err := e.SubmitPost("owner", "golang", PostSubmission{Kind: PostPoll, Title: "poll", PollOptions: []string{"yes", "no"}})
+		if err != nil {
+			t.Fatalf("SubmitPost: %v", err)
+		}
+		return post
+	}
+	text := mustCreatePost(t, e, "owner", "golang", "no poll here")
+	frozen := poll()
+	if err := e.LockPost(frozen.ID, "owner", true); err != nil {
+		t.Fatalf("LockPost: %v", err)
+	}
+	deleted := poll()
+	if err := e.DeletePost(deleted.ID, "owner"); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		postID  string
+		wantErr error
+	}{
+		{"not a poll", text.ID, ErrNotFound},
+		{"unknown post", "post_missing", ErrNotFound},
+		{"frozen votes", frozen.ID, ErrConflict},
+		{"deleted poll", deleted.ID, ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		if _, err := e.VotePoll(tt.postID, "alice", 0); !errors.Is(err, tt.wantErr) {
+			t.Errorf("VotePoll %s error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if _, err := e.GetPollResults(text.ID, "alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetPollResults on a text post error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PostKind is what a post links to besides its title
+type PostKind string
+
+const (
+	PostSelf  PostKind = "self"  // text in Content
+	PostLink  PostKind = "link"  // a URL on another site
+	PostImage PostKind = "image" // an image in the media store
+	PostPoll  PostKind = "poll"  // a survey users vote in
+
+	// PostCrosspost shares another post in CrosspostOf. Crossposts are made
+	// with CrosspostPost, so ParsePostKind does not accept this kind.
+	PostCrosspost PostKind = "crosspost"
+)
+
+// ParsePostKind converts a kind name into a PostKind. An empty string selects
+// PostSelf.
+func ParsePostKind(value string) (PostKind, error) {
+	switch kind := PostKind(value); kind {
+	case "":
+		return PostSelf, nil
+	case PostSelf, PostLink, PostImage, PostPoll:
+		return kind, nil
+	}
+	return "", &ValidationError{Field: "kind", Reason: fmt.Sprintf("unknown post kind %q", value)}
+}
+
+// PostSubmission is a new post. Content is optional text for every kind;
+// URL is required for link posts, Image for image posts and PollOptions for
+// polls. A zero PollDuration selects DefaultPollDuration. FlairTemplateID
+// picks a post flair, and FlairText replaces its text when it is editable.
+type PostSubmission struct {
+	Kind            PostKind
+	Title           string
+	Content         string
+	URL             string
+	Image           []byte
+	PollOptions     []string
+	PollDuration    time.Duration
+	FlairTemplateID string
+	FlairText       string
+}
+
+// SetMediaStore sets where uploaded images are kept. Without a store, image
+// posts are rejected.
+func (e *RedditEngine) SetMediaStore(store *MediaStore) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.media = store
+}
+
+// Media returns the store of uploaded images, or nil when uploads are off
+func (e *RedditEngine) Media() *MediaStore {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.media
+}
+
+// linkDomain returns the lower-cased host of a link post's URL without any
+// "www." prefix
+func linkDomain(link string) (string, error) {
+	parsed, err := url.Parse(link)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return "", &ValidationError{Field: "url", Reason: fmt.Sprintf("%q is not an http or https URL", link)}
+	}
+	return normalizeDomain(parsed.Hostname()), nil
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimPrefix(strings.ToLower(domain), "www.")
+}
+
+// SubmitPost creates a post of any kind. Image posts are checked against the
+// subreddit before their image is stored, and the image is deleted again if
+// the post is refused after all, so refused posts cannot fill the media
+// store.
+func (e *RedditEngine) SubmitPost(author, subredditName string, submission PostSubmission) (*Post, error) {
+	if err := e.checkNotSuspended(author); err != nil {
+		return nil, err
+	}
+
+	if err := requireText("title", submission.Title); err != nil {
+		return nil, err
+	}
+	kind, err := ParsePostKind(string(submission.Kind))
+	if err != nil {
+		return nil, err
+	}
+	if kind != PostLink && submission.URL != "" {
+		return nil, &ValidationError{Field: "url", Reason: "only link posts have a URL"}
+	}
+	if kind != PostImage && len(submission.Image) > 0 {
+		return nil, &ValidationError{Field: "image", Reason: "only image posts have an image"}
+	}
+	if kind != PostPoll && (len(submission.PollOptions) > 0 || submission.PollDuration != 0) {
+		return nil, &ValidationError{Field: "poll options", Reason: "only polls have options"}
+	}
+
+	post := &Post{
+		ID:        fmt.Sprintf("post_%d", time.Now().UnixNano()),
+		Kind:      kind,
+		Title:     submission.Title,
+		Content:   submission.Content,
+		Author:    author,
+		Subreddit: subredditName,
+		CreatedAt: time.Now(),
+		Voters:    make(map[string]int),
+		Comments:  make([]*Comment, 0),
+	}
+
+	switch kind {
+	case PostLink:
+		if post.Domain, err = linkDomain(submission.URL); err != nil {
+			return nil, err
+		}
+		post.URL = submission.URL
+	case PostImage:
+		store := e.Media()
+		if store == nil {
+			return nil, &ValidationError{Field: "kind", Reason: "image posts are not enabled"}
+		}
+		e.mu.RLock()
+		_, _, _, err = e.checkCanPublish(post, submission.FlairTemplateID, submission.FlairText)
+		e.mu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		if post.Image, err = store.Put(submission.Image); err != nil {
+			return nil, err
+		}
+	case PostPoll:
+		if post.Poll, err = newPoll(submission.PollOptions, submission.PollDuration, post.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.publishPost(post, submission.FlairTemplateID, submission.FlairText); err != nil {
+		if post.Image != nil {
+			e.discardImage(post.Image)
+		}
+		return nil, err
+	}
+	return post, nil
+}
+
+// discardImage deletes the image of a post that could not be published,
+// unless another post shows the same image. A file left behind because the
+// deletion failed only costs disk space, so the error is dropped.
+func (e *RedditEngine) discardImage(media *Media) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, post := range e.posts {
+		post.mu.RLock()
+		shared := post.Image != nil && post.Image.Hash == media.Hash
+		post.mu.RUnlock()
+		if shared {
+			return
+		}
+	}
+	if e.media != nil {
+		_ = e.media.Delete(media)
+	}
+}
+
+// publishPost adds a new post to its subreddit once the subreddit's posting
+// rules, flair requirement and rate limits allow it, then runs AutoModerator
+// on it. A crosspost is also linked to its original.
+func (e *RedditEngine) publishPost(post *Post, flairTemplateID, flairText string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	user, subreddit, flair, err := e.checkCanPublish(post, flairTemplateID, flairText)
+	if err != nil {
+		return err
+	}
+	original := post.CrosspostOf
+	if original != nil {
+		// The original may have been deleted with its subreddit meanwhile
+		if _, err := e.lookupPost(original.ID); err != nil {
+			return err
+		}
+		original.mu.Lock()
+		original.Crossposts = append(original.Crossposts, post)
+		original.mu.Unlock()
+	}
+
+	post.Flair = flair
+	e.posts[post.ID] = post
+
+	subreddit.mu.Lock()
+	subreddit.Posts = append(subreddit.Posts, post)
+	subreddit.mu.Unlock()
+
+	e.recordRateLimit(user, RateLimitPosts, subreddit)
+	e.applyAutoModerator(user, post, nil)
+	return nil
+}
+
+// checkCanPublish checks a new post against the posting rules, flair
+// requirement and rate limits of its subreddit without recording it, and
+// returns its author, its subreddit and the flair it starts with. Callers
+// must hold e.mu.
+func (e *RedditEngine) checkCanPublish(post *Post, flairTemplateID, flairText string) (*User, *Subreddit, Flair, error) {
+	user, err := e.lookupUser(post.Author)
+	if err != nil {
+		return nil, nil, Flair{}, err
+	}
+
+	subreddit, err := e.lookupSubreddit(post.Subreddit)
+	if err != nil {
+		return nil, nil, Flair{}, err
+	}
+
+	if err := e.checkCanPost(post.Author, subreddit); err != nil {
+		return nil, nil, Flair{}, err
+	}
+	subreddit.mu.RLock()
+	flair, err := subreddit.resolvePostFlair(post.Author, flairTemplateID, flairText)
+	subreddit.mu.RUnlock()
+	if err != nil {
+		return nil, nil, Flair{}, err
+	}
+	if err := e.checkRateLimit(user, RateLimitPosts, subreddit); err != nil {
+		return nil, nil, Flair{}, err
+	}
+	return user, subreddit, flair, nil
+}
+
+// GetDomainPosts returns the link posts to domain or any of its subdomains,
+// across every subreddit viewer can see, ordered by sortMode
+func (e *RedditEngine) GetDomainPosts(domain, viewer string, sortMode SortMode, window TimeWindow) ([]*Post, error) {
+	domain = normalizeDomain(domain)
+	if err := requireText("domain", domain); err != nil {
+		return nil, err
+	}
+
+	var posts []*Post
+	canView := make(map[string]bool)
+
+	e.mu.RLock()
+	for _, post := range e.posts {
+		post.mu.RLock()
+		host, subredditName := post.Domain, post.Subreddit
+		post.mu.RUnlock()
+
+		if host == "" || !matchesDomain(host, domain) {
+			continue
+		}
+
+		visible, checked := canView[subredditName]
+		if !checked {
+			if subreddit, ok := e.subreddits[subredditName]; ok {
+				subreddit.mu.RLock()
+				visible = subreddit.canView(viewer)
+				subreddit.mu.RUnlock()
+			}
+			canView[subredditName] = visible
+		}
+		if visible {
+			posts = append(posts, post)
+		}
+	}
+	e.mu.RUnlock()
+
+	return sortPosts(e.visiblePosts(posts, viewer), sortMode, window), nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testPNG returns a small PNG image
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatalf("encoding PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// newTestMediaStore gives e a media store in a temporary directory
+func newTestMediaStore(t *testing.T, e *RedditEngine) (*MediaStore, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := NewMediaStore(dir, DefaultMaxImageSize)
+	if err != nil {
+		t.Fatalf("NewMediaStore: %v", err)
+	}
+	e.SetMediaStore(store)
+	return store, dir
+}
+
+func TestParsePostKind(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    PostKind
+		wantErr error
+	}{
+		{"", PostSelf, nil},
+		{"self", PostSelf, nil},
+		{"link", PostLink, nil},
+		{"image", PostImage, nil},
+		{"poll", PostPoll, nil},
+		{"crosspost", "", ErrValidation},
+		{"video", "", ErrValidation},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePostKind(tt.value)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParsePostKind(%q) = %q, %v, want %q, %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLinkDomain(t *testing.T) {
+	tests := []struct {
+		link    string
+		want    string
+		wantErr error
+	}{
+		{"https://go.dev/blog", "go.dev", nil},
+		{"http://WWW.Example.com:8080/path?q=1", "example.com", nil},
+		{"https://blog.golang.org", "blog.golang.org", nil},
+		{"ftp://example.com/file", "", ErrValidation},
+		{"example.com", "", ErrValidation},
+		{"https://", "", ErrValidation},
+	}
+
+	for _, tt := range tests {
+		got, err := linkDomain(tt.link)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("linkDomain(%q) = %q, %v, want %q, %v", tt.link, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSubmitPostKinds(t *testing.T) {
+	e := newTestEngine(t, "alice")
+	e.SetRateLimits(SimulationRateLimits)
+	mustCreateSubreddit(t, e, "misc", "alice", SubredditPublic)
+	newTestMediaStore(t, e)
+
+	tests := []struct {
+		name       string
+		submission PostSubmission
+		wantErr    error
+		wantDomain string
+		wantImage  bool
+		wantPoll   bool
+	}{
+		{"self", PostSubmission{Title: "text", Content: "body"}, nil, "", false, false},
+		{"link", PostSubmission{Kind: PostLink, Title: "link", URL: "https://www.go.dev/doc"}, nil, "go.dev", false, false},
+		{"image", PostSubmission{Kind: PostImage, Title: "pic", Image: testPNG(t)}, nil, "", true, false},
+		{"poll", PostSubmission{Kind: PostPoll, Title: "poll", PollOptions: []string{"yes", "no"}}, nil, "", false, true},
+		{"link without a URL", PostSubmission{Kind: PostLink, Title: "link"}, ErrValidation, "", false, false},
+		{"link with a bad URL", PostSubmission{Kind: PostLink, Title: "link", URL: "javascript:alert(1)"}, ErrValidation, "", false, false},
+		{"self with a URL", PostSubmission{Title: "text", URL: "https://go.dev"}, ErrValidation, "", false, false},
+		{"self with an image", PostSubmission{Title: "text", Image: testPNG(t)}, ErrValidation, "", false, false},
+		{"link with poll options", PostSubmission{Kind: PostLink, Title: "link", URL: "https://go.dev", PollOptions: []string{"a", "b"}}, ErrValidation, "", false, false},
+		{"image without an image", PostSubmission{Kind: PostImage, Title: "pic"}, ErrValidation, "", false, false},
+		{"image that is not an image", PostSubmission{Kind: PostImage, Title: "pic", Image: []byte("plain text")}, ErrValidation, "", false, false},
+		{"unknown kind", PostSubmission{Kind: "video", Title: "clip"}, ErrValidation, "", false, false},
+		{"without a title", PostSubmission{Kind: PostLink, URL: "https://go.dev"}, ErrValidation, "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := e.SubmitPost("alice", "misc", tt.submission)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SubmitPost error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			wantKind, _ := ParsePostKind(string(tt.submission.Kind))
+			if post.Kind != wantKind || post.Domain != tt.wantDomain || (post.Image != nil) != tt.wantImage || (post.Poll != nil) != tt.wantPoll {
+				t.Errorf("SubmitPost = kind %q domain %q image %v poll %v, want %q %q %v %v",
+					post.Kind, post.Domain, post.Image != nil, post.Poll != nil, wantKind, tt.wantDomain, tt.wantImage, tt.wantPoll)
+			}
+		})
+	}
+}
+
+func TestImagePosts(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "pics", "owner", SubredditRestricted)
+
+	// Without a media store image posts are off
+	if _, err := e.SubmitPost("owner", "pics", PostSubmission{Kind: PostImage, Title: "pic", Image: testPNG(t)}); !errors.Is(err, ErrValidation) {
+		t.Errorf("SubmitPost without a media store error = %v, want ErrValidation", err)
+	}
+
+	_, dir := newTestMediaStore(t, e)
+	if _, err := e.SubmitPost("alice", "pics", PostSubmission{Kind: PostImage, Title: "pic", Image: testPNG(t)}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("SubmitPost to a restricted subreddit error = %v, want ErrForbidden", err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("a refused image post left %d files in the media store", len(files))
+	}
+
+	post, err := e.SubmitPost("owner", "pics", PostSubmission{Kind: PostImage, Title: "pic", Image: testPNG(t)})
+	if err != nil {
+		t.Fatalf("SubmitPost: %v", err)
+	}
+	if post.Image.MIMEType != "image/png" || post.Image.Width != 4 || post.Image.Height != 3 {
+		t.Errorf("Image = %+v, want a 4x3 PNG", *post.Image)
+	}
+	for _, name := range []string{post.Image.FileName(), post.Image.ThumbnailName()} {
+		file, err := e.Media().Open(name)
+		if err != nil {
+			t.Errorf("Open(%q): %v", name, err)
+			continue
+		}
+		file.Close()
+	}
+	if _, err := e.Media().Open("../secrets.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open outside the store error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMediaStoreLimits(t *testing.T) {
+	store, err := NewMediaStore(t.TempDir(), 64)
+	if err != nil {
+		t.Fatalf("NewMediaStore: %v", err)
+	}
+	big := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for i := range big.Pix {
+		big.Pix[i] = byte(i * 7)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, big); err != nil {
+		t.Fatalf("encoding PNG: %v", err)
+	}
+
+	if _, err := store.Put(buf.Bytes()); !errors.Is(err, ErrValidation) {
+		t.Errorf("Put(%d bytes) error = %v, want ErrValidation", buf.Len(), err)
+	}
+	if _, err := store.Put(nil); !errors.Is(err, ErrValidation) {
+		t.Errorf("Put(nothing) error = %v, want ErrValidation", err)
+	}
+}
+
+func TestGetDomainPosts(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	e.SetRateLimits(SimulationRateLimits)
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	mustCreateSubreddit(t, e, "secret", "owner", SubredditPrivate)
+
+	link := func(subredditName, url string) *Post {
+		t.Helper()
+		post, err := e.SubmitPost("owner", subredditName, PostSubmission{Kind: PostLink, Title: url, URL: url})
+		if err != nil {
+			t.Fatalf("SubmitPost(%q): %v", url, err)
+		}
+		return post
+	}
+	root := link("golang", "https://go.dev/doc")
+	sub := link("golang", "https://blog.go.dev/post")
+	hidden := link("secret", "https://go.dev/private")
+	link("golang", "https://notgo.dev/")
+	mustCreatePost(t, e, "owner", "golang", "self post")
+
+	tests := []struct {
+		domain string
+		viewer string
+		want   []string
+	}{
+		{"go.dev", "alice", []string{sub.ID, root.ID}},
+		{"WWW.GO.DEV", "alice", []string{sub.ID, root.ID}},
+		{"blog.go.dev", "alice", []string{sub.ID}},
+		{"go.dev", "owner", []string{hidden.ID, sub.ID, root.ID}},
+		{"example.com", "alice", []string{}},
+	}
+
+	for _, tt := range tests {
+		posts, err := e.GetDomainPosts(tt.domain, tt.viewer, SortNew, TimeAll)
+		if err != nil {
+			t.Fatalf("GetDomainPosts(%q): %v", tt.domain, err)
+		}
+		if got := postIDs(posts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetDomainPosts(%q, viewer %q) = %v, want %v", tt.domain, tt.viewer, got, tt.want)
+		}
+	}
+	if _, err := e.GetDomainPosts(" ", "alice", SortNew, TimeAll); !errors.Is(err, ErrValidation) {
+		t.Errorf("GetDomainPosts(blank) error = %v, want ErrValidation", err)
+	}
+}
+
+func TestDiscardImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		shared   bool
+		wantKept bool
+	}{
+		{"unused image", false, false},
+		{"image of another post", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, "alice")
+			mustCreateSubreddit(t, e, "pics", "alice", SubredditPublic)
+			store, dir := newTestMediaStore(t, e)
+
+			media, err := store.Put(testPNG(t))
+			if err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if tt.shared {
+				if _, err := e.SubmitPost("alice", "pics", PostSubmission{Kind: PostImage, Title: "Pic", Image: testPNG(t)}); err != nil {
+					t.Fatalf("SubmitPost: %v", err)
+				}
+			}
+
+			e.discardImage(media)
+			for _, name := range []string{media.FileName(), media.ThumbnailName()} {
+				_, err := os.Stat(filepath.Join(dir, name))
+				if kept := err == nil; kept != tt.wantKept {
+					t.Errorf("%s kept = %v, want %v", name, kept, tt.wantKept)
+				}
+			}
+		})
+	}
+}
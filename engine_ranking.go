@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// SortMode selects how a listing of posts is ordered
+type SortMode string
+
+const (
+	SortHot           SortMode = "hot"
+	SortTop           SortMode = "top"
+	SortNew           SortMode = "new"
+	SortControversial SortMode = "controversial"
+	SortRising        SortMode = "rising"
+)
+
+// TimeWindow limits top and controversial listings to recent posts
+type TimeWindow string
+
+const (
+	TimeHour  TimeWindow = "hour"
+	TimeDay   TimeWindow = "day"
+	TimeWeek  TimeWindow = "week"
+	TimeMonth TimeWindow = "month"
+	TimeYear  TimeWindow = "year"
+	TimeAll   TimeWindow = "all"
+)
+
+// redditEpoch is the reference point of Reddit's hot ranking (2005-12-08)
+const redditEpoch = 1134028003
+
+// risingWindow is how far back the rising listing looks for posts
+const risingWindow = 24 * time.Hour
+
+// ParseSortMode converts a sort query parameter into a SortMode. An empty
+// string selects SortNew, the order feeds have always used.
+func ParseSortMode(value string) (SortMode, error) {
+	switch mode := SortMode(value); mode {
+	case "":
+		return SortNew, nil
+	case SortHot, SortTop, SortNew, SortControversial, SortRising:
+		return mode, nil
+	}
+	return "", &ValidationError{Field: "sort", Reason: fmt.Sprintf("unknown sort %q", value)}
+}
+
+// ParseTimeWindow converts a t query parameter into a TimeWindow. An empty
+// string selects TimeAll.
+func ParseTimeWindow(value string) (TimeWindow, error) {
+	switch window := TimeWindow(value); window {
+	case "":
+		return TimeAll, nil
+	case TimeHour, TimeDay, TimeWeek, TimeMonth, TimeYear, TimeAll:
+		return window, nil
+	}
+	return "", &ValidationError{Field: "t", Reason: fmt.Sprintf("unknown time window %q", value)}
+}
+
+// cutoff returns the oldest creation time inside the window, or the zero
+// time for TimeAll
+func (t TimeWindow) cutoff(now time.Time) time.Time {
+	switch t {
+	case TimeHour:
+		return now.Add(-time.Hour)
+	case TimeDay:
+		return now.AddDate(0, 0, -1)
+	case TimeWeek:
+		return now.AddDate(0, 0, -7)
+	case TimeMonth:
+		return now.AddDate(0, -1, 0)
+	case TimeYear:
+		return now.AddDate(-1, 0, 0)
+	}
+	return time.Time{}
+}
+
+// hotScore is Reddit's hot ranking: the log of the score plus a bonus that
+// grows by one every 12.5 hours, so newer posts need fewer votes to rank.
+func hotScore(upvotes, downvotes int, createdAt time.Time) float64 {
+	score := float64(upvotes - downvotes)
+	order := math.Log10(math.Max(math.Abs(score), 1))
+	sign := 0.0
+	if score > 0 {
+		sign = 1
+	} else if score < 0 {
+		sign = -1
+	}
+	seconds := float64(createdAt.Unix() - redditEpoch)
+	return sign*order + seconds/45000
+}
+
+// controversyScore favours posts with many votes split evenly between up and
+// down. Posts without votes in both directions are not controversial.
+func controversyScore(upvotes, downvotes int) float64 {
+	if upvotes <= 0 || downvotes <= 0 {
+		return 0
+	}
+	magnitude := float64(upvotes + downvotes)
+	balance := float64(downvotes) / float64(upvotes)
+	if upvotes < downvotes {
+		balance = float64(upvotes) / float64(downvotes)
+	}
+	return math.Pow(magnitude, balance)
+}
+
+// risingScore measures how quickly a recent post is gathering votes
+func risingScore(upvotes, downvotes int, createdAt, now time.Time) float64 {
+	hours := now.Sub(createdAt).Hours()
+	return float64(upvotes-downvotes) / math.Pow(hours+2, 1.5)
+}
+
+// sortPosts returns posts ordered by mode. Top and controversial listings only
+// keep posts created inside window, rising only keeps posts from the last day.
+// Ties are broken newest first, then by ID, so the order is stable.
+func sortPosts(posts []*Post, mode SortMode, window TimeWindow) []*Post {
+	now := time.Now()
+
+	type rankedPost struct {
+		post *Post
+		key  float64
+	}
+
+	var cutoff time.Time
+	switch mode {
+	case SortTop, SortControversial:
+		cutoff = window.cutoff(now)
+	case SortRising:
+		cutoff = now.Add(-risingWindow)
+	}
+
+	ranked := make([]rankedPost, 0, len(posts))
+	for _, post := range posts {
+		if post.CreatedAt.Before(cutoff) {
+			continue
+		}
+
+		post.mu.RLock()
+		entry := rankedPost{post: post}
+		switch mode {
+		case SortHot:
+			entry.key = hotScore(post.Upvotes, post.Downvotes, post.CreatedAt)
+		case SortTop:
+			entry.key = float64(post.Upvotes - post.Downvotes)
+		case SortControversial:
+			entry.key = controversyScore(post.Upvotes, post.Downvotes)
+		case SortRising:
+			entry.key = risingScore(post.Upvotes, post.Downvotes, post.CreatedAt, now)
+		}
+		post.mu.RUnlock()
+
+		ranked = append(ranked, entry)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.key != b.key {
+			return a.key > b.key
+		}
+		if !a.post.CreatedAt.Equal(b.post.CreatedAt) {
+			return a.post.CreatedAt.After(b.post.CreatedAt)
+		}
+		return a.post.ID > b.post.ID
+	})
+
+	sorted := make([]*Post, len(ranked))
+	for i, entry := range ranked {
+		sorted[i] = entry.post
+	}
+	return sorted
+}
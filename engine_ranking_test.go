@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHotScore(t *testing.T) {
+	epoch := time.Unix(redditEpoch, 0)
+
+	tests := []struct {
+		name      string
+		upvotes   int
+		downvotes int
+		createdAt time.Time
+		want      float64
+	}{
+		{"no votes at the epoch", 0, 0, epoch, 0},
+		{"score 10", 10, 0, epoch, 1},
+		{"score 100", 150, 50, epoch, 2},
+		{"score -100", 0, 100, epoch, -2},
+		{"score 1", 1, 0, epoch, 0},
+		{"12.5 hours later", 1, 0, epoch.Add(45000 * time.Second), 1},
+		{"score 10, 25 hours later", 10, 0, epoch.Add(90000 * time.Second), 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hotScore(tt.upvotes, tt.downvotes, tt.createdAt); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("hotScore(%d, %d) = %v, want %v", tt.upvotes, tt.downvotes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestControversyScore(t *testing.T) {
+	tests := []struct {
+		upvotes   int
+		downvotes int
+		want      float64
+	}{
+		{0, 0, 0},
+		{10, 0, 0},
+		{0, 10, 0},
+		{10, 10, 20},
+		{10, 5, math.Sqrt(15)},
+		{5, 10, math.Sqrt(15)},
+		{100, 1, math.Pow(101, 0.01)},
+	}
+
+	for _, tt := range tests {
+		if got := controversyScore(tt.upvotes, tt.downvotes); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("controversyScore(%d, %d) = %v, want %v", tt.upvotes, tt.downvotes, got, tt.want)
+		}
+	}
+}
+
+func TestRisingScore(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		upvotes   int
+		downvotes int
+		age       time.Duration
+		want      float64
+	}{
+		{"brand new", 10, 0, 0, 10 / math.Pow(2, 1.5)},
+		{"two hours old", 10, 2, 2 * time.Hour, 8.0 / 8},
+		{"seven hours old", 27, 0, 7 * time.Hour, 1},
+		{"negative", 0, 8, 2 * time.Hour, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := risingScore(tt.upvotes, tt.downvotes, now.Add(-tt.age), now); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("risingScore = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortPosts(t *testing.T) {
+	now := time.Now()
+	post := func(id string, upvotes, downvotes int, age time.Duration) *Post {
+		return &Post{ID: id, Upvotes: upvotes, Downvotes: downvotes, CreatedAt: now.Add(-age)}
+	}
+	posts := []*Post{
+		post("old_popular", 500, 10, 30*24*time.Hour),
+		post("day_split", 40, 38, 20*time.Hour),
+		post("hour_good", 30, 2, 30*time.Minute),
+		post("fresh", 1, 0, time.Minute),
+		post("week_split", 100, 100, 3*24*time.Hour),
+	}
+
+	tests := []struct {
+		mode   SortMode
+		window TimeWindow
+		want   []string
+	}{
+		{SortNew, TimeAll, []string{"fresh", "hour_good", "day_split", "week_split", "old_popular"}},
+		{SortTop, TimeAll, []string{"old_popular", "hour_good", "day_split", "fresh", "week_split"}},
+		{SortTop, TimeDay, []string{"hour_good", "day_split", "fresh"}},
+		{SortTop, TimeHour, []string{"hour_good", "fresh"}},
+		{SortControversial, TimeAll, []string{"week_split", "day_split", "hour_good", "old_popular", "fresh"}},
+		{SortControversial, TimeWeek, []string{"week_split", "day_split", "hour_good", "fresh"}},
+		{SortHot, TimeAll, []string{"hour_good", "fresh", "day_split", "week_split", "old_popular"}},
+		{SortRising, TimeAll, []string{"hour_good", "fresh", "day_split"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode)+"/"+string(tt.window), func(t *testing.T) {
+			if got := postIDs(sortPosts(posts, tt.mode, tt.window)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortPosts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortPostsTieBreak(t *testing.T) {
+	created := time.Now().Add(-time.Hour)
+	posts := []*Post{
+		{ID: "t3_a", Upvotes: 5, CreatedAt: created},
+		{ID: "t3_b", Upvotes: 5, CreatedAt: created.Add(time.Minute)},
+		{ID: "t3_c", Upvotes: 5, CreatedAt: created},
+	}
+
+	want := []string{"t3_b", "t3_c", "t3_a"}
+	if got := postIDs(sortPosts(posts, SortTop, TimeAll)); !reflect.DeepEqual(got, want) {
+		t.Errorf("sortPosts = %v, want %v", got, want)
+	}
+}
+
+func TestParseListingOptions(t *testing.T) {
+	sorts := []struct {
+		value   string
+		want    SortMode
+		wantErr error
+	}{
+		{"", SortNew, nil},
+		{"hot", SortHot, nil},
+		{"rising", SortRising, nil},
+		{"best", "", ErrValidation},
+	}
+	for _, tt := range sorts {
+		got, err := ParseSortMode(tt.value)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseSortMode(%q) = %q, %v, want %q, %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	windows := []struct {
+		value   string
+		want    TimeWindow
+		wantErr error
+	}{
+		{"", TimeAll, nil},
+		{"week", TimeWeek, nil},
+		{"decade", "", ErrValidation},
+	}
+	for _, tt := range windows {
+		got, err := ParseTimeWindow(tt.value)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseTimeWindow(%q) = %q, %v, want %q, %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateLimitKind names the kind of content a rate limit applies to
+type RateLimitKind string
+
+const (
+	RateLimitPosts    RateLimitKind = "posts"    // counted per subreddit
+	RateLimitComments RateLimitKind = "comments" // counted per subreddit
+	RateLimitMessages RateLimitKind = "messages" // counted across the site
+)
+
+// RateLimit allows Limit actions in any Window, or NewLimit for new and
+// low-karma accounts. A zero Limit turns the limit off; a zero NewLimit
+// falls back to Limit.
+type RateLimit struct {
+	Limit    int
+	NewLimit int
+	Window   time.Duration
+}
+
+// RateLimits configures how fast users may create content. Moderators are
+// exempt in the subreddits they moderate, and admins everywhere.
+type RateLimits struct {
+	Posts    RateLimit
+	Comments RateLimit
+	Messages RateLimit
+
+	NewAccountAge time.Duration // accounts younger than this are new
+	LowKarma      int           // accounts with less total karma are low-karma
+}
+
+// DefaultRateLimits are the limits of a new engine
+var DefaultRateLimits = RateLimits{
+	Posts:         RateLimit{Limit: 5, NewLimit: 2, Window: 10 * time.Minute},
+	Comments:      RateLimit{Limit: 30, NewLimit: 10, Window: 10 * time.Minute},
+	Messages:      RateLimit{Limit: 20, NewLimit: 5, Window: 10 * time.Minute},
+	NewAccountAge: 24 * time.Hour,
+	LowKarma:      10,
+}
+
+// SimulationRateLimits are relaxed limits for simulations and load tests,
+// whose users are registered moments before they post and comment in bursts.
+// They have no tighter limits for new accounts, so never use them for an
+// engine serving real users.
+var SimulationRateLimits = RateLimits{
+	Posts:    RateLimit{Limit: 100, Window: time.Minute},
+	Comments: RateLimit{Limit: 500, Window: time.Minute},
+	Messages: RateLimit{Limit: 100, Window: time.Minute},
+}
+
+// RateLimitError reports a user creating content faster than allowed.
+// RetryAfter is how long until the next attempt can succeed.
+type RateLimitError struct {
+	Kind       RateLimitKind
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("too many %s; try again in %s", e.Kind, e.RetryAfter.Round(time.Second))
+}
+
+// SetRateLimits replaces the content rate limits
+func (e *RedditEngine) SetRateLimits(limits RateLimits) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rateLimits = limits
+}
+
+func (l *RateLimits) limit(kind RateLimitKind) RateLimit {
+	switch kind {
+	case RateLimitPosts:
+		return l.Posts
+	case RateLimitComments:
+		return l.Comments
+	}
+	return l.Messages
+}
+
+// takeRateLimit records one action of kind by user, in subreddit when it is
+// not nil, or returns a RateLimitError when the user is over the limit.
+// Callers must hold e.mu.
+func (e *RedditEngine) takeRateLimit(user *User, kind RateLimitKind, subreddit *Subreddit) error {
+	return e.rateLimit(user, kind, subreddit, true)
+}
+
+// checkRateLimit is takeRateLimit without recording the action, for callers
+// that can still fail afterwards. They call recordRateLimit once the action
+// has succeeded, without releasing e.mu in between. Callers must hold e.mu.
+func (e *RedditEngine) checkRateLimit(user *User, kind RateLimitKind, subreddit *Subreddit) error {
+	return e.rateLimit(user, kind, subreddit, false)
+}
+
+// recordRateLimit records one action of kind by user that checkRateLimit
+// allowed. Callers must hold e.mu.
+func (e *RedditEngine) recordRateLimit(user *User, kind RateLimitKind, subreddit *Subreddit) {
+	key, limit := e.rateLimitKey(user, kind, subreddit)
+	if key == "" {
+		return
+	}
+
+	user.mu.Lock()
+	defer user.mu.Unlock()
+
+	now := time.Now()
+	user.recentActions[key] = append(user.recentInWindow(key, limit.Window, now), now)
+}
+
+// rateLimitKey returns the key actions of kind by user are counted under and
+// the limit that applies, or an empty key when user is exempt. Callers must
+// hold e.mu.
+func (e *RedditEngine) rateLimitKey(user *User, kind RateLimitKind, subreddit *Subreddit) (string, RateLimit) {
+	limit := e.rateLimits.limit(kind)
+	if limit.Limit == 0 || e.admins[user.Username] {
+		return "", limit
+	}
+
+	key := string(kind)
+	if subreddit != nil {
+		subreddit.mu.RLock()
+		moderator := subreddit.hasModPermission(user.Username, 0)
+		subreddit.mu.RUnlock()
+		if moderator {
+			return "", limit
+		}
+		key += "/" + subreddit.Name
+	}
+	return key, limit
+}
+
+// recentInWindow forgets the actions under key that fell out of window and
+// returns the rest. Callers must hold u.mu for writing.
+func (u *User) recentInWindow(key string, window time.Duration, now time.Time) []time.Time {
+	if u.recentActions == nil {
+		u.recentActions = make(map[string][]time.Time)
+	}
+	recent := u.recentActions[key]
+	for len(recent) > 0 && now.Sub(recent[0]) >= window {
+		recent = recent[1:]
+	}
+	u.recentActions[key] = recent
+	return recent
+}
+
+// rateLimit returns a RateLimitError when user is over the limit of kind,
+// and otherwise records the action when take is set. Callers must hold e.mu.
+func (e *RedditEngine) rateLimit(user *User, kind RateLimitKind, subreddit *Subreddit, take bool) error {
+	key, limit := e.rateLimitKey(user, kind, subreddit)
+	if key == "" {
+		return nil
+	}
+
+	user.mu.Lock()
+	defer user.mu.Unlock()
+
+	now := time.Now()
+	allowed := limit.Limit
+	if limit.NewLimit > 0 && (now.Sub(user.CreatedAt) < e.rateLimits.NewAccountAge || user.TotalKarma() < e.rateLimits.LowKarma) {
+		allowed = limit.NewLimit
+	}
+
+	recent := user.recentInWindow(key, limit.Window, now)
+	if len(recent) >= allowed {
+		retryAfter := recent[len(recent)-allowed].Add(limit.Window).Sub(now)
+		return &RateLimitError{Kind: kind, RetryAfter: retryAfter}
+	}
+
+	if take {
+		user.recentActions[key] = append(recent, now)
+	}
+	return nil
+}
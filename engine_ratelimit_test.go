@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testRateLimits allow three comments an hour, or one for new accounts
+var testRateLimits = RateLimits{
+	Comments:      RateLimit{Limit: 3, NewLimit: 1, Window: time.Hour},
+	Messages:      RateLimit{Limit: 2, Window: time.Hour},
+	NewAccountAge: 24 * time.Hour,
+}
+
+// age makes username's account older than the new account age
+func age(e *RedditEngine, username string) {
+	e.users[username].CreatedAt = time.Now().Add(-48 * time.Hour)
+}
+
+func TestCommentRateLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		author string
+		setup  func(t *testing.T, e *RedditEngine)
+		want   int
+	}{
+		{"new account", "alice", func(*testing.T, *RedditEngine) {}, 1},
+		{"old account", "alice", func(_ *testing.T, e *RedditEngine) { age(e, "alice") }, 3},
+		{"low karma", "alice", func(_ *testing.T, e *RedditEngine) {
+			age(e, "alice")
+			e.rateLimits.LowKarma = 10
+		}, 1},
+		{"moderator", "owner", func(*testing.T, *RedditEngine) {}, 5},
+		{"admin", "alice", func(t *testing.T, e *RedditEngine) {
+			if err := e.SetAdmin("alice", true); err != nil {
+				t.Fatalf("SetAdmin: %v", err)
+			}
+		}, 5},
+		{"no limit", "alice", func(_ *testing.T, e *RedditEngine) { e.rateLimits.Comments.Limit = 0 }, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, "owner", "alice")
+			e.SetRateLimits(testRateLimits)
+			mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+			post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+			tt.setup(t, e)
+
+			allowed := 0
+			var err error
+			for ; allowed < 5; allowed++ {
+				if _, err = e.AddComment("comment", tt.author, post.ID, ""); err != nil {
+					break
+				}
+			}
+			if allowed != tt.want {
+				t.Fatalf("%d comments allowed, want %d (last error: %v)", allowed, tt.want, err)
+			}
+			if allowed == 5 {
+				return
+			}
+
+			var limited *RateLimitError
+			if !errors.As(err, &limited) || !errors.Is(err, ErrRateLimited) {
+				t.Fatalf("AddComment = %v, want a RateLimitError", err)
+			}
+			if limited.RetryAfter <= 0 || limited.RetryAfter > time.Hour {
+				t.Errorf("RetryAfter = %v, want within the window", limited.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestCommentRateLimitPerSubreddit(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	e.SetRateLimits(testRateLimits)
+	for _, name := range []string{"golang", "rust"} {
+		mustCreateSubreddit(t, e, name, "owner", SubredditPublic)
+		post := mustCreatePost(t, e, "owner", name, "News")
+		if _, err := e.AddComment("comment", "alice", post.ID, ""); err != nil {
+			t.Errorf("first comment in %s: %v", name, err)
+		}
+	}
+}
+
+func TestFailedCommentKeepsAllowance(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	e.SetRateLimits(testRateLimits)
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+
+	parent, err := e.AddComment("parent", "owner", post.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	if err := e.DeleteComment(parent.ID, "owner"); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+
+	// alice may comment once; the refused reply must not use that up
+	if _, err := e.AddComment("reply", "alice", post.ID, parent.ID); !errors.Is(err, ErrConflict) {
+		t.Fatalf("reply to a deleted comment = %v, want %v", err, ErrConflict)
+	}
+	if _, err := e.AddComment("comment", "alice", post.ID, ""); err != nil {
+		t.Errorf("comment after a refused reply = %v, want nil", err)
+	}
+}
+
+func TestMessageRateLimitIsSiteWide(t *testing.T) {
+	e := newTestEngine(t, "alice", "bob", "carol")
+	e.SetRateLimits(testRateLimits)
+
+	tests := []struct {
+		to   string
+		want error
+	}{
+		{"bob", nil},
+		{"carol", nil},
+		{"bob", ErrRateLimited},
+		{"carol", ErrRateLimited},
+	}
+
+	for i, tt := range tests {
+		if _, err := e.SendDirectMessage("alice", tt.to, "hi"); !errors.Is(err, tt.want) {
+			t.Errorf("message %d to %s = %v, want %v", i+1, tt.to, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// SavedItem is a post or comment a user bookmarked. Exactly one of Post and
+// Comment is set.
+type SavedItem struct {
+	Post    *Post
+	Comment *Comment
+	SavedAt time.Time
+}
+
+// checkCanSave checks that username exists and can see the post a save or
+// hide acts on. commentID, when not empty, selects a comment instead.
+func (e *RedditEngine) checkCanSave(username, postID, commentID string) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if _, err := e.lookupUser(username); err != nil {
+		return err
+	}
+	if commentID != "" {
+		comment, err := e.lookupComment(commentID)
+		if err != nil {
+			return err
+		}
+		postID = comment.PostID
+	}
+	post, err := e.lookupPost(postID)
+	if err != nil {
+		return err
+	}
+	return e.checkCanViewPost(post, username)
+}
+
+// marks returns the hidden posts of the user, or the saved posts and
+// comments when hidden is false. Callers must hold u.mu.
+func (u *User) marks(hidden bool) map[string]time.Time {
+	if hidden {
+		return u.Hidden
+	}
+	return u.Saved
+}
+
+// markItem records id as saved or hidden by username, or forgets it when
+// mark is false. Marking an item again keeps the original time.
+func (e *RedditEngine) markItem(username, id string, hidden, mark bool) error {
+	e.mu.RLock()
+	user, err := e.lookupUser(username)
+	e.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	user.mu.Lock()
+	defer user.mu.Unlock()
+
+	marks := user.marks(hidden)
+	if !mark {
+		delete(marks, id)
+	} else if _, ok := marks[id]; !ok {
+		marks[id] = time.Now()
+	}
+	return nil
+}
+
+// SavePost bookmarks a post for username
+func (e *RedditEngine) SavePost(username, postID string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+	if err := e.checkCanSave(username, postID, ""); err != nil {
+		return err
+	}
+	return e.markItem(username, postID, false, true)
+}
+
+// UnsavePost removes a post from username's bookmarks
+func (e *RedditEngine) UnsavePost(username, postID string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+	return e.markItem(username, postID, false, false)
+}
+
+// SaveComment bookmarks a comment for username
+func (e *RedditEngine) SaveComment(username, commentID string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+	if err := e.checkCanSave(username, "", commentID); err != nil {
+		return err
+	}
+	return e.markItem(username, commentID, false, true)
+}
+
+// UnsaveComment removes a comment from username's bookmarks
+func (e *RedditEngine) UnsaveComment(username, commentID string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+	return e.markItem(username, commentID, false, false)
+}
+
+// HidePost keeps a post out of username's feed
+func (e *RedditEngine) HidePost(username, postID string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+	if err := e.checkCanSave(username, postID, ""); err != nil {
+		return err
+	}
+	return e.markItem(username, postID, true, true)
+}
+
+// UnhidePost puts a hidden post back in username's feed
+func (e *RedditEngine) UnhidePost(username, postID string) error {
+	if err := e.checkNotSuspended(username); err != nil {
+		return err
+	}
+	return e.markItem(username, postID, true, false)
+}
+
+// copyMarks copies the hidden or saved items of username
+func (e *RedditEngine) copyMarks(username string, hidden bool) (map[string]time.Time, error) {
+	e.mu.RLock()
+	user, err := e.lookupUser(username)
+	e.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	user.mu.RLock()
+	defer user.mu.RUnlock()
+
+	marks := make(map[string]time.Time, len(user.marks(hidden)))
+	for id, at := range user.marks(hidden) {
+		marks[id] = at
+	}
+	return marks, nil
+}
+
+// canSeeItem reports whether viewer may see a post or one of its comments:
+// the subreddit must be visible to them, and removed or filtered content
+// needs the posts permission. Callers must hold e.mu.
+func (e *RedditEngine) canSeeItem(post *Post, removed bool, viewer string) bool {
+	subreddit, err := e.lookupSubreddit(post.Subreddit)
+	if err != nil {
+		return false
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+	return subreddit.canView(viewer) && (!removed || subreddit.hasModPermission(viewer, PermPosts))
+}
+
+// GetSavedItems returns the posts and comments username saved, most recently
+// saved first. A non-empty subredditName keeps only the items from that
+// subreddit. Items that were since deleted with their subreddit, or that
+// the user can no longer see, are left out, and comments by users they
+// blocked are "[blocked]" placeholders.
+func (e *RedditEngine) GetSavedItems(username, subredditName string) ([]*SavedItem, error) {
+	saved, err := e.copyMarks(username, false)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	blocked := e.blockedBy(username)
+	items := make([]*SavedItem, 0, len(saved))
+	for id, savedAt := range saved {
+		item := &SavedItem{SavedAt: savedAt}
+		var post *Post
+		var removed bool
+		if item.Post = e.posts[id]; item.Post != nil {
+			post = item.Post
+			post.mu.RLock()
+			removed = post.hidden()
+			post.mu.RUnlock()
+		} else if item.Comment = e.comments[id]; item.Comment != nil {
+			post = e.posts[item.Comment.PostID]
+			item.Comment.mu.RLock()
+			removed = item.Comment.hidden()
+			item.Comment.mu.RUnlock()
+		}
+		if post == nil || (subredditName != "" && post.Subreddit != subredditName) {
+			continue
+		}
+		if !e.canSeeItem(post, removed, username) {
+			continue
+		}
+		if item.Comment != nil {
+			item.Comment, _ = viewWithBlocks(item.Comment, blocked)
+		}
+		items = append(items, item)
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].SavedAt.After(items[j].SavedAt)
+	})
+	return items, nil
+}
+
+// GetHiddenPosts returns the posts username hid, most recently hidden first
+func (e *RedditEngine) GetHiddenPosts(username string) ([]*Post, error) {
+	hidden, err := e.copyMarks(username, true)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	posts := make([]*Post, 0, len(hidden))
+	for id := range hidden {
+		post, ok := e.posts[id]
+		if !ok {
+			continue
+		}
+		post.mu.RLock()
+		removed := post.hidden()
+		post.mu.RUnlock()
+		if e.canSeeItem(post, removed, username) {
+			posts = append(posts, post)
+		}
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(posts, func(i, j int) bool {
+		return hidden[posts[i].ID].After(hidden[posts[j].ID])
+	})
+	return posts, nil
+}
+
+// withoutHidden drops the posts in hidden
+func withoutHidden(posts []*Post, hidden map[string]time.Time) []*Post {
+	if len(hidden) == 0 {
+		return posts
+	}
+	kept := make([]*Post, 0, len(posts))
+	for _, post := range posts {
+		if _, ok := hidden[post.ID]; !ok {
+			kept = append(kept, post)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// savedIDs returns the IDs of saved items in order
+func savedIDs(items []*SavedItem) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.Post != nil {
+			ids = append(ids, item.Post.ID)
+		} else {
+			ids = append(ids, item.Comment.ID)
+		}
+	}
+	return ids
+}
+
+// postIDs returns the IDs of posts in order
+func postIDs(posts []*Post) []string {
+	ids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	return ids
+}
+
+func TestSaveItem(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	mustCreateSubreddit(t, e, "secret", "owner", SubredditPrivate)
+	public := mustCreatePost(t, e, "owner", "golang", "Public")
+	private := mustCreatePost(t, e, "owner", "secret", "Private")
+	comment, err := e.AddComment("psst", "owner", private.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		save func() error
+		want error
+	}{
+		{"public post", func() error { return e.SavePost("alice", public.ID) }, nil},
+		{"private post", func() error { return e.SavePost("alice", private.ID) }, ErrForbidden},
+		{"private comment", func() error { return e.SaveComment("alice", comment.ID) }, ErrForbidden},
+		{"hide private post", func() error { return e.HidePost("alice", private.ID) }, ErrForbidden},
+		{"unknown post", func() error { return e.SavePost("alice", "post_0") }, ErrNotFound},
+		{"unknown user", func() error { return e.SavePost("nobody", public.ID) }, ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.save(); !errors.Is(err, tt.want) {
+				t.Errorf("save = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSavedItems(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	mustCreateSubreddit(t, e, "rust", "owner", SubredditPublic)
+	mustCreateSubreddit(t, e, "secret", "owner", SubredditPrivate)
+	if err := e.ApproveUser("secret", "owner", "alice"); err != nil {
+		t.Fatalf("ApproveUser: %v", err)
+	}
+
+	kept := mustCreatePost(t, e, "owner", "golang", "Kept")
+	removed := mustCreatePost(t, e, "owner", "golang", "Removed")
+	private := mustCreatePost(t, e, "owner", "secret", "Private")
+	rust := mustCreatePost(t, e, "owner", "rust", "Rust")
+	comment, err := e.AddComment("nice", "owner", rust.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+
+	for _, username := range []string{"alice", "owner"} {
+		for _, id := range []string{kept.ID, removed.ID, private.ID} {
+			if err := e.SavePost(username, id); err != nil {
+				t.Fatalf("SavePost: %v", err)
+			}
+		}
+		if err := e.SaveComment(username, comment.ID); err != nil {
+			t.Fatalf("SaveComment: %v", err)
+		}
+	}
+
+	// Removed content stays with moderators, and alice loses the private post
+	if err := e.ModeratePost(removed.ID, "owner", ModRemove, "spam"); err != nil {
+		t.Fatalf("ModeratePost: %v", err)
+	}
+	if err := e.UnapproveUser("secret", "owner", "alice"); err != nil {
+		t.Fatalf("UnapproveUser: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		username  string
+		subreddit string
+		want      []string
+	}{
+		{"everything", "alice", "", []string{comment.ID, kept.ID}},
+		{"one subreddit", "alice", "golang", []string{kept.ID}},
+		{"moderator", "owner", "", []string{comment.ID, private.ID, removed.ID, kept.ID}},
+		{"moderator, one subreddit", "owner", "golang", []string{removed.ID, kept.ID}},
+		{"unknown subreddit", "alice", "python", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := e.GetSavedItems(tt.username, tt.subreddit)
+			if err != nil {
+				t.Fatalf("GetSavedItems: %v", err)
+			}
+			if got := savedIDs(items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("saved = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHidePost(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	if err := e.JoinSubreddit("alice", "golang"); err != nil {
+		t.Fatalf("JoinSubreddit: %v", err)
+	}
+	first := mustCreatePost(t, e, "owner", "golang", "First")
+	second := mustCreatePost(t, e, "owner", "golang", "Second")
+
+	tests := []struct {
+		name       string
+		change     func() error
+		wantFeed   []string
+		wantHidden []string
+	}{
+		{"nothing hidden", nil, []string{second.ID, first.ID}, []string{}},
+		{"hide", func() error { return e.HidePost("alice", first.ID) }, []string{second.ID}, []string{first.ID}},
+		{"hide again", func() error { return e.HidePost("alice", first.ID) }, []string{second.ID}, []string{first.ID}},
+		{"unhide", func() error { return e.UnhidePost("alice", first.ID) }, []string{second.ID, first.ID}, []string{}},
+	}
+
+	// The cases run in order and each change stays in place for the next
+	for _, tt := range tests {
+		if tt.change != nil {
+			if err := tt.change(); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+		feed, err := e.GetUserFeed("alice", SortNew, "")
+		if err != nil {
+			t.Fatalf("GetUserFeed: %v", err)
+		}
+		hidden, err := e.GetHiddenPosts("alice")
+		if err != nil {
+			t.Fatalf("GetHiddenPosts: %v", err)
+		}
+		if got := postIDs(feed); !reflect.DeepEqual(got, tt.wantFeed) {
+			t.Errorf("%s: feed = %v, want %v", tt.name, got, tt.wantFeed)
+		}
+		if got := postIDs(hidden); !reflect.DeepEqual(got, tt.wantHidden) {
+			t.Errorf("%s: hidden = %v, want %v", tt.name, got, tt.wantHidden)
+		}
+	}
+}
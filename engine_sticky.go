@@ -0,0 +1,184 @@
+package main
+
+// maxStickyPosts is how many posts a subreddit can pin, as on Reddit
+const maxStickyPosts = 2
+
+// unsticky takes post out of the sticky slots, if it holds one. Callers must
+// hold subreddit.mu for writing.
+func (s *Subreddit) unsticky(post *Post) bool {
+	for i, sticky := range s.Stickied {
+		if sticky == post {
+			s.Stickied = append(s.Stickied[:i], s.Stickied[i+1:]...)
+			post.mu.Lock()
+			post.Stickied = false
+			post.mu.Unlock()
+			return true
+		}
+	}
+	return false
+}
+
+// lookupModeratedPost resolves a post and its subreddit for a moderator
+// action
+func (e *RedditEngine) lookupModeratedPost(postID string) (*Post, *Subreddit, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	post, err := e.lookupPost(postID)
+	if err != nil {
+		return nil, nil, err
+	}
+	subreddit, err := e.lookupSubreddit(post.Subreddit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return post, subreddit, nil
+}
+
+// StickyPost pins a post to the top of its subreddit's listings, below any
+// post stickied earlier. A subreddit holds at most two sticky posts. Needs
+// the posts permission.
+func (e *RedditEngine) StickyPost(postID, actor string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	post, subreddit, err := e.lookupModeratedPost(postID)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermPosts); err != nil {
+		return err
+	}
+
+	post.mu.Lock()
+	defer post.mu.Unlock()
+
+	switch {
+	case post.Stickied:
+		return newError(ErrConflict, "post is already stickied")
+	case post.Deleted || post.hidden():
+		return newError(ErrConflict, "removed, filtered or deleted posts cannot be stickied")
+	case len(subreddit.Stickied) >= maxStickyPosts:
+		return newError(ErrConflict, "%s already has %d sticky posts", subreddit.Name, maxStickyPosts)
+	}
+
+	post.Stickied = true
+	subreddit.Stickied = append(subreddit.Stickied, post)
+	subreddit.logModAction(actor, LogSticky, postID, "")
+	return nil
+}
+
+// UnstickyPost releases a post's sticky slot. Needs the posts permission.
+func (e *RedditEngine) UnstickyPost(postID, actor string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	post, subreddit, err := e.lookupModeratedPost(postID)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermPosts); err != nil {
+		return err
+	}
+	if !subreddit.unsticky(post) {
+		return newError(ErrConflict, "post is not stickied")
+	}
+	subreddit.logModAction(actor, LogUnsticky, postID, "")
+	return nil
+}
+
+// LockPost stops new comments on a post; moderators can still comment. With
+// freezeVotes the votes on the post and its comments are frozen as well.
+// Locking a locked post updates freezeVotes. Needs the posts permission.
+func (e *RedditEngine) LockPost(postID, actor string, freezeVotes bool) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	post, subreddit, err := e.lookupModeratedPost(postID)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermPosts); err != nil {
+		return err
+	}
+
+	post.mu.Lock()
+	post.Locked = true
+	post.VotesFrozen = freezeVotes
+	post.mu.Unlock()
+
+	reason := ""
+	if freezeVotes {
+		reason = "votes frozen"
+	}
+	subreddit.logModAction(actor, LogLock, postID, reason)
+	return nil
+}
+
+// UnlockPost reopens a locked post for comments and votes. Needs the posts
+// permission.
+func (e *RedditEngine) UnlockPost(postID, actor string) error {
+	if err := e.checkNotSuspended(actor); err != nil {
+		return err
+	}
+
+	post, subreddit, err := e.lookupModeratedPost(postID)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.Lock()
+	defer subreddit.mu.Unlock()
+
+	if err := subreddit.checkModPermission(actor, PermPosts); err != nil {
+		return err
+	}
+
+	post.mu.Lock()
+	defer post.mu.Unlock()
+
+	if !post.Locked {
+		return newError(ErrConflict, "post is not locked")
+	}
+	post.Locked = false
+	post.VotesFrozen = false
+	subreddit.logModAction(actor, LogUnlock, postID, "")
+	return nil
+}
+
+// stickyFirst puts the sticky posts at the front of a sorted listing, in the
+// order they were stickied. Sticky posts are listed even when the sort's time
+// window would leave them out.
+func stickyFirst(posts []*Post, stickied []*Post) []*Post {
+	if len(stickied) == 0 {
+		return posts
+	}
+
+	ordered := make([]*Post, 0, len(posts)+len(stickied))
+	pinned := make(map[*Post]bool, len(stickied))
+	for _, post := range stickied {
+		ordered = append(ordered, post)
+		pinned[post] = true
+	}
+	for _, post := range posts {
+		if !pinned[post] {
+			ordered = append(ordered, post)
+		}
+	}
+	return ordered
+}
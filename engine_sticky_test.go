@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestStickyPosts(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	e.SetRateLimits(SimulationRateLimits)
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	posts := make([]*Post, 5)
+	for i := range posts {
+		posts[i] = mustCreatePost(t, e, "alice", "golang", "post")
+	}
+	if err := e.ModeratePost(posts[4].ID, "owner", ModRemove, ""); err != nil {
+		t.Fatalf("ModeratePost: %v", err)
+	}
+
+	// The steps run in order; wantTop is the start of alice's listing
+	tests := []struct {
+		name    string
+		step    func() error
+		wantErr error
+		wantTop []string
+	}{
+		{"by a non-moderator", func() error { return e.StickyPost(posts[0].ID, "alice") }, ErrForbidden,
+			[]string{posts[3].ID, posts[2].ID}},
+		{"first sticky", func() error { return e.StickyPost(posts[0].ID, "owner") }, nil,
+			[]string{posts[0].ID, posts[3].ID}},
+		{"already stickied", func() error { return e.StickyPost(posts[0].ID, "owner") }, ErrConflict,
+			[]string{posts[0].ID, posts[3].ID}},
+		{"removed post", func() error { return e.StickyPost(posts[4].ID, "owner") }, ErrConflict,
+			[]string{posts[0].ID, posts[3].ID}},
+		{"second sticky", func() error { return e.StickyPost(posts[1].ID, "owner") }, nil,
+			[]string{posts[0].ID, posts[1].ID, posts[3].ID}},
+		{"third sticky", func() error { return e.StickyPost(posts[2].ID, "owner") }, ErrConflict,
+			[]string{posts[0].ID, posts[1].ID, posts[3].ID}},
+		{"unsticky", func() error { return e.UnstickyPost(posts[0].ID, "owner") }, nil,
+			[]string{posts[1].ID, posts[3].ID}},
+		{"unsticky a regular post", func() error { return e.UnstickyPost(posts[0].ID, "owner") }, ErrConflict,
+			[]string{posts[1].ID, posts[3].ID}},
+		{"slot freed", func() error { return e.StickyPost(posts[2].ID, "owner") }, nil,
+			[]string{posts[1].ID, posts[2].ID, posts[3].ID}},
+		{"deleting frees the slot", func() error { return e.DeletePost(posts[1].ID, "alice") }, nil,
+			[]string{posts[2].ID, posts[3].ID}},
+		{"removing frees the slot", func() error { return e.ModeratePost(posts[2].ID, "owner", ModRemove, "") }, nil,
+			[]string{posts[3].ID, posts[1].ID}},
+	}
+
+	for _, tt := range tests {
+		if err := tt.step(); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		listing, err := e.GetSubredditPosts("golang", "alice", SortNew, TimeAll)
+		if err != nil {
+			t.Fatalf("%s: GetSubredditPosts: %v", tt.name, err)
+		}
+		if got := postIDs(listing)[:len(tt.wantTop)]; !reflect.DeepEqual(got, tt.wantTop) {
+			t.Errorf("%s: listing starts %v, want %v", tt.name, got, tt.wantTop)
+		}
+	}
+
+	if stickied := e.subreddits["golang"].Stickied; len(stickied) != 0 {
+		t.Errorf("subreddit still holds %d sticky posts, want 0", len(stickied))
+	}
+	if posts[1].Stickied || posts[2].Stickied {
+		t.Error("deleted or removed post is still marked stickied")
+	}
+}
+
+func TestLockPost(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "alice", "golang", "heated")
+	comment, err := e.AddComment("opinion", "alice", post.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+
+	if err := e.LockPost(post.ID, "alice", false); !errors.Is(err, ErrForbidden) {
+		t.Errorf("LockPost by a non-moderator error = %v, want ErrForbidden", err)
+	}
+	if err := e.UnlockPost(post.ID, "owner"); !errors.Is(err, ErrConflict) {
+		t.Errorf("UnlockPost of an open post error = %v, want ErrConflict", err)
+	}
+
+	// wantErr holds the errors of alice commenting, voting on the post and
+	// voting on the comment, and of the owner commenting
+	tests := []struct {
+		name    string
+		step    func() error
+		wantErr [4]error
+	}{
+		{"locked", func() error { return e.LockPost(post.ID, "owner", false) },
+			[4]error{ErrConflict, nil, nil, nil}},
+		{"votes frozen", func() error { return e.LockPost(post.ID, "owner", true) },
+			[4]error{ErrConflict, ErrConflict, ErrConflict, nil}},
+		{"unlocked", func() error { return e.UnlockPost(post.ID, "owner") },
+			[4]error{nil, nil, nil, nil}},
+	}
+
+	for _, tt := range tests {
+		if err := tt.step(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		_, commentErr := e.AddComment("more", "alice", post.ID, "")
+		_, postVoteErr := e.VotePost(post.ID, "alice", VoteUp)
+		_, commentVoteErr := e.VoteComment(comment.ID, "alice", VoteUp)
+		_, modCommentErr := e.AddComment("calm down", "owner", post.ID, "")
+		got := [4]error{commentErr, postVoteErr, commentVoteErr, modCommentErr}
+		for i, action := range [4]string{"comment", "post vote", "comment vote", "moderator comment"} {
+			if !errors.Is(got[i], tt.wantErr[i]) {
+				t.Errorf("%s: %s error = %v, want %v", tt.name, action, got[i], tt.wantErr[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testPassword is the password of every account created by newTestEngine
+const testPassword = "hunter2"
+
+// newTestEngine returns an engine with the given users registered
+func newTestEngine(t *testing.T, usernames ...string) *RedditEngine {
+	t.Helper()
+	e := NewRedditEngine()
+	for _, username := range usernames {
+		if err := e.RegisterUser(username, testPassword); err != nil {
+			t.Fatalf("RegisterUser(%q): %v", username, err)
+		}
+	}
+	return e
+}
+
+// mustCreateSubreddit creates a subreddit moderated by creator
+func mustCreateSubreddit(t *testing.T, e *RedditEngine, name, creator string, subType SubredditType) {
+	t.Helper()
+	if err := e.CreateSubreddit(name, "", creator, subType); err != nil {
+		t.Fatalf("CreateSubreddit(%q): %v", name, err)
+	}
+}
+
+// mustCreatePost submits a text post
+func mustCreatePost(t *testing.T, e *RedditEngine, author, subredditName, title string) *Post {
+	t.Helper()
+	post, err := e.CreatePost(title, "content of "+title, author, subredditName)
+	if err != nil {
+		t.Fatalf("CreatePost(%q): %v", title, err)
+	}
+	return post
+}
+
+func TestVotePost(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+
+	// The votes are cast in order against the same post
+	tests := []struct {
+		name      string
+		voter     string
+		direction int
+		want      VoteResult
+		wantKarma int
+	}{
+		{"upvote", "alice", VoteUp, VoteResult{Upvotes: 1, Score: 1}, 1},
+		{"repeat upvote", "alice", VoteUp, VoteResult{Upvotes: 1, Score: 1}, 1},
+		{"switch to downvote", "alice", VoteDown, VoteResult{Downvotes: 1, Score: -1}, -1},
+		{"second voter", "bob", VoteDown, VoteResult{Downvotes: 2, Score: -2}, -2},
+		{"withdraw", "alice", VoteNone, VoteResult{Downvotes: 1, Score: -1}, -1},
+		{"withdraw without a vote", "alice", VoteNone, VoteResult{Downvotes: 1, Score: -1}, -1},
+		{"switch to upvote", "bob", VoteUp, VoteResult{Upvotes: 1, Score: 1}, 1},
+	}
+
+	for _, tt := range tests {
+		got, err := e.VotePost(post.ID, tt.voter, tt.direction)
+		if err != nil {
+			t.Fatalf("%s: VotePost: %v", tt.name, err)
+		}
+		if *got != tt.want {
+			t.Errorf("%s: VotePost = %+v, want %+v", tt.name, *got, tt.want)
+		}
+		if karma := e.users["owner"].PostKarma; karma != tt.wantKarma {
+			t.Errorf("%s: author karma = %d, want %d", tt.name, karma, tt.wantKarma)
+		}
+	}
+}
+
+func TestVotePostErrors(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+
+	tests := []struct {
+		name      string
+		postID    string
+		voter     string
+		direction int
+		want      error
+	}{
+		{"direction too high", post.ID, "alice", 2, ErrValidation},
+		{"direction too low", post.ID, "alice", -2, ErrValidation},
+		{"unknown post", "post_0", "alice", VoteUp, ErrNotFound},
+		{"unknown voter", post.ID, "nobody", VoteUp, ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := e.VotePost(tt.postID, tt.voter, tt.direction); !errors.Is(err, tt.want) {
+				t.Errorf("VotePost = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if post.Votes != 0 || len(post.Voters) != 0 {
+		t.Errorf("refused votes changed the post: score %d, %d voters", post.Votes, len(post.Voters))
+	}
+}
+
+func TestVoteComment(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+	comment, err := e.AddComment("Nice release", "alice", post.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	deleted, err := e.AddComment("Never mind", "alice", post.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	if err := e.DeleteComment(deleted.ID, "alice"); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+
+	// The votes are cast in order; comment votes only move comment karma
+	tests := []struct {
+		name      string
+		commentID string
+		voter     string
+		direction int
+		want      *VoteResult
+		wantErr   error
+		wantKarma int
+	}{
+		{"upvote", comment.ID, "owner", VoteUp, &VoteResult{Upvotes: 1, Score: 1}, nil, 1},
+		{"repeat upvote", comment.ID, "owner", VoteUp, &VoteResult{Upvotes: 1, Score: 1}, nil, 1},
+		{"second upvote", comment.ID, "bob", VoteUp, &VoteResult{Upvotes: 2, Score: 2}, nil, 2},
+		{"switch to downvote", comment.ID, "owner", VoteDown, &VoteResult{Upvotes: 1, Downvotes: 1}, nil, 0},
+		{"withdraw", comment.ID, "bob", VoteNone, &VoteResult{Downvotes: 1, Score: -1}, nil, -1},
+		{"deleted comment", deleted.ID, "bob", VoteUp, nil, ErrConflict, -1},
+		{"unknown comment", "comment_0", "bob", VoteUp, nil, ErrNotFound, -1},
+		{"bad direction", comment.ID, "bob", 3, nil, ErrValidation, -1},
+	}
+
+	for _, tt := range tests {
+		got, err := e.VoteComment(tt.commentID, tt.voter, tt.direction)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: VoteComment = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if tt.want != nil && *got != *tt.want {
+			t.Errorf("%s: VoteComment = %+v, want %+v", tt.name, *got, *tt.want)
+		}
+		alice := e.users["alice"]
+		if alice.CommentKarma != tt.wantKarma || alice.PostKarma != 0 {
+			t.Errorf("%s: karma = %d post, %d comment, want 0, %d", tt.name, alice.PostKarma, alice.CommentKarma, tt.wantKarma)
+		}
+	}
+}
+
+func TestListSubreddits(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	created := time.Now()
+	for i, name := range []string{"golang", "GoPro", "rust", "gophers"} {
+		mustCreateSubreddit(t, e, name, "owner", SubredditPublic)
+		e.subreddits[name].CreatedAt = created.Add(time.Duration(i) * time.Minute)
+	}
+	for _, membership := range []struct{ user, subreddit string }{
+		{"alice", "rust"}, {"bob", "rust"}, {"alice", "gophers"},
+	} {
+		if err := e.JoinSubreddit(membership.user, membership.subreddit); err != nil {
+			t.Fatalf("JoinSubreddit(%q, %q): %v", membership.user, membership.subreddit, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		order  SubredditSort
+		want   []string
+	}{
+		{"by members", "", SubredditSortMembers, []string{"rust", "gophers", "GoPro", "golang"}},
+		{"newest first", "", SubredditSortNew, []string{"gophers", "rust", "GoPro", "golang"}},
+		{"oldest first", "", SubredditSortOld, []string{"golang", "GoPro", "rust", "gophers"}},
+		{"prefix ignores case", "GO", SubredditSortOld, []string{"golang", "GoPro", "gophers"}},
+		{"longer prefix", "gop", SubredditSortMembers, []string{"gophers", "GoPro"}},
+		{"no match", "python", SubredditSortMembers, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listed := e.ListSubreddits(tt.prefix, tt.order, "")
+			got := make([]string, len(listed))
+			for i, subreddit := range listed {
+				got[i] = subreddit.Name
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListSubreddits(%q, %q) = %v, want %v", tt.prefix, tt.order, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSubredditSort(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    SubredditSort
+		wantErr error
+	}{
+		{"", SubredditSortMembers, nil},
+		{"members", SubredditSortMembers, nil},
+		{"new", SubredditSortNew, nil},
+		{"old", SubredditSortOld, nil},
+		{"hot", "", ErrValidation},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSubredditSort(tt.value)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseSubredditSort(%q) = %q, %v, want %q, %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGetSubreddit(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	for _, username := range []string{"bob", "alice"} {
+		if err := e.JoinSubreddit(username, "golang"); err != nil {
+			t.Fatalf("JoinSubreddit(%q): %v", username, err)
+		}
+	}
+
+	subreddit, err := e.GetSubreddit("golang")
+	if err != nil {
+		t.Fatalf("GetSubreddit: %v", err)
+	}
+	if subreddit.Creator != "owner" || subreddit.CreatedAt.IsZero() {
+		t.Errorf("GetSubreddit = creator %q created %v, want owner and a creation time", subreddit.Creator, subreddit.CreatedAt)
+	}
+	if _, err := e.GetSubreddit("rust"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSubreddit(unknown) error = %v, want ErrNotFound", err)
+	}
+
+	members, err := e.GetSubredditMembers("golang", "")
+	if err != nil {
+		t.Fatalf("GetSubredditMembers: %v", err)
+	}
+	if want := []string{"alice", "bob"}; !reflect.DeepEqual(members, want) {
+		t.Errorf("GetSubredditMembers = %v, want %v", members, want)
+	}
+	if _, err := e.GetSubredditMembers("rust", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSubredditMembers(unknown) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetSubredditPostsWithoutJoining(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	first := mustCreatePost(t, e, "owner", "golang", "first")
+	second := mustCreatePost(t, e, "owner", "golang", "second")
+
+	for _, viewer := range []string{"alice", ""} {
+		posts, err := e.GetSubredditPosts("golang", viewer, SortNew, TimeAll)
+		if err != nil {
+			t.Fatalf("GetSubredditPosts(viewer %q): %v", viewer, err)
+		}
+		if got, want := postIDs(posts), []string{second.ID, first.ID}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GetSubredditPosts(viewer %q) = %v, want %v", viewer, got, want)
+		}
+	}
+	if _, err := e.GetSubredditPosts("rust", "alice", SortNew, TimeAll); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSubredditPosts(unknown) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestEditPost(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+
+	if _, err := e.EditPost(post.ID, "alice", "hijacked"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("EditPost by another user error = %v, want ErrForbidden", err)
+	}
+	if !post.EditedAt.IsZero() {
+		t.Errorf("EditedAt = %v after a refused edit, want zero", post.EditedAt)
+	}
+
+	edited, err := e.EditPost(post.ID, "owner", "updated")
+	if err != nil {
+		t.Fatalf("EditPost: %v", err)
+	}
+	if edited.Content != "updated" || edited.EditedAt.IsZero() {
+		t.Errorf("EditPost = content %q edited %v, want updated content and an edit time", edited.Content, edited.EditedAt)
+	}
+
+	if _, err := e.EditPost("t3_missing", "owner", "text"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("EditPost(unknown) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+	comment, err := e.AddComment("first!", "alice", post.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+
+	if err := e.DeletePost(post.ID, "alice"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("DeletePost by another user error = %v, want ErrForbidden", err)
+	}
+	if err := e.DeletePost(post.ID, "owner"); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+
+	got, err := e.GetPost(post.ID)
+	if err != nil {
+		t.Fatalf("GetPost after delete: %v", err)
+	}
+	if !got.Deleted || got.Author != deletedMarker || got.Content != deletedMarker || got.Title != "Go 1.23" {
+		t.Errorf("tombstone = deleted %v author %q content %q title %q, want a [deleted] post keeping its title",
+			got.Deleted, got.Author, got.Content, got.Title)
+	}
+
+	posts, err := e.GetSubredditPosts("golang", "alice", SortNew, TimeAll)
+	if err != nil {
+		t.Fatalf("GetSubredditPosts: %v", err)
+	}
+	if ids := postIDs(posts); !reflect.DeepEqual(ids, []string{post.ID}) {
+		t.Errorf("GetSubredditPosts = %v, want the tombstone %s to keep its place", ids, post.ID)
+	}
+	comments, err := e.GetComments(post.ID, "alice")
+	if err != nil {
+		t.Fatalf("GetComments: %v", err)
+	}
+	if len(comments) != 1 || comments[0].ID != comment.ID {
+		t.Errorf("GetComments = %d comments, want the comment %s to survive", len(comments), comment.ID)
+	}
+
+	if err := e.DeletePost(post.ID, "owner"); !errors.Is(err, ErrConflict) {
+		t.Errorf("second DeletePost error = %v, want ErrConflict", err)
+	}
+	if _, err := e.EditPost(post.ID, "owner", "back"); !errors.Is(err, ErrConflict) {
+		t.Errorf("EditPost after delete error = %v, want ErrConflict", err)
+	}
+}
+
+func TestCommentReplies(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+	other := mustCreatePost(t, e, "owner", "golang", "Go 1.24")
+
+	top, err := e.AddComment("top", "alice", post.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	reply, err := e.AddComment("reply", "bob", post.ID, top.ID)
+	if err != nil {
+		t.Fatalf("AddComment(reply): %v", err)
+	}
+	if reply.ParentID != top.ID || len(top.Children) != 1 || top.Children[0] != reply {
+		t.Errorf("reply parent = %q with %d children on the parent, want it nested under %s", reply.ParentID, len(top.Children), top.ID)
+	}
+	if len(post.Comments) != 1 {
+		t.Errorf("post has %d top-level comments, want 1", len(post.Comments))
+	}
+
+	if _, err := e.AddComment("misplaced", "bob", other.ID, top.ID); !errors.Is(err, ErrValidation) {
+		t.Errorf("reply on another post error = %v, want ErrValidation", err)
+	}
+	if _, err := e.AddComment("orphan", "bob", post.ID, "comment_missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("reply to an unknown comment error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestEditAndDeleteComment(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice", "bob")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+	comment, err := e.AddComment("original", "alice", post.ID, "")
+	if err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	reply, err := e.AddComment("reply", "bob", post.ID, comment.ID)
+	if err != nil {
+		t.Fatalf("AddComment(reply): %v", err)
+	}
+
+	if _, err := e.EditComment(comment.ID, "bob", "hijacked"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("EditComment by another user error = %v, want ErrForbidden", err)
+	}
+	edited, err := e.EditComment(comment.ID, "alice", "updated")
+	if err != nil {
+		t.Fatalf("EditComment: %v", err)
+	}
+	if edited.Content != "updated" || edited.EditedAt.IsZero() {
+		t.Errorf("EditComment = content %q edited %v, want updated content and an edit time", edited.Content, edited.EditedAt)
+	}
+
+	if err := e.DeleteComment(comment.ID, "bob"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("DeleteComment by another user error = %v, want ErrForbidden", err)
+	}
+	if err := e.DeleteComment(comment.ID, "alice"); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+	if !comment.Deleted || comment.Author != deletedMarker || comment.Content != deletedMarker {
+		t.Errorf("placeholder = deleted %v author %q content %q, want a [deleted] comment", comment.Deleted, comment.Author, comment.Content)
+	}
+
+	comments, err := e.GetComments(post.ID, "owner")
+	if err != nil {
+		t.Fatalf("GetComments: %v", err)
+	}
+	if len(comments) != 1 || len(comments[0].Children) != 1 || comments[0].Children[0].ID != reply.ID {
+		t.Errorf("GetComments lost the reply %s under the deleted comment", reply.ID)
+	}
+
+	if err := e.DeleteComment(comment.ID, "alice"); !errors.Is(err, ErrConflict) {
+		t.Errorf("second DeleteComment error = %v, want ErrConflict", err)
+	}
+	if _, err := e.EditComment(comment.ID, "alice", "back"); !errors.Is(err, ErrConflict) {
+		t.Errorf("EditComment after delete error = %v, want ErrConflict", err)
+	}
+	if _, err := e.AddComment("late reply", "bob", post.ID, comment.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("reply to a deleted comment error = %v, want ErrConflict", err)
+	}
+}
+
+func TestGetCommentContext(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+
+	// thread[i] replies to thread[i-1]
+	thread := make([]string, 4)
+	parentID := ""
+	for i := range thread {
+		comment, err := e.AddComment("level", "alice", post.ID, parentID)
+		if err != nil {
+			t.Fatalf("AddComment(level %d): %v", i, err)
+		}
+		thread[i] = comment.ID
+		parentID = comment.ID
+	}
+
+	tests := []struct {
+		name    string
+		comment string
+		context int
+		want    []string
+	}{
+		{"no context", thread[3], 0, thread[3:]},
+		{"two ancestors", thread[3], 2, thread[1:]},
+		{"more context than ancestors", thread[3], 10, thread},
+		{"top-level comment", thread[0], 5, thread[:1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comments, err := e.GetCommentContext(tt.comment, tt.context, "alice")
+			if err != nil {
+				t.Fatalf("GetCommentContext: %v", err)
+			}
+			got := make([]string, len(comments))
+			for i, comment := range comments {
+				got[i] = comment.ID
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCommentContext = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := e.GetCommentContext("comment_missing", 1, "alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetCommentContext(unknown) error = %v, want ErrNotFound", err)
+	}
+}
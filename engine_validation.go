@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NotFoundError reports a reference to a user, subreddit, post, comment or
+// message that does not exist
+type NotFoundError struct {
+	Kind string
+	ID   string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %q not found", e.Kind, e.ID)
+}
+
+// ValidationError reports an argument that is missing or malformed
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// MembershipError reports a user posting to a subreddit they have not joined
+// while membership is required
+type MembershipError struct {
+	Username  string
+	Subreddit string
+}
+
+func (e *MembershipError) Error() string {
+	return fmt.Sprintf("%s must join %s before posting", e.Username, e.Subreddit)
+}
+
+// SetRequireMembership controls whether users must join a subreddit before
+// they can post in it
+func (e *RedditEngine) SetRequireMembership(require bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requireMembership = require
+}
+
+// The lookup helpers resolve a reference or return a NotFoundError. Callers
+// must hold e.mu.
+
+func (e *RedditEngine) lookupUser(username string) (*User, error) {
+	user, ok := e.users[username]
+	if !ok {
+		return nil, &NotFoundError{Kind: "user", ID: username}
+	}
+	return user, nil
+}
+
+func (e *RedditEngine) lookupSubreddit(name string) (*Subreddit, error) {
+	subreddit, ok := e.subreddits[name]
+	if !ok {
+		return nil, &NotFoundError{Kind: "subreddit", ID: name}
+	}
+	return subreddit, nil
+}
+
+func (e *RedditEngine) lookupPost(postID string) (*Post, error) {
+	post, ok := e.posts[postID]
+	if !ok {
+		return nil, &NotFoundError{Kind: "post", ID: postID}
+	}
+	return post, nil
+}
+
+func (e *RedditEngine) lookupComment(commentID string) (*Comment, error) {
+	comment, ok := e.comments[commentID]
+	if !ok {
+		return nil, &NotFoundError{Kind: "comment", ID: commentID}
+	}
+	return comment, nil
+}
+
+// checkCanPost enforces bans, mutes, the subreddit type and the membership
+// requirement for new posts. Callers must hold e.mu.
+func (e *RedditEngine) checkCanPost(username string, subreddit *Subreddit) error {
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	if err := subreddit.checkCanContribute(username); err != nil {
+		return err
+	}
+	if err := subreddit.checkCanSubmit(username); err != nil {
+		return err
+	}
+	if e.requireMembership && !subreddit.Members[username] {
+		return &MembershipError{Username: username, Subreddit: subreddit.Name}
+	}
+	return nil
+}
+
+// checkCanComment enforces bans, mutes, private subreddits and locks in the
+// subreddit of post. Callers must hold e.mu.
+func (e *RedditEngine) checkCanComment(username string, post *Post) error {
+	subreddit, err := e.lookupSubreddit(post.Subreddit)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	if err := subreddit.checkCanContribute(username); err != nil {
+		return err
+	}
+	if err := subreddit.checkCanView(username); err != nil {
+		return err
+	}
+
+	post.mu.RLock()
+	locked := post.Locked
+	post.mu.RUnlock()
+	if locked && !subreddit.hasModPermission(username, PermPosts) {
+		return newError(ErrConflict, "post is locked")
+	}
+	return nil
+}
+
+// checkCanVote rejects votes from users banned from the subreddit or unable
+// to see it. Callers must hold e.mu.
+func (e *RedditEngine) checkCanVote(username, subredditName string) error {
+	subreddit, err := e.lookupSubreddit(subredditName)
+	if err != nil {
+		return err
+	}
+
+	subreddit.mu.RLock()
+	defer subreddit.mu.RUnlock()
+
+	if err := subreddit.checkNotBanned(username); err != nil {
+		return err
+	}
+	return subreddit.checkCanView(username)
+}
+
+// requireText rejects empty or whitespace-only values
+func requireText(field, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return &ValidationError{Field: field, Reason: "cannot be empty"}
+	}
+	return nil
+}
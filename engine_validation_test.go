@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReferentialIntegrity(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+
+	tests := []struct {
+		name     string
+		call     func() error
+		wantKind string
+	}{
+		{"subreddit by an unknown creator", func() error {
+			return e.CreateSubreddit("rust", "", "ghost", SubredditPublic)
+		}, "user"},
+		{"post by an unknown author", func() error {
+			_, err := e.CreatePost("title", "content", "ghost", "golang")
+			return err
+		}, "user"},
+		{"post in an unknown subreddit", func() error {
+			_, err := e.CreatePost("title", "content", "alice", "rust")
+			return err
+		}, "subreddit"},
+		{"comment by an unknown author", func() error {
+			_, err := e.AddComment("hi", "ghost", post.ID, "")
+			return err
+		}, "user"},
+		{"comment on an unknown post", func() error {
+			_, err := e.AddComment("hi", "alice", "t3_missing", "")
+			return err
+		}, "post"},
+		{"reply to an unknown comment", func() error {
+			_, err := e.AddComment("hi", "alice", post.ID, "comment_missing")
+			return err
+		}, "comment"},
+		{"joining an unknown subreddit", func() error {
+			return e.JoinSubreddit("alice", "rust")
+		}, "subreddit"},
+		{"vote by an unknown user", func() error {
+			_, err := e.VotePost(post.ID, "ghost", VoteUp)
+			return err
+		}, "user"},
+		{"message to an unknown user", func() error {
+			_, err := e.SendDirectMessage("alice", "ghost", "hello")
+			return err
+		}, "user"},
+		{"feed of an unknown user", func() error {
+			_, err := e.GetUserFeed("ghost", SortNew, TimeAll)
+			return err
+		}, "user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var notFound *NotFoundError
+			if !errors.As(err, &notFound) || !errors.Is(err, ErrNotFound) {
+				t.Fatalf("error = %v, want a NotFoundError", err)
+			}
+			if notFound.Kind != tt.wantKind {
+				t.Errorf("NotFoundError.Kind = %q, want %q", notFound.Kind, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestRequiredText(t *testing.T) {
+	e := newTestEngine(t, "owner")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+
+	tests := []struct {
+		name      string
+		call      func() error
+		wantField string
+	}{
+		{"subreddit name", func() error {
+			return e.CreateSubreddit(" ", "", "owner", SubredditPublic)
+		}, "subreddit name"},
+		{"comment", func() error {
+			_, err := e.AddComment("\t\n", "owner", post.ID, "")
+			return err
+		}, "comment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var invalid *ValidationError
+			if err := tt.call(); !errors.As(err, &invalid) || invalid.Field != tt.wantField {
+				t.Errorf("error = %v, want a ValidationError for %q", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestRequireMembership(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+
+	// Posting is open to everyone until membership is required
+	mustCreatePost(t, e, "alice", "golang", "before")
+
+	e.SetRequireMembership(true)
+	_, err := e.CreatePost("during", "content", "alice", "golang")
+	var membership *MembershipError
+	if !errors.As(err, &membership) || !errors.Is(err, ErrForbidden) {
+		t.Fatalf("CreatePost without joining error = %v, want a MembershipError", err)
+	}
+	if membership.Username != "alice" || membership.Subreddit != "golang" {
+		t.Errorf("MembershipError = %+v, want alice in golang", *membership)
+	}
+
+	if err := e.JoinSubreddit("alice", "golang"); err != nil {
+		t.Fatalf("JoinSubreddit: %v", err)
+	}
+	mustCreatePost(t, e, "alice", "golang", "after")
+}
+
+func TestUserFeedSkipsMissingSubreddits(t *testing.T) {
+	e := newTestEngine(t, "owner", "alice")
+	mustCreateSubreddit(t, e, "golang", "owner", SubredditPublic)
+	post := mustCreatePost(t, e, "owner", "golang", "Go 1.23")
+	if err := e.JoinSubreddit("alice", "golang"); err != nil {
+		t.Fatalf("JoinSubreddit: %v", err)
+	}
+	e.users["alice"].Subreddits["vanished"] = true
+
+	feed, err := e.GetUserFeed("alice", SortNew, TimeAll)
+	if err != nil {
+		t.Fatalf("GetUserFeed: %v", err)
+	}
+	if len(feed) != 1 || feed[0].ID != post.ID {
+		t.Errorf("GetUserFeed = %v, want only %s", postIDs(feed), post.ID)
+	}
+}
@@ -3,12 +3,54 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
+	"strings"
 	"time"
 )
 
 func main() {
 	engine := NewRedditEngine()
 
+	// REDDIT_RATE_LIMITS=simulation relaxes the content rate limits for load
+	// testing; without it the server keeps DefaultRateLimits
+	switch limits := os.Getenv("REDDIT_RATE_LIMITS"); limits {
+	case "", "default":
+	case "simulation":
+		engine.SetRateLimits(SimulationRateLimits)
+	default:
+		log.Fatalf("REDDIT_RATE_LIMITS must be default or simulation, not %q", limits)
+	}
+
+	// Site admins are listed in REDDIT_ADMINS as username:password pairs,
+	// separated by commas. Their accounts are registered before the server
+	// starts, so nobody else can register the names first.
+	for _, entry := range strings.Split(os.Getenv("REDDIT_ADMINS"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		admin, password, ok := strings.Cut(entry, ":")
+		if !ok {
+			log.Fatalf("REDDIT_ADMINS entry %q has no password", admin)
+		}
+		if err := engine.RegisterUser(admin, password); err != nil {
+			log.Fatalf("Failed to register admin %s: %v", admin, err)
+		}
+		if err := engine.SetAdmin(admin, true); err != nil {
+			log.Fatalf("Failed to make %s an admin: %v", admin, err)
+		}
+	}
+
+	// Uploaded images are kept in REDDIT_MEDIA_DIR, "media" by default
+	mediaDir := os.Getenv("REDDIT_MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+	media, err := NewMediaStore(mediaDir, DefaultMaxImageSize)
+	if err != nil {
+		log.Fatalf("Failed to open media store: %v", err)
+	}
+	engine.SetMediaStore(media)
+
 	// Create and start the API server
 	server := NewAPIServer(engine)
 	go func() {
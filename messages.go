@@ -1,5 +1,7 @@
 package main
 
+import "time"
+
 type RegisterUserMessage struct {
 	Username string
 	Password string
@@ -9,6 +11,192 @@ type CreateSubredditMessage struct {
 	Name        string
 	Description string
 	Creator     string
+	Type        SubredditType // defaults to SubredditPublic
+}
+
+type ListSubredditsMessage struct {
+	Prefix string
+	Sort   SubredditSort // defaults to SubredditSortMembers
+	Viewer string
+}
+
+type GetSubredditMessage struct {
+	Name string
+}
+
+type GetSubredditMembersMessage struct {
+	Subreddit string
+	Viewer    string
+}
+
+type SuspendUserMessage struct {
+	Actor    string
+	Username string
+	Reason   string
+	Duration time.Duration // zero for a permanent suspension
+}
+
+type UnsuspendUserMessage struct {
+	Actor    string
+	Username string
+}
+
+// BanSubredditMessage bans a subreddit, or unbans it when Ban is false
+type BanSubredditMessage struct {
+	Actor     string
+	Subreddit string
+	Reason    string
+	Ban       bool
+}
+
+type DeleteSubredditMessage struct {
+	Actor     string
+	Subreddit string
+}
+
+type QuarantineSubredditMessage struct {
+	Actor      string
+	Subreddit  string
+	Quarantine bool // false lifts the quarantine
+}
+
+type OptInToQuarantineMessage struct {
+	Username  string
+	Subreddit string
+}
+
+type SetSubredditTypeMessage struct {
+	Subreddit string
+	Actor     string
+	Type      SubredditType
+}
+
+type SetRequirePostFlairMessage struct {
+	Subreddit string
+	Actor     string
+	Require   bool
+}
+
+// AddFlairTemplateMessage offers Template in a subreddit; its ID is assigned
+// by the engine
+type AddFlairTemplateMessage struct {
+	Subreddit string
+	Actor     string
+	Template  FlairTemplate
+}
+
+type RemoveFlairTemplateMessage struct {
+	Subreddit  string
+	Actor      string
+	TemplateID string
+}
+
+type GetFlairTemplatesMessage struct {
+	Subreddit string
+	Viewer    string
+	Kind      FlairKind
+}
+
+// SetPostFlairMessage flairs a post; an empty TemplateID clears the flair
+type SetPostFlairMessage struct {
+	PostID     string
+	Actor      string
+	TemplateID string
+	Text       string
+}
+
+// SetUserFlairMessage sets the flair of Username in a subreddit; an empty
+// TemplateID clears it
+type SetUserFlairMessage struct {
+	Subreddit  string
+	Actor      string
+	Username   string
+	TemplateID string
+	Text       string
+}
+
+type GetApprovedUsersMessage struct {
+	Subreddit string
+	Actor     string
+}
+
+// ApproveUserMessage approves Username, or takes the approval back when
+// Approve is false
+type ApproveUserMessage struct {
+	Subreddit string
+	Actor     string
+	Username  string
+	Approve   bool
+}
+
+type RequestToJoinMessage struct {
+	Subreddit string
+	Username  string
+	Message   string
+}
+
+type GetJoinRequestsMessage struct {
+	Subreddit string
+	Actor     string
+}
+
+// ReviewJoinRequestMessage approves or denies the join request of Username
+type ReviewJoinRequestMessage struct {
+	Subreddit string
+	Actor     string
+	Username  string
+	Approve   bool
+}
+
+type GetModeratorsMessage struct {
+	Subreddit string
+}
+
+type AddModeratorMessage struct {
+	Subreddit   string
+	Actor       string
+	Username    string
+	Permissions ModPermission
+}
+
+type InviteModeratorMessage struct {
+	Subreddit   string
+	Actor       string
+	Username    string
+	Permissions ModPermission
+}
+
+type AcceptModInviteMessage struct {
+	Subreddit string
+	Username  string
+}
+
+type RemoveModeratorMessage struct {
+	Subreddit string
+	Actor     string
+	Username  string
+}
+
+type GetRestrictionsMessage struct {
+	Kind      RestrictionKind // RestrictionBan or RestrictionMute
+	Subreddit string
+	Actor     string
+}
+
+type RestrictUserMessage struct {
+	Kind      RestrictionKind
+	Subreddit string
+	Actor     string
+	Username  string
+	Reason    string
+	Duration  time.Duration // zero for a permanent restriction
+}
+
+type LiftRestrictionMessage struct {
+	Kind      RestrictionKind
+	Subreddit string
+	Actor     string
+	Username  string
 }
 
 type JoinSubredditMessage struct {
@@ -16,11 +204,50 @@ type JoinSubredditMessage struct {
 	Subreddit string
 }
 
+// CreatePostMessage submits a post. An empty Kind makes a self post.
 type CreatePostMessage struct {
-	Title     string
-	Content   string
-	Author    string
-	Subreddit string
+	Title           string
+	Content         string
+	Author          string
+	Subreddit       string
+	Kind            PostKind
+	URL             string
+	Image           []byte
+	PollOptions     []string
+	PollDuration    time.Duration
+	FlairTemplateID string
+	FlairText       string
+}
+
+type GetPostMessage struct {
+	PostID string
+}
+
+// CrosspostMessage shares PostID into Subreddit. An empty Title reuses the
+// original's.
+type CrosspostMessage struct {
+	PostID          string
+	Author          string
+	Subreddit       string
+	Title           string
+	FlairTemplateID string
+	FlairText       string
+}
+
+type GetCrosspostsMessage struct {
+	PostID string
+	Viewer string
+}
+
+type EditPostMessage struct {
+	PostID  string
+	Editor  string
+	Content string
+}
+
+type DeletePostMessage struct {
+	PostID    string
+	Requester string
 }
 
 type AddCommentMessage struct {
@@ -30,13 +257,128 @@ type AddCommentMessage struct {
 	ParentCommentID string
 }
 
+type GetCommentContextMessage struct {
+	CommentID string
+	Context   int // number of ancestors to include
+	Viewer    string
+}
+
+type EditCommentMessage struct {
+	CommentID string
+	Editor    string
+	Content   string
+}
+
+type DeleteCommentMessage struct {
+	CommentID string
+	Requester string
+}
+
+type ReportPostMessage struct {
+	PostID   string
+	Reporter string
+	Reason   string
+}
+
+type ReportCommentMessage struct {
+	CommentID string
+	Reporter  string
+	Reason    string
+}
+
+type ModeratePostMessage struct {
+	PostID string
+	Actor  string
+	Action ModAction
+	Reason string
+}
+
+type ModerateCommentMessage struct {
+	CommentID string
+	Actor     string
+	Action    ModAction
+	Reason    string
+}
+
+type StickyPostMessage struct {
+	PostID string
+	Actor  string
+	Sticky bool // false unstickies the post
+}
+
+type LockPostMessage struct {
+	PostID      string
+	Actor       string
+	Lock        bool // false unlocks the post
+	FreezeVotes bool
+}
+
+type GetModLogMessage struct {
+	Subreddit string
+	Actor     string
+	Moderator string       // empty for every moderator
+	Action    ModLogAction // empty for every action
+}
+
+type GetModQueueMessage struct {
+	Subreddit string
+	Actor     string
+}
+
+type GetAutoModRulesMessage struct {
+	Subreddit string
+	Actor     string
+}
+
+type SetAutoModRulesMessage struct {
+	Subreddit string
+	Actor     string
+	Rules     []*AutoModRule
+}
+
+type TestAutoModRulesMessage struct {
+	Subreddit string
+	Actor     string
+	Sample    AutoModSample
+}
+
 type VotePostMessage struct {
+	PostID    string
+	Voter     string
+	Direction int // VoteUp, VoteDown or VoteNone
+}
+
+// VotePollMessage picks Option, counted from zero, in the poll of a post
+type VotePollMessage struct {
 	PostID string
-	Upvote bool
+	Voter  string
+	Option int
+}
+
+type VoteCommentMessage struct {
+	CommentID string
+	Voter     string
+	Direction int // VoteUp, VoteDown or VoteNone
 }
 
 type GetFeedMessage struct {
 	Username string
+	Sort     SortMode   // defaults to SortNew
+	Time     TimeWindow // defaults to TimeAll
+}
+
+type GetSubredditPostsMessage struct {
+	Subreddit string
+	Viewer    string // sees removed posts when moderating the subreddit
+	Sort      SortMode
+	Time      TimeWindow
+}
+
+type GetDomainPostsMessage struct {
+	Domain string
+	Viewer string
+	Sort   SortMode
+	Time   TimeWindow
 }
 
 type SendDMMessage struct {
@@ -55,6 +397,44 @@ type ReplyToDMMessage struct {
 	Content           string
 }
 
+// BlockUserMessage blocks Target for Username, or unblocks them when Block
+// is false
+type BlockUserMessage struct {
+	Username string
+	Target   string
+	Block    bool
+}
+
+type GetBlockedUsersMessage struct {
+	Username string
+}
+
+// SaveItemMessage saves a post or, when CommentID is set, a comment for
+// Username, or unsaves it when Save is false
+type SaveItemMessage struct {
+	Username  string
+	PostID    string
+	CommentID string
+	Save      bool
+}
+
+// HidePostMessage hides a post from the feed of Username, or unhides it when
+// Hide is false
+type HidePostMessage struct {
+	Username string
+	PostID   string
+	Hide     bool
+}
+
+type GetSavedItemsMessage struct {
+	Username  string
+	Subreddit string
+}
+
+type GetHiddenPostsMessage struct {
+	Username string
+}
+
 type GetStatsMessage struct{}
 
 type StatsResponse struct {
@@ -69,8 +449,10 @@ type StatsResponse struct {
 }
 
 type UserKarma struct {
-	Username string
-	Karma    int
+	Username     string
+	Karma        int // PostKarma + CommentKarma
+	PostKarma    int
+	CommentKarma int
 }
 
 type LeaveSubredditMessage struct {
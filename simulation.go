@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -46,7 +45,11 @@ func (s *Simulator) Run() {
 				log.Printf("Failed to register user%d: %v", i+1, err)
 				return
 			}
-			log.Printf("Registered user%d", i+1)
+			if err := client.Login("password"); err != nil {
+				log.Printf("Failed to log in user%d: %v", i+1, err)
+				return
+			}
+			log.Printf("Registered and logged in user%d", i+1)
 		}(i)
 	}
 	wg.Wait()
@@ -101,7 +104,7 @@ func (s *Simulator) Run() {
 	// 5. Get all posts for voting and commenting
 	log.Println("\nGathering posts for interaction...")
 	for _, client := range s.clients {
-		posts, err := client.GetPosts()
+		posts, err := client.GetPosts(SortNew, TimeAll)
 		if err != nil {
 			log.Printf("Failed to get posts: %v", err)
 			continue
@@ -117,16 +120,16 @@ func (s *Simulator) Run() {
 		numVotes := 5 + rand.Intn(5) // Vote on 5-10 posts
 		for i := 0; i < numVotes && len(s.posts) > 0; i++ {
 			postID := s.posts[rand.Intn(len(s.posts))]
-			upvote := rand.Float32() > 0.3 // 70% chance of upvote
-			if err := client.VotePost(postID, upvote); err != nil {
+			direction := VoteDown
+			if rand.Float32() > 0.3 { // 70% chance of upvote
+				direction = VoteUp
+			}
+			result, err := client.VotePost(postID, direction)
+			if err != nil {
 				log.Printf("Failed to vote: %v", err)
 				continue
 			}
-			if upvote {
-				log.Printf("%s upvoted post %s", client.username, postID)
-			} else {
-				log.Printf("%s downvoted post %s", client.username, postID)
-			}
+			log.Printf("%s %s post %s (score %d)", client.username, voteVerb(direction), postID, result.Score)
 			time.Sleep(time.Millisecond * 100)
 		}
 	}
@@ -153,58 +156,26 @@ func (s *Simulator) Run() {
 			postID := s.posts[rand.Intn(len(s.posts))]
 			commentText := commentTemplates[rand.Intn(len(commentTemplates))]
 
-			commentData := map[string]string{
-				"content": fmt.Sprintf("%s", commentText),
-			}
-
-			jsonData, err := json.Marshal(commentData)
-			if err != nil {
-				log.Printf("Failed to marshal comment: %v", err)
-				continue
-			}
-
-			req, err := http.NewRequest(
-				"POST",
-				fmt.Sprintf("%s/api/posts/%s/comments", s.baseURL, postID),
-				bytes.NewBuffer(jsonData),
-			)
-			if err != nil {
-				log.Printf("Failed to create request: %v", err)
-				continue
-			}
-
-			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("Username", client.username)
-
-			resp, err := http.DefaultClient.Do(req)
+			comment, err := client.AddComment(postID, "", commentText)
 			if err != nil {
 				log.Printf("Failed to add comment: %v", err)
 				continue
 			}
-			resp.Body.Close()
 
 			log.Printf("%s commented on post %s: %s", client.username, postID, commentText)
 			time.Sleep(time.Millisecond * 100)
 
 			if rand.Float32() < 0.25 {
+				replier := s.clients[rand.Intn(len(s.clients))]
 				replyText := commentTemplates[rand.Intn(len(commentTemplates))]
-				replyReq := CommentRequest{
-					Content: fmt.Sprintf("Reply: %s [Reply by %s]", replyText, client.username),
-				}
-
-				resp, err := http.Post(
-					fmt.Sprintf("%s/api/posts/%s/comments", s.baseURL, postID),
-					"application/json",
-					bytes.NewBuffer([]byte(fmt.Sprintf(`{"content":"%s"}`, replyReq.Content))),
-				)
 
+				_, err := replier.AddComment(postID, comment.ID, fmt.Sprintf("Reply: %s", replyText))
 				if err != nil {
 					log.Printf("Failed to add reply: %v", err)
 					continue
 				}
-				resp.Body.Close()
 
-				log.Printf("%s replied to a comment on post %s: %s", client.username, postID, replyText)
+				log.Printf("%s replied to comment %s on post %s: %s", replier.username, comment.ID, postID, replyText)
 				time.Sleep(time.Millisecond * 100)
 			}
 		}
@@ -45,15 +45,29 @@ func generateRandomName(prefix string, length int) string {
 	return prefix + string(result)
 }
 
-func (state *SimulatorActor) simulateUserActivity(context actor.Context, username string, activity int) {
-	defer state.wg.Done()
+// simulationAdmin creates the simulated subreddits
+const simulationAdmin = "admin"
 
-	// Register user
-	context.Request(state.enginePID, &RegisterUserMessage{
+// registerUser registers a simulated user and waits for the engine to reply
+func (state *SimulatorActor) registerUser(context actor.Context, username string) {
+	future := context.RequestFuture(state.enginePID, &RegisterUserMessage{
 		Username: username,
 		Password: "password",
-	})
+	}, 5*time.Second)
+
+	result, err := future.Result()
+	if err == nil {
+		err, _ = result.(error)
+	}
+	if err != nil {
+		fmt.Printf("Error registering user %s: %v\n", username, err)
+		return
+	}
 	fmt.Printf("User registered: %s\n", username)
+}
+
+func (state *SimulatorActor) simulateUserActivity(context actor.Context, username string, activity int) {
+	defer state.wg.Done()
 
 	// Join subreddits
 	numSubreddits := 2 + rand.Intn(3)
@@ -120,16 +134,20 @@ func (state *SimulatorActor) simulateUserActivity(context actor.Context, usernam
 		// Add votes
 		numVotes := 5 + rand.Intn(10)
 		for v := 0; v < numVotes; v++ {
-			isUpvote := rand.Float64() < 0.7 // 70% chance of upvote
+			direction := VoteDown
+			if rand.Float64() < 0.7 { // 70% chance of upvote
+				direction = VoteUp
+			}
 			voteMsg := &VotePostMessage{
-				PostID: postID,
-				Upvote: isUpvote,
+				PostID:    postID,
+				Voter:     fmt.Sprintf("user_%d", rand.Intn(state.userCount)),
+				Direction: direction,
 			}
 			future := context.RequestFuture(state.enginePID, voteMsg, time.Second)
 			if _, err := future.Result(); err != nil {
 				fmt.Printf("Error voting: %v\n", err)
 			} else {
-				if isUpvote {
+				if direction == VoteUp {
 					fmt.Printf("Upvoted post %s\n", postID)
 				} else {
 					fmt.Printf("Downvoted post %s\n", postID)
@@ -142,7 +160,7 @@ func (state *SimulatorActor) simulateUserActivity(context actor.Context, usernam
 		for c := 0; c < 2+rand.Intn(5); c++ {
 			commentMsg := &AddCommentMessage{
 				Content:         fmt.Sprintf("Comment %d on post %s", c, postID),
-				Author:          fmt.Sprintf("user_%d", rand.Intn(state.userCount)),
+				Author:          fmt.Sprintf("user_%d", rand.Intn(state.userCount)), // registered before activity starts
 				PostID:          postID,
 				ParentCommentID: "",
 			}
@@ -179,6 +197,13 @@ func (state *SimulatorActor) Receive(context actor.Context) {
 		state.startTime = time.Now()
 		distribution := state.GenerateZipfDistribution(1.3)
 
+		// The engine only accepts content from registered users, so register
+		// the subreddit creator and every simulated user before any activity
+		state.registerUser(context, simulationAdmin)
+		for i := 0; i < state.userCount; i++ {
+			state.registerUser(context, fmt.Sprintf("user_%d", i))
+		}
+
 		// Create subreddits first and wait for them to be created
 		subreddits := make([]string, 20)
 		for i := 0; i < 20; i++ {
@@ -187,7 +212,7 @@ func (state *SimulatorActor) Receive(context actor.Context) {
 			future := context.RequestFuture(state.enginePID, &CreateSubredditMessage{
 				Name:        subredditName,
 				Description: fmt.Sprintf("A community for %s", subredditName),
-				Creator:     "admin",
+				Creator:     simulationAdmin,
 			}, 5*time.Second)
 
 			_, err := future.Result()
@@ -229,7 +254,7 @@ func (state *SimulatorActor) Receive(context actor.Context) {
 		fmt.Printf("Total Downvotes: %d\n", msg.TotalDownvotes)
 		fmt.Printf("\nTop 10 Users by Karma:\n")
 		for i, user := range msg.TopUsers {
-			fmt.Printf("%d. %s: %d karma\n", i+1, user.Username, user.Karma)
+			fmt.Printf("%d. %s: %d karma (%d post, %d comment)\n", i+1, user.Username, user.Karma, user.PostKarma, user.CommentKarma)
 		}
 		context.Stop(context.Self())
 